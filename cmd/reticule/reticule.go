@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 
 	"github.com/alecthomas/kong"
 	"github.com/durp/reticule/internal/app/commands"
+	"github.com/durp/reticule/pkg/coinbasepro"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 )
@@ -26,7 +28,7 @@ func main() {
 		kong.BindTo(afero.NewOsFs(), (*afero.Fs)(nil)),
 	)
 	err := ktx.Run()
-	if err != nil {
+	if err != nil && !errors.Is(err, coinbasepro.ErrDryRun) {
 		logrus.Errorf("%+v", err)
 	}
 }
@@ -1,6 +1,11 @@
 package commands
 
 import (
+	"io"
+	"os"
+
+	"github.com/alecthomas/kong"
+	"github.com/durp/reticule/pkg/coinbasepro"
 	"github.com/sirupsen/logrus"
 )
 
@@ -10,13 +15,26 @@ type CLI struct {
 	LogLevel logLevel    `kong:"name='log-level',short='v',default='info',help='set level of log, one of [ panic, fatal, error, warn, info, debug, trace ]'"`
 }
 
+// AfterApply binds the coinbasepro.Logger of the LogLevel into the kong.Context for use by other commands.
+func (c *CLI) AfterApply(ktx *kong.Context) error {
+	logger, err := c.LogLevel.logger(os.Stderr)
+	if err != nil {
+		return err
+	}
+	ktx.BindTo(logger, (*coinbasepro.Logger)(nil))
+	return nil
+}
+
 type logLevel string
 
-func (l logLevel) AfterApply() error {
+// logger creates a Logger that writes entries of the level and above to w.
+func (l logLevel) logger(w io.Writer) (coinbasepro.Logger, error) {
 	lvl, err := logrus.ParseLevel(string(l))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	logrus.SetLevel(lvl)
-	return nil
+	logger := logrus.New()
+	logger.SetOutput(w)
+	logger.SetLevel(lvl)
+	return coinbasepro.NewLogrusLogger(logger), nil
 }
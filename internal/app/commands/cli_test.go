@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -9,12 +10,16 @@ import (
 )
 
 func TestLogLevel(t *testing.T) {
-	tmp := logrus.GetLevel()
-	defer func() { logrus.SetLevel(tmp) }()
-
-	err := logLevel("info").AfterApply()
+	level := logrus.GetLevel()
+	var b bytes.Buffer
+	logger, err := logLevel("warn").logger(&b)
 	require.NoError(t, err)
-	assert.Equal(t, logrus.InfoLevel, logrus.GetLevel())
-	err = logLevel("blah").AfterApply()
+	logger.Info("hidden")
+	logger.Warn("shown", "config", "sandbox")
+	assert.NotContains(t, b.String(), "hidden")
+	assert.Contains(t, b.String(), "msg=shown config=sandbox")
+	assert.Equal(t, level, logrus.GetLevel(), "the level of the standard logger is unchanged")
+
+	_, err = logLevel("blah").logger(&b)
 	require.Error(t, err)
 }
@@ -5,27 +5,55 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/durp/reticule/pkg/costbasis"
+	"github.com/durp/reticule/pkg/indicator"
 	"github.com/mitchellh/mapstructure"
-	"github.com/sirupsen/logrus"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/afero"
 	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v3"
 )
 
 type coinbaseCmd struct {
-	Config          string    `kong:"name='config',short='f',type='path',default='~/.reticule/coinbasepro'"`
-	Cancel          cancelCmd `kong:"cmd,name='cancel',help='cancel an order'"`
-	Create          createCmd `kong:"cmd,name='create',help='create resources including deposits, orders, and withdrawals'"`
-	Get             getCmd    `kong:"cmd,name='get',help='retrieve resource representations'"`
-	Watch           watchCmd  `kong:"cmd,name='watch',help='watch the websocket feed'"`
-	DevelopmentMode bool      `kong:"name='dev-mode',short='D',help='dev-mode collects API response shapes for inspection and comparison'"`
-
+	Config          string        `kong:"name='config',short='f',type='path',default='~/.reticule/coinbasepro'"`
+	BaseURL         string        `kong:"name='base-url',env='RETICULE_COINBASE_BASE_URL',help='url of coinbasepro api; overrides the config'"`
+	FeedURL         string        `kong:"name='feed-url',env='RETICULE_COINBASE_FEED_URL',help='url of websocket feed; overrides the config'"`
+	Use             string        `kong:"name='use',env='RETICULE_CONFIG_NAME',help='name of config to use instead of the current config'"`
+	Yes             bool          `kong:"name='yes',help='skip confirmation of changes in production'"`
+	DryRun          bool          `kong:"name='dry-run',help='validate create and cancel commands and print the signed request instead of sending it'"`
+	RateLimit       float64       `kong:"name='rate-limit',help='most api requests a second, shared by every request of the command; 0 is unlimited'"`
+	RateBurst       int           `kong:"name='rate-burst',default='10',help='most api requests at once within the rate limit'"`
+	TraceFile       string        `kong:"name='trace-file',type='path',help='append a json line to the file for the trace span of each api request'"`
+	ServerTime      time.Duration `kong:"name='server-time',help='sign requests with the server time, measured at start and again at this interval, instead of the local clock'"`
+	Cancel          cancelCmd     `kong:"cmd,name='cancel',help='cancel an order'"`
+	Create          createCmd     `kong:"cmd,name='create',help='create resources including deposits, orders, and withdrawals'"`
+	DCA             dcaCmd        `kong:"cmd,name='dca',help='buy on the cadence of each plan of a schedule until interrupted'"`
+	Get             getCmd        `kong:"cmd,name='get',help='retrieve resource representations'"`
+	Rebalance       rebalanceCmd  `kong:"cmd,name='rebalance',help='trade the accounts of the profile toward target allocations'"`
+	Serve           serveCmd      `kong:"cmd,name='serve',help='serve the api and feed to local services without sharing credentials'"`
+	Sweep           sweepCmd      `kong:"cmd,name='sweep',help='transfer the funds of every other profile to one profile'"`
+	TUI             tuiCmd        `kong:"cmd,name='tui',help='trade from a live dashboard of a product'"`
+	Watch           watchCmd      `kong:"cmd,name='watch',help='watch the websocket feed'"`
+	DevelopmentMode bool          `kong:"name='dev-mode',short='D',help='dev-mode collects API response shapes for inspection and comparison'"`
+
+	Credentials
 	Output
+
+	traceFile afero.File
+	options   []coinbasepro.Option
+	metrics   *coinbasepro.PrometheusMetrics
+	tracer    coinbasepro.Tracer
+	// profileClients are the Clients of the other configs of a ProfileView
+	profileClients []*coinbasepro.Client
 }
 
 var _ coinbaser = (*coinbasepro.Client)(nil)
@@ -34,60 +62,229 @@ var _ coinbaser = (*coinbasepro.Client)(nil)
 // BaseURL and Auth required to interact with the coinbasepro API.
 // If the config can be loaded, it creates the coinbase.Client and binds
 // it into the kong.Context for use by other commands.
-func (c *coinbaseCmd) AfterApply(ktx *kong.Context) error {
+func (c *coinbaseCmd) AfterApply(ctx context.Context, ktx *kong.Context, fs afero.Fs, logger coinbasepro.Logger) error {
+	name, cfg, err := c.config()
+	if err != nil {
+		return err
+	}
+	if c.BaseURL != "" {
+		cfg.BaseURL = c.BaseURL
+	}
+	if c.FeedURL != "" {
+		cfg.FeedURL = c.FeedURL
+	}
+	baseURL, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return err
+	}
+	feedURL, err := url.Parse(cfg.FeedURL)
+	if err != nil {
+		return err
+	}
+	if isProduction(baseURL) {
+		fmt.Fprintf(os.Stderr, productionBanner, name, baseURL)
+		if changes(ktx) && !c.Yes && !c.DryRun {
+			ok, err := confirm(os.Stdin, os.Stderr, fmt.Sprintf("run %q in production?", ktx.Command()))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errors.New("canceled")
+			}
+		}
+	}
+	c.Credentials.logger = logger
+	auth, err := c.Credentials.resolve(ctx, name, cfg)
+	if err != nil {
+		return err
+	}
+	c.options = []coinbasepro.Option{coinbasepro.WithLogger(logger)}
+	if c.RateLimit > 0 {
+		// every client of the command shares the rate limit
+		c.options = append(c.options, coinbasepro.WithRateLimiter(coinbasepro.NewTokenBucket(c.RateLimit, c.RateBurst)))
+	}
+	if c.ServerTime > 0 {
+		c.options = append(c.options, coinbasepro.WithServerTime(c.ServerTime))
+	}
+	c.metrics = coinbasepro.NewPrometheusMetrics()
+	ktx.Bind(c.metrics)
+	if c.TraceFile != "" {
+		c.traceFile, err = fs.OpenFile(c.TraceFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		c.tracer = coinbasepro.NewExportingTracer(coinbasepro.NewJSONSpanExporter(c.traceFile), coinbasepro.WithLogger(logger))
+	}
+	// DevelopmentMode is a nod to the fact that the Coinbase Pro API has some endpoints that either are
+	// subject to change or return responses with schemaless maps. My hope is that DevelopmentMode makes
+	// it easier to identify changes in the shape of data.
+	client, err := c.newClient(baseURL, feedURL, auth, c.DevelopmentMode)
+	if err != nil {
+		return err
+	}
+	ktx.Bind(&preflight{enabled: c.DryRun})
+	ktx.BindTo(client, (*coinbaser)(nil))
+	ktx.Bind(profileViewer(func(ctx context.Context) (*coinbasepro.ProfileView, error) {
+		return c.profileView(ctx, fs, name, cfg, client)
+	}))
+	guard := cfg.Guardrails
+	if guard == nil {
+		guard = &guardrails{}
+	}
+	ktx.Bind(guard)
+	return nil
+}
+
+const productionBanner = `
+  ****************************************************************
+  *  PRODUCTION: config %q uses %s
+  *  orders, transfers and cancellations affect real funds
+  ****************************************************************
+
+`
+
+// isProduction indicates whether the url is a Coinbase Pro api other than the sandbox.
+func isProduction(baseURL *url.URL) bool {
+	host := baseURL.Hostname()
+	return strings.HasSuffix(host, "coinbase.com") && !strings.Contains(host, "sandbox")
+}
+
+// changes indicates whether the selected command changes state, rather than retrieving or watching it.
+func changes(ktx *kong.Context) bool {
+	fields := strings.Fields(ktx.Command())
+	return len(fields) > 1 && (fields[1] == "create" || fields[1] == "cancel" || fields[1] == "sweep" || fields[1] == "rebalance" || fields[1] == "dca" || fields[1] == "serve")
+}
+
+// config reads the current config from the config file. When there is no config file but credentials are provided by
+// the environment, an unnamed sandbox config is used instead, so that containers need no config file.
+func (c *coinbaseCmd) config() (string, coinbaseProConfig, error) {
 	_, err := os.Stat(c.Config)
 	if errors.Is(err, os.ErrNotExist) {
-		return fmt.Errorf("no config found at %q: use 'coinbasepro create config' to create a config file", c.Config)
+		if c.Credentials.environmentProvided() {
+			return "environment", coinbaseProConfig{BaseURL: sandboxBaseURL, FeedURL: sandboxFeedURL}, nil
+		}
+		return "", coinbaseProConfig{}, fmt.Errorf("no config found at %q: use 'coinbasepro create config' to create a config file", c.Config)
 		// we are in "coinbasepro create config" command; we don't need a client and will create the config
 	}
 	if err != nil {
-		return err
+		return "", coinbaseProConfig{}, err
 	}
 	source, err := ioutil.ReadFile(c.Config)
 	if err != nil {
-		return err
+		return "", coinbaseProConfig{}, err
 	}
 	var configSet coinbaseProConfigSet
 	err = yaml.Unmarshal(source, &configSet)
 	if err != nil {
-		return err
+		return "", coinbaseProConfig{}, err
 	}
 	if len(configSet.Configs) == 0 {
-		return errors.New("no config exists, use the `config create` command to create a config")
+		return "", coinbaseProConfig{}, errors.New("no config exists, use the `config create` command to create a config")
 	}
 	current := configSet.Current
+	if c.Use != "" {
+		current = c.Use
+	}
 	if current == "" {
-		return fmt.Errorf("no current config is set, use the `config use` command to set the config to use")
+		return "", coinbaseProConfig{}, fmt.Errorf("no current config is set, use the `config use` command or --use to set the config to use")
 	}
 	cfg, ok := configSet.Configs[current]
 	if !ok {
-		return fmt.Errorf("no config with name %q, use the `config use` command to set the config to use", current)
+		return "", coinbaseProConfig{}, fmt.Errorf("no config with name %q, use the `config use` command or --use to set the config to use", current)
+	}
+	return current, cfg, nil
+}
+
+// newClient creates a Client of the api signed with auth in the modes of the command. Only the Client of the current
+// config collects shapes in development mode, since the shapes of every Client would share one store.
+func (c *coinbaseCmd) newClient(baseURL *url.URL, feedURL *url.URL, auth *coinbasepro.Auth, development bool) (*coinbasepro.Client, error) {
+	client, err := coinbasepro.NewClient(baseURL, feedURL, auth, c.options...)
+	if err != nil {
+		return nil, err
+	}
+	if development {
+		coinbasepro.DevelopmentMode(client)
+	}
+	if c.DryRun {
+		coinbasepro.DryRunMode(client, func(signed coinbasepro.SignedRequest) error {
+			return c.Output.Encode(signed)
+		})
+	}
+	coinbasepro.MetricsMode(client, c.metrics)
+	if c.tracer != nil {
+		coinbasepro.TracingMode(client, c.tracer)
+	}
+	return client, nil
+}
+
+// profileViewer creates the ProfileView of the user of the current config.
+type profileViewer func(ctx context.Context) (*coinbasepro.ProfileView, error)
+
+// profileView creates a ProfileView from the client of the current config and a Client of every other config of the
+// same api, so that a command can span each Profile of the user with the key of its config. The credentials of the
+// other configs come from the configs alone, never from the environment or flags.
+func (c *coinbaseCmd) profileView(ctx context.Context, fs afero.Fs, name string, current coinbaseProConfig, client *coinbasepro.Client) (*coinbasepro.ProfileView, error) {
+	clients := []*coinbasepro.Client{client}
+	exists, err := afero.Exists(fs, c.Config)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		configSet, err := readConfigSet(fs, c.Config)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(configSet.Configs))
+		for other := range configSet.Configs {
+			names = append(names, other)
+		}
+		sort.Strings(names)
+		for _, other := range names {
+			cfg := configSet.Configs[other]
+			if other == name || cfg.BaseURL != current.BaseURL {
+				continue
+			}
+			profileClient, err := c.configClient(ctx, other, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("config %q: %w", other, err)
+			}
+			clients = append(clients, profileClient)
+		}
 	}
+	return coinbasepro.NewProfileView(ctx, clients...)
+}
+
+// configClient creates a Client for another config of the command with the credentials of that config.
+func (c *coinbaseCmd) configClient(ctx context.Context, name string, cfg coinbaseProConfig) (*coinbasepro.Client, error) {
 	baseURL, err := url.Parse(cfg.BaseURL)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	feedURL, err := url.Parse(cfg.FeedURL)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	client, err := coinbasepro.NewClient(baseURL, feedURL, cfg.Auth)
+	auth, err := c.Credentials.stored(ctx, name, cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if c.DevelopmentMode {
-		// DevelopmentMode is a nod to the fact that the Coinbase Pro API has some endpoints that either are
-		// subject to change or return responses with schemaless maps. My hope is that DevelopmentMode makes
-		// it easier to identify changes in the shape of data.
-		coinbasepro.DevelopmentMode(client)
+	client, err := c.newClient(baseURL, feedURL, auth, false)
+	if err != nil {
+		return nil, err
 	}
-	ktx.BindTo(client, (*coinbaser)(nil))
-	return nil
+	c.profileClients = append(c.profileClients, client)
+	return client, nil
 }
 
 // Run of the coinbaseCmd is a good place to tuck cleanup
 // as it is called after any and all leaf commands.
-func (c *coinbaseCmd) Run(cb coinbaser) error {
+func (c *coinbaseCmd) Run(cb coinbaser) (capture error) {
+	if c.traceFile != nil {
+		defer func() { coinbasepro.Capture(&capture, c.traceFile.Close()) }()
+	}
+	for _, client := range c.profileClients {
+		defer func(client *coinbasepro.Client) { coinbasepro.Capture(&capture, client.Close()) }(client)
+	}
 	return cb.Close()
 }
 
@@ -117,6 +314,8 @@ type getCmd struct {
 	Limits           limitsCmd           `kong:"cmd,name='limits',help='get limits and limit details'"`
 	Orders           ordersCmd           `kong:"cmd,name='orders',help='get orders and order details'"`
 	PaymentMethods   paymentMethodsCmd   `kong:"cmd,name='payment-methods',help='get payment methods and payment method details'"`
+	PNL              pnlCmd              `kong:"cmd,name='pnl',help='get realized and unrealized profit and loss per product'"`
+	Portfolio        portfolioCmd        `kong:"cmd,name='portfolio',help='get value of accounts in a quote currency'"`
 	Products         productsCmd         `kong:"cmd,name='products',help='get products and product details'"`
 	ProductOrderBook productOrderBookCmd `kong:"cmd,name='product-book',help='get order book for a product'"`
 	ProductHistory   historicRatesCmd    `kong:"cmd,name='product-history',help='get history for a product'"`
@@ -126,6 +325,7 @@ type getCmd struct {
 	Profiles         profilesCmd         `kong:"cmd,name='profiles',help='get profiles and profile details'"`
 	Report           reportCmd           `kong:"cmd,name='report',help='get status of a report'"`
 	Time             serverTimeCmd       `kong:"cmd,name='server-time',help='get current server time'"`
+	TaxLots          taxLotsCmd          `kong:"cmd,name='tax-lots',help='get disposed tax lots with gains as form 8949 style csv'"`
 	Withdrawals      withdrawalsCmd      `kong:"cmd,name='withdrawals',help='get withdrawals and withdrawal details'"`
 	WithdrawalFee    withdrawalFeeCmd    `kong:"cmd,name='withdrawal-fee',help='get estimated fee for a withdrawal'"`
 }
@@ -166,6 +366,8 @@ type coinbaser interface {
 
 	CreateReport(ctx context.Context, createReportSpec coinbasepro.ReportSpec) (coinbasepro.Report, error)
 	GetReport(ctx context.Context, reportID string) (coinbasepro.Report, error)
+	WaitForReport(ctx context.Context, reportID string, backoff coinbasepro.ReportBackoff) (coinbasepro.Report, error)
+	DownloadReport(ctx context.Context, report coinbasepro.Report, fs afero.Fs, path string) error
 
 	ListProfiles(ctx context.Context, filter coinbasepro.ProfileFilter) ([]coinbasepro.Profile, error)
 	GetProfile(ctx context.Context, profileID string) (coinbasepro.Profile, error)
@@ -187,6 +389,15 @@ type coinbaser interface {
 
 	GetServerTime(ctx context.Context) (coinbasepro.ServerTime, error)
 
+	GetPortfolio(ctx context.Context, quote coinbasepro.CurrencyName) (coinbasepro.Portfolio, error)
+	PlanRebalance(ctx context.Context, targets coinbasepro.RebalanceTargets) (coinbasepro.RebalancePlan, error)
+	Rebalance(ctx context.Context, plan coinbasepro.RebalancePlan) ([]coinbasepro.Order, error)
+
+	ListLedger(ctx context.Context, accountID string) ([]*coinbasepro.LedgerEntry, error)
+	ListFills(ctx context.Context, filter coinbasepro.FillFilter) ([]*coinbasepro.Fill, error)
+	ListDeposits(ctx context.Context, filter coinbasepro.DepositFilter) ([]*coinbasepro.Deposit, error)
+	ListWithdrawals(ctx context.Context, filter coinbasepro.WithdrawalFilter) ([]*coinbasepro.Withdrawal, error)
+
 	// Watch websocket feed
 	Watch(ctx context.Context, subscriptionRequest coinbasepro.SubscriptionRequest, feed coinbasepro.Feed) (capture error)
 
@@ -194,10 +405,29 @@ type coinbaser interface {
 }
 
 type accountsCmd struct {
-	Account string `kong:"short='a',help='id of account to retrieve'"`
+	Account     string `kong:"short='a',help='id of account to retrieve'"`
+	AllProfiles bool   `kong:"name='all-profiles',help='list the accounts of every profile, with the key of each config of the same api'"`
 }
 
-func (a *accountsCmd) Run(ctx context.Context, cb coinbaser, enc encoder) error {
+func (a *accountsCmd) Validate() error {
+	if a.Account != "" && a.AllProfiles {
+		return errors.New("only one of 'account' or 'all-profiles' can be provided")
+	}
+	return nil
+}
+
+func (a *accountsCmd) Run(ctx context.Context, cb coinbaser, enc encoder, viewer profileViewer) error {
+	if a.AllProfiles {
+		view, err := viewer(ctx)
+		if err != nil {
+			return err
+		}
+		accounts, err := view.ListAccounts(ctx)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(accounts)
+	}
 	if a.Account != "" {
 		account, err := cb.GetAccount(ctx, a.Account)
 		if err != nil {
@@ -212,6 +442,158 @@ func (a *accountsCmd) Run(ctx context.Context, cb coinbaser, enc encoder) error
 	return enc.Encode(accounts)
 }
 
+type portfolioCmd struct {
+	Quote coinbasepro.CurrencyName `kong:"name='quote',short='q',default='USD',help='currency in which to value accounts'"`
+}
+
+func (p *portfolioCmd) Run(ctx context.Context, cb coinbaser, enc encoder) error {
+	portfolio, err := cb.GetPortfolio(ctx, p.Quote)
+	if err != nil {
+		return err
+	}
+	return enc.Encode(portfolio)
+}
+
+// costBasisFlags select the Method and Products used to build a costbasis.Book.
+type costBasisFlags struct {
+	Method    costbasis.Method         `kong:"name='method',short='m',default='fifo',enum='fifo,lifo,hifo,average',help='order in which lots are disposed of, one of [ fifo, lifo, hifo, average ]'"`
+	Quote     coinbasepro.CurrencyName `kong:"name='quote',short='q',default='USD',help='quote currency of products to report'"`
+	ProductID coinbasepro.ProductID    `kong:"name='product-id',short='p',help='limit report to a single product'"`
+}
+
+// book applies the Fills of every reported Product, and the transfers of their base Currencies, to a new
+// costbasis.Book.
+func (c *costBasisFlags) book(ctx context.Context, cb coinbaser, transfers func(ctx context.Context, cb coinbaser) ([]costbasis.Event, error)) (*costbasis.Book, error) {
+	book, err := costbasis.NewBook(c.Method)
+	if err != nil {
+		return nil, err
+	}
+	productIDs, err := c.productIDs(ctx, cb)
+	if err != nil {
+		return nil, err
+	}
+	var fills []*coinbasepro.Fill
+	for productID := range productIDs {
+		productFills, err := cb.ListFills(ctx, coinbasepro.FillFilter{ProductID: productID})
+		if err != nil {
+			return nil, err
+		}
+		fills = append(fills, productFills...)
+	}
+	transferEvents, err := transfers(ctx, cb)
+	if err != nil {
+		return nil, err
+	}
+	var events []costbasis.Event
+	for _, event := range append(costbasis.Events(fills, nil, nil, c.Quote), transferEvents...) {
+		if productIDs[event.ProductID] {
+			events = append(events, event)
+		}
+	}
+	return book, book.ApplyAll(events)
+}
+
+// productIDs are the Products to report: the ProductID, if given, or every Product quoted in the Quote Currency.
+func (c *costBasisFlags) productIDs(ctx context.Context, cb coinbaser) (map[coinbasepro.ProductID]bool, error) {
+	if c.ProductID != "" {
+		return map[coinbasepro.ProductID]bool{c.ProductID: true}, nil
+	}
+	products, err := cb.ListProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	productIDs := make(map[coinbasepro.ProductID]bool)
+	for _, product := range products {
+		if product.QuoteCurrency == c.Quote {
+			productIDs[coinbasepro.ProductID(product.ID)] = true
+		}
+	}
+	return productIDs, nil
+}
+
+type pnlCmd struct {
+	costBasisFlags
+}
+
+func (p *pnlCmd) Run(ctx context.Context, cb coinbaser, enc encoder) error {
+	book, err := p.book(ctx, cb, p.transfers)
+	if err != nil {
+		return err
+	}
+	prices := make(map[coinbasepro.ProductID]decimal.Decimal)
+	for _, productID := range book.ProductIDs() {
+		if len(book.Lots(productID)) == 0 {
+			continue
+		}
+		ticker, err := cb.GetProductTicker(ctx, productID)
+		if err != nil {
+			return err
+		}
+		prices[productID] = ticker.Price
+	}
+	return enc.Encode(book.Report(prices))
+}
+
+// transfers are the Deposits and Withdrawals of the current Profile.
+func (p *pnlCmd) transfers(ctx context.Context, cb coinbaser) ([]costbasis.Event, error) {
+	deposits, err := cb.ListDeposits(ctx, coinbasepro.DepositFilter{})
+	if err != nil {
+		return nil, err
+	}
+	withdrawals, err := cb.ListWithdrawals(ctx, coinbasepro.WithdrawalFilter{})
+	if err != nil {
+		return nil, err
+	}
+	return costbasis.Events(nil, deposits, withdrawals, p.Quote), nil
+}
+
+type taxLotsCmd struct {
+	costBasisFlags
+	Year int    `kong:"name='year',short='y',help='limit report to disposals in a calendar year (UTC); default is every year'"`
+	Out  string `kong:"name='out',type='path',help='file to which the CSV is written; default is stdout'"`
+}
+
+func (t *taxLotsCmd) Run(ctx context.Context, cb coinbaser, fs afero.Fs) (capture error) {
+	book, err := t.book(ctx, cb, t.transfers)
+	if err != nil {
+		return err
+	}
+	disposals := book.Disposals()
+	if t.Year != 0 {
+		from := time.Date(t.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		disposals = costbasis.DisposalsBetween(disposals, from, from.AddDate(1, 0, 0))
+	}
+	if t.Out == "" {
+		return costbasis.WriteForm8949(os.Stdout, disposals)
+	}
+	f, err := fs.Create(t.Out)
+	if err != nil {
+		return err
+	}
+	defer func() { coinbasepro.Capture(&capture, f.Close()) }()
+	return costbasis.WriteForm8949(f, disposals)
+}
+
+// transfers are the transfer LedgerEntries of every Account of the current Profile.
+func (t *taxLotsCmd) transfers(ctx context.Context, cb coinbaser) ([]costbasis.Event, error) {
+	accounts, err := cb.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var events []costbasis.Event
+	for _, account := range accounts {
+		if account.Currency == t.Quote {
+			continue
+		}
+		entries, err := cb.ListLedger(ctx, account.ID)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, costbasis.LedgerEvents(account.Currency, entries, t.Quote)...)
+	}
+	return events, nil
+}
+
 type ledgerCmd struct {
 	Account string `kong:"name='account',short='a',help='id of account to retrieve',required"`
 	Pagination
@@ -258,6 +640,8 @@ type ordersCmd struct {
 	ProductID coinbasepro.ProductID `kong:"name='product-id',short='p',help='filter by product id'"`
 	// Status limits list of Orders to the provided Statuses. The default, `all`, returns orders of all statuses
 	Status []coinbasepro.OrderStatusParam `kong:"name='status',short='s',help='filter by status(es)'"`
+	// AllProfiles lists the open Orders of every Profile
+	AllProfiles bool `kong:"name='all-profiles',help='list the open orders of every profile, with the key of each config of the same api'"`
 	Pagination
 }
 
@@ -271,10 +655,24 @@ func (o *ordersCmd) Validate() error {
 	if o.OrderID != "" && (o.ProductID != "" || len(o.Status) > 0 || !o.Pagination.Empty()) {
 		return errors.New("when 'order-id' us provided, it must be the only flag")
 	}
+	if o.AllProfiles && (o.ClientOrderID != "" || o.OrderID != "" || len(o.Status) > 0 || !o.Pagination.Empty()) {
+		return errors.New("when 'all-profiles' is provided, only 'product-id' can be provided")
+	}
 	return nil
 }
 
-func (o *ordersCmd) Run(ctx context.Context, client coinbaser, enc encoder) error {
+func (o *ordersCmd) Run(ctx context.Context, client coinbaser, enc encoder, viewer profileViewer) error {
+	if o.AllProfiles {
+		view, err := viewer(ctx)
+		if err != nil {
+			return err
+		}
+		orders, err := view.ListOpenOrders(ctx, o.ProductID)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(orders)
+	}
 	if o.OrderID != "" {
 		order, err := client.GetOrder(ctx, o.OrderID)
 		if err != nil {
@@ -314,9 +712,20 @@ type createOrderCmd struct {
 
 type limitOrderCmd struct {
 	Order coinbasepro.LimitOrder `kong:"name='order',short='o',help='json {\"size\": \"0.01\",\"price\": \"0.100\",\"side\": \"buy\",\"product_id\": \"BTC-USD\"}',required"`
+	guardrailOverride
 }
 
-func (l *limitOrderCmd) Run(ctx context.Context, client coinbaser, enc encoder) error {
+func (l *limitOrderCmd) Run(ctx context.Context, client coinbaser, enc encoder, guard *guardrails, check *preflight) error {
+	violations, err := guard.checkLimitOrder(ctx, client, l.Order)
+	if err != nil {
+		return err
+	}
+	if err = l.override(violations); err != nil {
+		return err
+	}
+	if err = check.checkLimitOrder(ctx, client, l.Order); err != nil {
+		return err
+	}
 	order, err := client.CreateLimitOrder(ctx, l.Order)
 	if err != nil {
 		return err
@@ -326,9 +735,20 @@ func (l *limitOrderCmd) Run(ctx context.Context, client coinbaser, enc encoder)
 
 type marketOrderCmd struct {
 	Order coinbasepro.MarketOrder `kong:"name='order',short='o',help='json {\"size\": \"0.01\",\"side\": \"buy\",\"product_id\": \"BTC-USD\"}',required"`
+	guardrailOverride
 }
 
-func (m *marketOrderCmd) Run(ctx context.Context, client coinbaser, enc encoder) error {
+func (m *marketOrderCmd) Run(ctx context.Context, client coinbaser, enc encoder, guard *guardrails, check *preflight) error {
+	violations, err := guard.checkMarketOrder(ctx, client, m.Order)
+	if err != nil {
+		return err
+	}
+	if err = m.override(violations); err != nil {
+		return err
+	}
+	if err = check.checkMarketOrder(ctx, client, m.Order); err != nil {
+		return err
+	}
 	order, err := client.CreateMarketOrder(ctx, m.Order)
 	if err != nil {
 		return err
@@ -382,10 +802,30 @@ type fillsCmd struct {
 	OrderID string `kong:"name='order-id',short='o',help='filter retrieval by order id'"`
 	// ProductID limits the list of Fills to those with the specified ProductID
 	ProductID coinbasepro.ProductID `kong:"name='product-id',short='p',help='filter retrieval by product id'"`
+	// AllProfiles lists every page of the Fills of every Profile
+	AllProfiles bool `kong:"name='all-profiles',help='list the fills of every profile, newest first, with the key of each config of the same api'"`
 	Pagination
 }
 
-func (f *fillsCmd) Run(ctx context.Context, client coinbaser, enc encoder) error {
+func (f *fillsCmd) Validate() error {
+	if f.AllProfiles && !f.Pagination.Empty() {
+		return errors.New("pagination cannot be provided with 'all-profiles'")
+	}
+	return nil
+}
+
+func (f *fillsCmd) Run(ctx context.Context, client coinbaser, enc encoder, viewer profileViewer) error {
+	if f.AllProfiles {
+		view, err := viewer(ctx)
+		if err != nil {
+			return err
+		}
+		fills, err := view.ListFills(ctx, f.Filter())
+		if err != nil {
+			return err
+		}
+		return enc.Encode(fills)
+	}
 	pagination, err := f.Pagination.Params()
 	if err != nil {
 		return err
@@ -548,9 +988,20 @@ type createWithdrawalCmd struct {
 
 type paymentMethodWithdrawalCmd struct {
 	Withdrawal coinbasepro.PaymentMethodWithdrawalSpec `kong:"name='withdrawal',short='w',help='json {\"amount\":10.00,\"currency\":\"USD\",\"id\":\"bc677162-d934-5f1a-968c-a496b1c1270b\"}',required"`
+	guardrailOverride
 }
 
-func (p *paymentMethodWithdrawalCmd) Run(ctx context.Context, client coinbaser, enc encoder) error {
+func (p *paymentMethodWithdrawalCmd) Run(ctx context.Context, client coinbaser, enc encoder, guard *guardrails, check *preflight) error {
+	violations, err := guard.checkWithdrawal(ctx, client, p.Withdrawal.Currency, p.Withdrawal.Amount)
+	if err != nil {
+		return err
+	}
+	if err = p.override(violations); err != nil {
+		return err
+	}
+	if err = check.checkBalance(ctx, client, p.Withdrawal.Currency, p.Withdrawal.Amount); err != nil {
+		return err
+	}
 	withdrawal, err := client.CreatePaymentMethodWithdrawal(ctx, p.Withdrawal)
 	if err != nil {
 		return err
@@ -560,9 +1011,20 @@ func (p *paymentMethodWithdrawalCmd) Run(ctx context.Context, client coinbaser,
 
 type coinbaseAccountWithdrawalCmd struct {
 	Withdrawal coinbasepro.CoinbaseAccountWithdrawalSpec `kong:"name='withdrawal',short='w',help='json {\"amount\":10.00,\"currency\":\"USD\",\"coinbase_account_id\":\"bc677162-d934-5f1a-968c-a496b1c1270b\"}',required"`
+	guardrailOverride
 }
 
-func (c *coinbaseAccountWithdrawalCmd) Run(ctx context.Context, client coinbaser, enc encoder) error {
+func (c *coinbaseAccountWithdrawalCmd) Run(ctx context.Context, client coinbaser, enc encoder, guard *guardrails, check *preflight) error {
+	violations, err := guard.checkWithdrawal(ctx, client, c.Withdrawal.Currency, c.Withdrawal.Amount)
+	if err != nil {
+		return err
+	}
+	if err = c.override(violations); err != nil {
+		return err
+	}
+	if err = check.checkBalance(ctx, client, c.Withdrawal.Currency, c.Withdrawal.Amount); err != nil {
+		return err
+	}
 	withdrawal, err := client.CreateCoinbaseAccountWithdrawal(ctx, c.Withdrawal)
 	if err != nil {
 		return err
@@ -572,9 +1034,20 @@ func (c *coinbaseAccountWithdrawalCmd) Run(ctx context.Context, client coinbaser
 
 type cryptoAddressWithdrawalCmd struct {
 	Withdrawal coinbasepro.CryptoAddressWithdrawalSpec `kong:"name='withdrawal',short='w',help='json {\"amount\":10.00,\"currency\":\"BTC\",\"crypto_address\":\"0x5ad5769cd04681FeD900BCE3DDc877B50E83d469\"}',required"`
+	guardrailOverride
 }
 
-func (c *cryptoAddressWithdrawalCmd) Run(ctx context.Context, client coinbaser, enc encoder) error {
+func (c *cryptoAddressWithdrawalCmd) Run(ctx context.Context, client coinbaser, enc encoder, guard *guardrails, check *preflight) error {
+	violations, err := guard.checkWithdrawal(ctx, client, c.Withdrawal.Currency, c.Withdrawal.Amount)
+	if err != nil {
+		return err
+	}
+	if err = c.override(violations); err != nil {
+		return err
+	}
+	if err = check.checkBalance(ctx, client, c.Withdrawal.Currency, c.Withdrawal.Amount); err != nil {
+		return err
+	}
 	withdrawal, err := client.CreateCryptoAddressWithdrawal(ctx, c.Withdrawal)
 	if err != nil {
 		return err
@@ -598,7 +1071,10 @@ type createStablecoinConversion struct {
 	StablecoinConversion coinbasepro.StablecoinConversionSpec `kong:"name='stablecoin',help='json: {\"from\":\"BTC\",\"to\":\"USD\",\"amount\":\"1.0\"}',required"`
 }
 
-func (c *createStablecoinConversion) Run(ctx context.Context, client coinbaser, enc encoder) error {
+func (c *createStablecoinConversion) Run(ctx context.Context, client coinbaser, enc encoder, check *preflight) error {
+	if err := check.checkBalance(ctx, client, c.StablecoinConversion.From, c.StablecoinConversion.Amount); err != nil {
+		return err
+	}
 	conversion, err := client.CreateStablecoinConversion(ctx, c.StablecoinConversion)
 	if err != nil {
 		return err
@@ -637,14 +1113,30 @@ func (f *feesCmd) Run(ctx context.Context, client coinbaser, enc encoder) error
 }
 
 type createReportCmd struct {
-	Spec coinbasepro.ReportSpec `kong:"name='spec',short='s',help='json {\"type\": \"fills\",\"start_date\": \"2014-11-01T00:00:00.000Z\",\"end_date\": \"2014-11-30T23:59:59.000Z\"}'"`
+	Spec    coinbasepro.ReportSpec `kong:"name='spec',short='s',help='json {\"type\": \"fills\",\"start_date\": \"2014-11-01T00:00:00.000Z\",\"end_date\": \"2014-11-30T23:59:59.000Z\"}'"`
+	Wait    bool                   `kong:"name='wait',short='w',help='wait until the report is ready'"`
+	Timeout time.Duration          `kong:"name='timeout',default='10m',help='maximum time to wait for the report'"`
+	Out     string                 `kong:"name='out',type='path',help='file to which the ready report is downloaded; implies --wait'"`
 }
 
-func (c *createReportCmd) Run(ctx context.Context, client coinbaser, enc encoder) error {
+func (c *createReportCmd) Run(ctx context.Context, client coinbaser, enc encoder, fs afero.Fs) error {
 	report, err := client.CreateReport(ctx, c.Spec)
 	if err != nil {
 		return err
 	}
+	if c.Wait || c.Out != "" {
+		waitCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+		report, err = client.WaitForReport(waitCtx, report.ID, coinbasepro.DefaultReportBackoff)
+		if err != nil {
+			return err
+		}
+	}
+	if c.Out != "" {
+		if err = client.DownloadReport(ctx, report, fs, c.Out); err != nil {
+			return err
+		}
+	}
 	return enc.Encode(report)
 }
 
@@ -762,7 +1254,14 @@ func (p *productTradesCmd) Run(ctx context.Context, client coinbaser, enc encode
 type historicRatesCmd struct {
 	HistoricRateParams
 
-	ProductID coinbasepro.ProductID `kong:"name='product-id',short='p',required"`
+	ProductID  coinbasepro.ProductID `kong:"name='product-id',short='p',required"`
+	Indicators []indicator.Spec      `kong:"name='indicator',short='i',help='add computed indicator columns, oldest candle first, any of [ sma:20, ema:20, rsi:14, macd:12:26:9, bollinger:20:2, atr:14, vwap ]'"`
+}
+
+// indicatorCandle is a Candle with the computed columns of any requested indicators.
+type indicatorCandle struct {
+	coinbasepro.Candle `yaml:",inline"`
+	Indicators         map[string]*decimal.Decimal `json:"indicators" yaml:"indicators"`
 }
 
 type HistoricRateParams struct {
@@ -784,7 +1283,25 @@ func (h *historicRatesCmd) Run(ctx context.Context, client coinbaser, enc encode
 	if err != nil {
 		return err
 	}
-	return enc.Encode(historicRates)
+	if len(h.Indicators) == 0 {
+		return enc.Encode(historicRates)
+	}
+	candles := indicator.Chronological(historicRates)
+	rows := make([]indicatorCandle, len(candles))
+	for i, candle := range candles {
+		rows[i] = indicatorCandle{
+			Candle:     *candle,
+			Indicators: make(map[string]*decimal.Decimal),
+		}
+	}
+	for _, spec := range h.Indicators {
+		for column, series := range spec.Columns(candles) {
+			for i, value := range series {
+				rows[i].Indicators[column] = value
+			}
+		}
+	}
+	return enc.Encode(rows)
 }
 
 func (h *historicRatesCmd) Validate() error {
@@ -837,6 +1354,13 @@ func (t *serverTimeCmd) Run(ctx context.Context, client coinbaser, enc encoder)
 
 // -- websocket feed --
 type watchCmd struct {
+	MetricsListen string `kong:"name='metrics-listen',help='address on which to serve prometheus metrics of the feed at /metrics, such as 127.0.0.1:9100'"`
+
+	feedFlags
+}
+
+// feedFlags select the channels and products of a subscription to the websocket feed.
+type feedFlags struct {
 	ProductIDs []coinbasepro.ProductID   `kong:"name='product-ids',short='p',help='product ids to add to all feeds'"`
 	Channels   []coinbasepro.ChannelName `kong:"name='channels',short='c',help='specific channel name and product ids to watch'"`
 	Heartbeat  []coinbasepro.ProductID   `kong:"name='heartbeat',short='b',help='watch heartbeat channel of product ids'"`
@@ -848,17 +1372,35 @@ type watchCmd struct {
 	Matches    []coinbasepro.ProductID   `kong:"name='matches',short='m',help='watch match channel of product ids'"`
 }
 
-func (w *watchCmd) Run(ctx context.Context, client coinbaser, enc encoder) error {
+func (w *watchCmd) Run(ctx context.Context, client coinbaser, enc encoder, metrics *coinbasepro.PrometheusMetrics, logger coinbasepro.Logger) error {
+	if s, ok := enc.(streamer); ok {
+		enc = s.Stream()
+	}
 	feed := coinbasepro.NewFeed()
 
 	wg, ctx := errgroup.WithContext(ctx)
+	if w.MetricsListen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		server := &http.Server{Addr: w.MetricsListen, Handler: mux}
+		wg.Go(func() error {
+			if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		})
+		wg.Go(func() error {
+			<-ctx.Done()
+			return server.Close()
+		})
+	}
 	wg.Go(func() error {
 		return client.Watch(ctx, w.subscriptionRequest(), feed)
 	})
 
 	wg.Go(func() error {
 		for message := range feed.Messages {
-			logrus.Debug("receive message on channel")
+			logger.Debug("receive message on channel")
 			err := enc.Encode(message)
 			if err != nil {
 				return err
@@ -869,7 +1411,7 @@ func (w *watchCmd) Run(ctx context.Context, client coinbaser, enc encoder) error
 	return wg.Wait()
 }
 
-func (w *watchCmd) subscriptionRequest() coinbasepro.SubscriptionRequest {
+func (w feedFlags) subscriptionRequest() coinbasepro.SubscriptionRequest {
 	var channels []coinbasepro.Channel
 	if len(w.Heartbeat) > 0 {
 		channels = append(channels, coinbasepro.Channel{
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reportCoinbaser creates a report that is ready at once or never, and downloads its file to the fs.
+type reportCoinbaser struct {
+	coinbaser
+	ready bool
+}
+
+func (r *reportCoinbaser) CreateReport(_ context.Context, _ coinbasepro.ReportSpec) (coinbasepro.Report, error) {
+	return coinbasepro.Report{ID: "report", Status: coinbasepro.ReportStatusPending}, nil
+}
+
+func (r *reportCoinbaser) WaitForReport(ctx context.Context, reportID string, _ coinbasepro.ReportBackoff) (coinbasepro.Report, error) {
+	if !r.ready {
+		<-ctx.Done()
+		return coinbasepro.Report{}, ctx.Err()
+	}
+	return coinbasepro.Report{ID: reportID, Status: coinbasepro.ReportStatusReady, FileURL: "https://example.com/report.csv"}, nil
+}
+
+func (r *reportCoinbaser) DownloadReport(_ context.Context, report coinbasepro.Report, fs afero.Fs, path string) error {
+	return afero.WriteFile(fs, path, []byte(report.FileURL), 0600)
+}
+
+func TestCreateReportCmd(t *testing.T) {
+	parse := func(t *testing.T, args ...string) *createReportCmd {
+		var cli struct {
+			createReportCmd
+		}
+		_, err := mustNew(t, &cli).Parse(append([]string{`--spec={"type":"fills","product_id":"BTC-USD","format":"csv","start_date":"2021-01-01T00:00:00Z","end_date":"2021-02-01T00:00:00Z"}`}, args...))
+		require.NoError(t, err)
+		return &cli.createReportCmd
+	}
+	t.Run("Out", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		var b bytes.Buffer
+		cmd := parse(t, `--out=/reports/fills.csv`)
+		require.NoError(t, cmd.Run(context.Background(), &reportCoinbaser{ready: true}, &Output{Output: OutputTypeJSON, w: &b}, fs))
+		content, err := afero.ReadFile(fs, "/reports/fills.csv")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/report.csv", string(content))
+		var printed coinbasepro.Report
+		require.NoError(t, json.Unmarshal(b.Bytes(), &printed))
+		assert.Equal(t, coinbasepro.ReportStatusReady, printed.Status)
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		var b bytes.Buffer
+		cmd := parse(t, `--out=/reports/fills.csv`, `--timeout=10ms`)
+		err := cmd.Run(context.Background(), &reportCoinbaser{}, &Output{Output: OutputTypeJSON, w: &b}, fs)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		exists, err := afero.Exists(fs, "/reports/fills.csv")
+		require.NoError(t, err)
+		assert.False(t, exists, "nothing is downloaded when the wait times out")
+		assert.Empty(t, b.String())
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"sort"
 
 	"github.com/durp/reticule/pkg/coinbasepro"
 	"github.com/spf13/afero"
@@ -16,7 +17,13 @@ import (
 type configCmd struct {
 	Create createConfigCmd `kong:"cmd,name='create',help='create a new config'"`
 	Delete deleteConfigCmd `kong:"cmd,name='delete',help='delete a config'"`
+	List   listConfigCmd   `kong:"cmd,name='list',help='list configs'"`
+	Seal   sealConfigCmd   `kong:"cmd,name='seal',help='encrypt the credentials of existing configs'"`
+	Show   showConfigCmd   `kong:"cmd,name='show',help='show a config with secrets masked'"`
 	Update updateConfigCmd `kong:"cmd,name='update',help='update an existing config'"`
+	Use    useConfigCmd    `kong:"cmd,name='use',help='set the config to use'"`
+
+	Output
 }
 
 type createConfigCmd struct {
@@ -27,6 +34,22 @@ type deleteConfigCmd struct {
 	Coinbase deleteCoinbaseConfigCmd `kong:"cmd,name='coinbase',alias='cb',help='delete an existing coinbasepro config'"`
 }
 
+type listConfigCmd struct {
+	Coinbase listCoinbaseConfigCmd `kong:"cmd,name='coinbase',alias='cb',help='list coinbasepro configs, marking the current config'"`
+}
+
+type showConfigCmd struct {
+	Coinbase showCoinbaseConfigCmd `kong:"cmd,name='coinbase',alias='cb',help='show a coinbasepro config with secrets masked'"`
+}
+
+type useConfigCmd struct {
+	Coinbase useCoinbaseConfigCmd `kong:"cmd,name='coinbase',alias='cb',help='set the coinbasepro config to use'"`
+}
+
+type sealConfigCmd struct {
+	Coinbase sealCoinbaseConfigCmd `kong:"cmd,name='coinbase',alias='cb',help='encrypt the credentials of existing coinbasepro configs'"`
+}
+
 type updateConfigCmd struct {
 	Coinbase updateCoinbaseConfigCmd `kong:"cmd,name='coinbase',alias='cb',help='update an existing coinbasepro config'"`
 }
@@ -36,10 +59,63 @@ type coinbaseProConfigSet struct {
 	Configs map[string]coinbaseProConfig
 }
 
+// summary describes the named config without its credentials.
+func (c coinbaseProConfigSet) summary(name string) coinbaseProConfigSummary {
+	cfg := c.Configs[name]
+	summary := coinbaseProConfigSummary{
+		Name:        name,
+		Current:     name == c.Current,
+		BaseURL:     cfg.BaseURL,
+		FeedURL:     cfg.FeedURL,
+		Credentials: "none",
+	}
+	switch {
+	case cfg.CredentialHelper != "":
+		summary.Credentials = "helper"
+	case cfg.Sealed != "":
+		summary.Credentials = "sealed"
+	case cfg.Auth != nil:
+		summary.Credentials = "plaintext"
+	}
+	return summary
+}
+
 type coinbaseProConfig struct {
 	BaseURL string
 	FeedURL string
-	Auth    *coinbasepro.Auth
+	Auth    *coinbasepro.Auth `yaml:"auth,omitempty"`
+	// Sealed is the encrypted Auth; either Auth or Sealed is set
+	Sealed string `yaml:"sealed,omitempty"`
+	// CredentialHelper is a command that prints the credentials, used instead of Auth or Sealed
+	CredentialHelper string `yaml:"credentialhelper,omitempty"`
+	// Guardrails limit orders and withdrawals made with the config
+	Guardrails *guardrails `yaml:"guardrails,omitempty"`
+}
+
+// auth returns the Auth of the named config, unsealing it with a passphrase when the config is sealed.
+func (c coinbaseProConfig) auth(name string) (*coinbasepro.Auth, error) {
+	if c.Sealed == "" {
+		return c.Auth, nil
+	}
+	passphrase, err := readPassphrase(fmt.Sprintf("passphrase for config %q: ", name))
+	if err != nil {
+		return nil, err
+	}
+	return openAuth(c.Sealed, passphrase)
+}
+
+// seal encrypts the Auth of the config with the passphrase.
+func (c *coinbaseProConfig) seal(passphrase string) error {
+	if c.Auth == nil {
+		return errors.New("config has no credentials to seal")
+	}
+	sealed, err := sealAuth(c.Auth, passphrase)
+	if err != nil {
+		return err
+	}
+	c.Auth = nil
+	c.Sealed = sealed
+	return nil
 }
 
 type createCoinbaseConfigCmd struct {
@@ -50,36 +126,28 @@ type createCoinbaseConfigCmd struct {
 	Passphrase string   `king:"name='passphrase',short='p',help='coinbasepro api passphrase'"`
 	Secret     string   `king:"name='secret',short='s',help='coinbasepro provided api secret'"`
 	Use        bool     `king:"name='use',short='s',help='set as config to use'"`
+	Seal       bool     `kong:"name='seal',help='encrypt credentials with a passphrase read from the terminal or RETICULE_PASSPHRASE'"`
+	Helper     string   `kong:"name='credential-helper',help='command that prints json credentials, used instead of stored credentials'"`
 }
 
-func (c *createCoinbaseConfigCmd) Run(fs afero.Fs) (capture error) {
+func (c *createCoinbaseConfigCmd) Run(fs afero.Fs) error {
 	configPath, err := configPath()
 	if err != nil {
 		return err
 	}
 	var configSet coinbaseProConfigSet
-	f, err := fs.OpenFile(configPath, os.O_RDWR|os.O_CREATE, 0755)
+	_, err = fs.Stat(configPath)
 	switch {
 	case errors.Is(err, os.ErrNotExist):
-		cfgDir := path.Dir(configPath)
-		err = fs.MkdirAll(cfgDir, os.ModePerm)
+		err = fs.MkdirAll(path.Dir(configPath), configDirMode)
 		if err != nil {
 			return err
 		}
 		fmt.Printf("creating config %q\n", configPath)
-		f, err = fs.Create(configPath)
-		if err != nil {
-			return err
-		}
 	case err != nil:
 		return err
 	default:
-		defer func() { coinbasepro.Capture(&capture, f.Close()) }()
-		b, err := ioutil.ReadAll(f)
-		if err != nil {
-			return err
-		}
-		err = yaml.Unmarshal(b, &configSet)
+		configSet, err = readConfigSet(fs, configPath)
 		if err != nil {
 			return err
 		}
@@ -94,6 +162,16 @@ func (c *createCoinbaseConfigCmd) Run(fs afero.Fs) (capture error) {
 			c.Key,
 			c.Passphrase,
 			c.Secret),
+		CredentialHelper: c.Helper,
+	}
+	if c.Seal {
+		passphrase, err := readNewPassphrase(fmt.Sprintf("passphrase for config %q: ", c.Name))
+		if err != nil {
+			return err
+		}
+		if err = cfg.seal(passphrase); err != nil {
+			return err
+		}
 	}
 	if configSet.Configs == nil {
 		configSet.Configs = make(map[string]coinbaseProConfig)
@@ -103,12 +181,7 @@ func (c *createCoinbaseConfigCmd) Run(fs afero.Fs) (capture error) {
 		configSet.Current = c.Name
 	}
 	configSet.Configs[c.Name] = cfg
-	enc := yaml.NewEncoder(f)
-	err = enc.Encode(&configSet)
-	if err != nil {
-		return err
-	}
-	return enc.Close()
+	return writeConfigSet(fs, configPath, configSet)
 }
 
 type deleteCoinbaseConfigCmd struct {
@@ -128,6 +201,159 @@ func (d *deleteCoinbaseConfigCmd) Run(fs afero.Fs) error {
 	return writeConfigSet(fs, configPath, configSet)
 }
 
+type listCoinbaseConfigCmd struct{}
+
+// coinbaseProConfigSummary describes a config without its credentials.
+type coinbaseProConfigSummary struct {
+	Name        string `json:"name" yaml:"name"`
+	Current     bool   `json:"current" yaml:"current"`
+	BaseURL     string `json:"base_url" yaml:"base_url"`
+	FeedURL     string `json:"feed_url" yaml:"feed_url"`
+	Credentials string `json:"credentials" yaml:"credentials"`
+}
+
+func (l *listCoinbaseConfigCmd) Run(fs afero.Fs, enc encoder) error {
+	configPath, err := configPath()
+	if err != nil {
+		return err
+	}
+	configSet, err := readConfigSet(fs, configPath)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(configSet.Configs))
+	for name := range configSet.Configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	summaries := make([]coinbaseProConfigSummary, 0, len(names))
+	for _, name := range names {
+		summaries = append(summaries, configSet.summary(name))
+	}
+	return enc.Encode(summaries)
+}
+
+type showCoinbaseConfigCmd struct {
+	Name string `kong:"name='name',short='n',help='name of config; default is the current config'"`
+}
+
+// maskedCoinbaseProConfig is a config with its secrets masked. Only the last four characters of the Key are shown,
+// enough to tell keys apart.
+type maskedCoinbaseProConfig struct {
+	coinbaseProConfigSummary `yaml:",inline"`
+	Key                      string `json:"key,omitempty" yaml:"key,omitempty"`
+	Passphrase               string `json:"passphrase,omitempty" yaml:"passphrase,omitempty"`
+	Secret                   string `json:"secret,omitempty" yaml:"secret,omitempty"`
+	CredentialHelper         string `json:"credential_helper,omitempty" yaml:"credential_helper,omitempty"`
+}
+
+func (s *showCoinbaseConfigCmd) Run(fs afero.Fs, enc encoder) error {
+	configPath, err := configPath()
+	if err != nil {
+		return err
+	}
+	configSet, err := readConfigSet(fs, configPath)
+	if err != nil {
+		return err
+	}
+	name := s.Name
+	if name == "" {
+		name = configSet.Current
+	}
+	cfg, ok := configSet.Configs[name]
+	if !ok {
+		return fmt.Errorf("coinbase config %q does not exist", name)
+	}
+	masked := maskedCoinbaseProConfig{
+		coinbaseProConfigSummary: configSet.summary(name),
+		CredentialHelper:         cfg.CredentialHelper,
+	}
+	if cfg.Auth != nil {
+		masked.Key = mask(cfg.Auth.Key, 4)
+		masked.Passphrase = mask(cfg.Auth.Passphrase, 0)
+		masked.Secret = mask(cfg.Auth.Secret, 0)
+	}
+	return enc.Encode(masked)
+}
+
+// mask replaces all but the last show characters of a secret.
+func mask(secret string, show int) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= show*2 {
+		show = 0
+	}
+	return "********" + secret[len(secret)-show:]
+}
+
+type useCoinbaseConfigCmd struct {
+	Name string `kong:"name='name',short='n',help='name of config to use',required"`
+}
+
+func (u *useCoinbaseConfigCmd) Run(fs afero.Fs) error {
+	configPath, err := configPath()
+	if err != nil {
+		return err
+	}
+	configSet, err := readConfigSet(fs, configPath)
+	if err != nil {
+		return err
+	}
+	if _, ok := configSet.Configs[u.Name]; !ok {
+		return fmt.Errorf("coinbase config %q does not exist, use `config create coinbase` to create a new config", u.Name)
+	}
+	configSet.Current = u.Name
+	if err = writeConfigSet(fs, configPath, configSet); err != nil {
+		return err
+	}
+	fmt.Printf("using config %q\n", u.Name)
+	return nil
+}
+
+type sealCoinbaseConfigCmd struct {
+	Name string `kong:"name='name',short='n',help='name of config to seal; default is every plaintext config'"`
+}
+
+// Run seals plaintext configs in place. Configs that are already sealed keep their passphrase.
+func (s *sealCoinbaseConfigCmd) Run(fs afero.Fs) error {
+	configPath, err := configPath()
+	if err != nil {
+		return err
+	}
+	configSet, err := readConfigSet(fs, configPath)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name, cfg := range configSet.Configs {
+		if (s.Name == "" || s.Name == name) && cfg.Auth != nil {
+			names = append(names, name)
+		}
+	}
+	if _, ok := configSet.Configs[s.Name]; s.Name != "" && !ok {
+		return fmt.Errorf("coinbase config %q does not exist", s.Name)
+	}
+	if len(names) == 0 {
+		fmt.Println("no plaintext configs to seal")
+		return nil
+	}
+	sort.Strings(names)
+	passphrase, err := readNewPassphrase("passphrase for sealed configs: ")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		cfg := configSet.Configs[name]
+		if err = cfg.seal(passphrase); err != nil {
+			return err
+		}
+		configSet.Configs[name] = cfg
+		fmt.Printf("sealed config %q\n", name)
+	}
+	return writeConfigSet(fs, configPath, configSet)
+}
+
 type updateCoinbaseConfigCmd struct {
 	Name       string   `kong:"name='name',short='n',help='name of config',required"`
 	BaseURL    *url.URL `kong:"name='base-url',short='b',help='url of coinbasepro api that provided key'"`
@@ -137,6 +363,8 @@ type updateCoinbaseConfigCmd struct {
 	Rename     string   `kong:"name='rename',short='r',help='new name for config'"`
 	Secret     string   `king:"name='secret',short='s',help='coinbasepro provided api secret'"`
 	Use        bool     `king:"name='use',short='s',help='set as config to use'"`
+	Seal       bool     `kong:"name='seal',help='encrypt credentials with a passphrase read from the terminal or RETICULE_PASSPHRASE'"`
+	Helper     string   `kong:"name='credential-helper',help='command that prints json credentials, used instead of stored credentials'"`
 }
 
 func (c *updateCoinbaseConfigCmd) Run(fs afero.Fs) error {
@@ -158,14 +386,48 @@ func (c *updateCoinbaseConfigCmd) Run(fs afero.Fs) error {
 	if c.FeedURL != nil {
 		cfg.FeedURL = c.FeedURL.String()
 	}
-	if c.Key != "" {
-		cfg.Auth.Key = c.Key
+	if c.Key != "" || c.Passphrase != "" || c.Secret != "" {
+		var passphrase string
+		if cfg.Sealed != "" {
+			// credentials are resealed with the passphrase that unsealed them
+			passphrase, err = readPassphrase(fmt.Sprintf("passphrase for config %q: ", c.Name))
+			if err != nil {
+				return err
+			}
+			cfg.Auth, err = openAuth(cfg.Sealed, passphrase)
+			if err != nil {
+				return err
+			}
+		}
+		if cfg.Auth == nil {
+			cfg.Auth = &coinbasepro.Auth{}
+		}
+		if c.Key != "" {
+			cfg.Auth.Key = c.Key
+		}
+		if c.Passphrase != "" {
+			cfg.Auth.Passphrase = c.Passphrase
+		}
+		if c.Secret != "" {
+			cfg.Auth.Secret = c.Secret
+		}
+		if passphrase != "" {
+			if err = cfg.seal(passphrase); err != nil {
+				return err
+			}
+		}
 	}
-	if c.Passphrase != "" {
-		cfg.Auth.Passphrase = c.Passphrase
+	if c.Seal && cfg.Sealed == "" {
+		passphrase, err := readNewPassphrase(fmt.Sprintf("passphrase for config %q: ", c.Name))
+		if err != nil {
+			return err
+		}
+		if err = cfg.seal(passphrase); err != nil {
+			return err
+		}
 	}
-	if c.Secret != "" {
-		cfg.Auth.Secret = c.Secret
+	if c.Helper != "" {
+		cfg.CredentialHelper = c.Helper
 	}
 	if c.Rename != "" {
 		delete(configSet.Configs, c.Name)
@@ -178,6 +440,18 @@ func (c *updateCoinbaseConfigCmd) Run(fs afero.Fs) error {
 	return writeConfigSet(fs, configPath, configSet)
 }
 
+// The sandbox urls are the default for new configs and for credentials from the environment without a config.
+const (
+	sandboxBaseURL = "https://api-public.sandbox.pro.coinbase.com"
+	sandboxFeedURL = "wss://ws-feed-public.sandbox.pro.coinbase.com"
+)
+
+const (
+	// configFileMode restricts the config file, which holds credentials, to the current user
+	configFileMode os.FileMode = 0600
+	configDirMode  os.FileMode = 0700
+)
+
 func configPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -209,11 +483,15 @@ func readConfigSet(fs afero.Fs, configPath string) (coinbaseProConfigSet, error)
 }
 
 func writeConfigSet(fs afero.Fs, configPath string, configSet coinbaseProConfigSet) (capture error) {
-	f, err := fs.OpenFile(configPath, os.O_WRONLY|os.O_TRUNC, 0755)
+	f, err := fs.OpenFile(configPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, configFileMode)
 	if err != nil {
 		return err
 	}
 	defer func() { coinbasepro.Capture(&capture, f.Close()) }()
+	// configs created before configFileMode was introduced were readable by everyone
+	if err = fs.Chmod(configPath, configFileMode); err != nil {
+		return err
+	}
 	enc := yaml.NewEncoder(f)
 	err = enc.Encode(&configSet)
 	if err != nil {
@@ -49,6 +49,41 @@ configs:
 		strings.TrimSpace(string(b)))
 }
 
+func TestCreateCoinbaseConfigCmd_Second(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, args := range [][]string{
+		{`--name=horatioHornblower`, `--key=key`, `--passphrase=passphrase`, `--secret=secret`},
+		{`--name=jackAubrey`, `--base-url=https://api.pro.coinbase.com`, `--credential-helper=pass coinbase`},
+	} {
+		var cli struct {
+			createCoinbaseConfigCmd
+		}
+		_, err := mustNew(t, &cli).Parse(args)
+		require.NoError(t, err)
+		require.NoError(t, cli.Run(fs))
+	}
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	configPath := home + "/.reticule/coinbasepro"
+	configSet, err := readConfigSet(fs, configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "horatioHornblower", configSet.Current)
+	require.Len(t, configSet.Configs, 2)
+	assert.Equal(t, "key", configSet.Configs["horatioHornblower"].Auth.Key)
+	assert.Equal(t, "https://api.pro.coinbase.com", configSet.Configs["jackAubrey"].BaseURL)
+	assert.Equal(t, "pass coinbase", configSet.Configs["jackAubrey"].CredentialHelper)
+	info, err := fs.Stat(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(configFileMode), info.Mode().Perm())
+
+	var cli struct {
+		createCoinbaseConfigCmd
+	}
+	_, err = mustNew(t, &cli).Parse([]string{`--name=jackAubrey`})
+	require.NoError(t, err)
+	assert.Error(t, cli.Run(fs))
+}
+
 func TestUpdateCoinbaseConfigCmd(t *testing.T) {
 	var cli struct {
 		updateCoinbaseConfigCmd
@@ -116,3 +151,63 @@ func mustNew(t *testing.T, cli interface{}, options ...kong.Option) *kong.Kong {
 	require.NoError(t, err)
 	return parser
 }
+
+func TestListShowUseCoinbaseConfigCmd(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	configPath := home + "/.reticule/coinbasepro"
+	require.NoError(t, afero.WriteFile(fs, configPath, []byte(`
+current: horatioHornblower
+configs:
+    horatioHornblower:
+        baseurl: https://api-public.sandbox.pro.coinbase.com
+        feedurl: wss://ws-feed-public.sandbox.pro.coinbase.com
+        auth:
+            key: 0123456789abcdef
+            passphrase: passphrase
+            secret: secret
+    jackAubrey:
+        baseurl: https://api.pro.coinbase.com
+        feedurl: wss://ws-feed.pro.coinbase.com
+        sealed: reticule-seal-v1:c2VhbGVk
+`), 0600))
+	t.Run("List", func(t *testing.T) {
+		var b strings.Builder
+		require.NoError(t, (&listCoinbaseConfigCmd{}).Run(fs, &Output{Output: OutputTypeYAML, w: &b}))
+		assert.Equal(t, strings.TrimSpace(`
+- name: horatioHornblower
+  current: true
+  base_url: https://api-public.sandbox.pro.coinbase.com
+  feed_url: wss://ws-feed-public.sandbox.pro.coinbase.com
+  credentials: plaintext
+- name: jackAubrey
+  current: false
+  base_url: https://api.pro.coinbase.com
+  feed_url: wss://ws-feed.pro.coinbase.com
+  credentials: sealed
+`), strings.TrimSpace(b.String()))
+	})
+	t.Run("Show", func(t *testing.T) {
+		var b strings.Builder
+		require.NoError(t, (&showCoinbaseConfigCmd{}).Run(fs, &Output{Output: OutputTypeYAML, w: &b}))
+		assert.Equal(t, strings.TrimSpace(`
+name: horatioHornblower
+current: true
+base_url: https://api-public.sandbox.pro.coinbase.com
+feed_url: wss://ws-feed-public.sandbox.pro.coinbase.com
+credentials: plaintext
+key: '********cdef'
+passphrase: '********'
+secret: '********'
+`), strings.TrimSpace(b.String()))
+		assert.Error(t, (&showCoinbaseConfigCmd{Name: "nobody"}).Run(fs, &Output{Output: OutputTypeYAML, w: &b}))
+	})
+	t.Run("Use", func(t *testing.T) {
+		require.NoError(t, (&useCoinbaseConfigCmd{Name: "jackAubrey"}).Run(fs))
+		configSet, err := readConfigSet(fs, configPath)
+		require.NoError(t, err)
+		assert.Equal(t, "jackAubrey", configSet.Current)
+		assert.Error(t, (&useCoinbaseConfigCmd{Name: "nobody"}).Run(fs))
+	})
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirm(t *testing.T) {
+	for answer, expected := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false, "y": true} {
+		var out strings.Builder
+		ok, err := confirm(strings.NewReader(answer), &out, "continue?")
+		require.NoError(t, err)
+		assert.Equal(t, expected, ok, answer)
+		assert.Equal(t, "continue? [y/N]: ", out.String())
+	}
+}
+
+func TestIsProduction(t *testing.T) {
+	for raw, expected := range map[string]bool{
+		"https://api.pro.coinbase.com":                true,
+		"https://api-public.sandbox.pro.coinbase.com": false,
+		"http://localhost:8080":                       false,
+	} {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+		assert.Equal(t, expected, isProduction(u), raw)
+	}
+}
+
+func TestCoinbaseCmd_Config(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "coinbasepro")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`
+current: horatioHornblower
+configs:
+    horatioHornblower:
+        baseurl: https://api-public.sandbox.pro.coinbase.com
+    jackAubrey:
+        baseurl: https://api.pro.coinbase.com
+`), 0600))
+	cmd := coinbaseCmd{Config: configPath}
+	name, cfg, err := cmd.config()
+	require.NoError(t, err)
+	assert.Equal(t, "horatioHornblower", name)
+	assert.Equal(t, "https://api-public.sandbox.pro.coinbase.com", cfg.BaseURL)
+	cmd.Use = "jackAubrey"
+	name, cfg, err = cmd.config()
+	require.NoError(t, err)
+	assert.Equal(t, "jackAubrey", name)
+	assert.Equal(t, "https://api.pro.coinbase.com", cfg.BaseURL)
+	cmd.Use = "nobody"
+	_, _, err = cmd.config()
+	assert.Error(t, err)
+}
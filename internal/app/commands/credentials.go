@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+)
+
+// Environment variables that provide credentials. Each may instead name a file holding the value, such as a mounted
+// Kubernetes secret, when suffixed with credentialFileSuffix.
+const (
+	keyEnv               = "RETICULE_COINBASE_KEY"
+	apiPassphraseEnv     = "RETICULE_COINBASE_PASSPHRASE"
+	secretEnv            = "RETICULE_COINBASE_SECRET"
+	credentialFileSuffix = "_FILE"
+)
+
+// Credentials resolves the coinbasepro.Auth of a config from the first of these sources that provides any credential:
+//  1. the RETICULE_COINBASE_KEY, RETICULE_COINBASE_PASSPHRASE and RETICULE_COINBASE_SECRET environment variables, or
+//     the files named by the same variables suffixed with _FILE
+//  2. a credential helper command, from --credential-helper, RETICULE_COINBASE_CREDENTIAL_HELPER or the config, run
+//     with `sh -c` that prints json {"key": "...", "passphrase": "...", "secret": "..."}
+//  3. the Auth of the config, unsealed when the config is sealed
+//
+// The source that is chosen must provide all three credentials; sources are never mixed.
+type Credentials struct {
+	CredentialHelper string `kong:"name='credential-helper',env='RETICULE_COINBASE_CREDENTIAL_HELPER',help='command that prints json credentials; overrides the config'"`
+
+	// logger warns of credentials stored in plaintext; nil logs nothing
+	logger coinbasepro.Logger
+}
+
+// environmentProvided indicates whether any credential is set in the environment.
+func (c *Credentials) environmentProvided() bool {
+	for _, env := range []string{keyEnv, apiPassphraseEnv, secretEnv} {
+		if _, ok := os.LookupEnv(env); ok {
+			return true
+		}
+		if _, ok := os.LookupEnv(env + credentialFileSuffix); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the Auth for the named config from the first source that provides it.
+func (c *Credentials) resolve(ctx context.Context, name string, cfg coinbaseProConfig) (*coinbasepro.Auth, error) {
+	if c.environmentProvided() {
+		return environmentAuth()
+	}
+	if c.CredentialHelper != "" {
+		return helperAuth(ctx, c.CredentialHelper, name)
+	}
+	return c.stored(ctx, name, cfg)
+}
+
+// stored returns the Auth for the named config from its own credential helper or credentials, ignoring the
+// environment and flags, as for the other configs of a command that spans several configs.
+func (c *Credentials) stored(ctx context.Context, name string, cfg coinbaseProConfig) (*coinbasepro.Auth, error) {
+	if cfg.CredentialHelper != "" {
+		return helperAuth(ctx, cfg.CredentialHelper, name)
+	}
+	if cfg.Auth == nil && cfg.Sealed == "" {
+		return nil, fmt.Errorf("config %q has no credentials", name)
+	}
+	if cfg.Sealed == "" && c.logger != nil {
+		c.logger.Warn("config stores credentials in plaintext, use `config seal coinbase` to encrypt them", "config", name)
+	}
+	return cfg.auth(name)
+}
+
+func environmentAuth() (*coinbasepro.Auth, error) {
+	var values []string
+	for _, env := range []string{keyEnv, apiPassphraseEnv, secretEnv} {
+		value, err := environmentCredential(env)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return coinbasepro.NewAuth(values[0], values[1], values[2]), nil
+}
+
+// environmentCredential reads the credential from the env variable or from the file named by the env variable with
+// credentialFileSuffix. Setting both is an error, as is setting neither.
+func environmentCredential(env string) (string, error) {
+	value, ok := os.LookupEnv(env)
+	file, fileOK := os.LookupEnv(env + credentialFileSuffix)
+	switch {
+	case ok && fileOK:
+		return "", fmt.Errorf("only one of %s and %s%s may be set", env, env, credentialFileSuffix)
+	case ok:
+		return value, nil
+	case fileOK:
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	default:
+		return "", fmt.Errorf("credentials from the environment require %s or %s%s", env, env, credentialFileSuffix)
+	}
+}
+
+// helperAuth runs the credential helper with the name of the config in RETICULE_CONFIG_NAME.
+func helperAuth(ctx context.Context, helper string, name string) (*coinbasepro.Auth, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", helper)
+	cmd.Env = append(os.Environ(), "RETICULE_CONFIG_NAME="+name)
+	cmd.Stderr = os.Stderr
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential helper failed: %w", err)
+	}
+	var credentials struct {
+		Key        string `json:"key"`
+		Passphrase string `json:"passphrase"`
+		Secret     string `json:"secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &credentials); err != nil {
+		return nil, fmt.Errorf("credential helper output is not json credentials: %w", err)
+	}
+	if credentials.Key == "" || credentials.Passphrase == "" || credentials.Secret == "" {
+		return nil, errors.New("credential helper must print a key, passphrase and secret")
+	}
+	return coinbasepro.NewAuth(credentials.Key, credentials.Passphrase, credentials.Secret), nil
+}
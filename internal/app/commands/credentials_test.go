@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentials(t *testing.T) {
+	ctx := context.Background()
+	stored := coinbaseProConfig{Auth: coinbasepro.NewAuth("config-key", "config-passphrase", "config-secret")}
+	helper := `echo '{"key": "helper-key", "passphrase": "helper-passphrase", "secret": "helper-secret"}'`
+	t.Run("Config", func(t *testing.T) {
+		var credentials Credentials
+		auth, err := credentials.resolve(ctx, "name", stored)
+		require.NoError(t, err)
+		assert.Equal(t, stored.Auth, auth)
+		_, err = credentials.resolve(ctx, "name", coinbaseProConfig{})
+		assert.Error(t, err)
+	})
+	t.Run("Helper", func(t *testing.T) {
+		cfg := stored
+		cfg.CredentialHelper = `exit 1`
+		credentials := Credentials{CredentialHelper: helper}
+		auth, err := credentials.resolve(ctx, "name", cfg)
+		require.NoError(t, err)
+		assert.Equal(t, coinbasepro.NewAuth("helper-key", "helper-passphrase", "helper-secret"), auth)
+		_, err = (&Credentials{}).resolve(ctx, "name", cfg)
+		assert.Error(t, err)
+		_, err = (&Credentials{CredentialHelper: `echo '{"key": "key"}'`}).resolve(ctx, "name", cfg)
+		assert.Error(t, err)
+	})
+	t.Run("Environment", func(t *testing.T) {
+		secretFile := filepath.Join(t.TempDir(), "secret")
+		require.NoError(t, ioutil.WriteFile(secretFile, []byte("file-secret\n"), 0600))
+		setenv(t, keyEnv, "env-key")
+		setenv(t, apiPassphraseEnv, "env-passphrase")
+		setenv(t, secretEnv+credentialFileSuffix, secretFile)
+		credentials := Credentials{CredentialHelper: helper}
+		auth, err := credentials.resolve(ctx, "name", stored)
+		require.NoError(t, err)
+		assert.Equal(t, coinbasepro.NewAuth("env-key", "env-passphrase", "file-secret"), auth)
+		setenv(t, secretEnv, "env-secret")
+		_, err = credentials.resolve(ctx, "name", stored)
+		assert.Error(t, err)
+	})
+	t.Run("EnvironmentIncomplete", func(t *testing.T) {
+		setenv(t, keyEnv, "env-key")
+		_, err := (&Credentials{}).resolve(ctx, "name", stored)
+		assert.Error(t, err)
+	})
+}
+
+func setenv(t *testing.T, key string, value string) {
+	t.Helper()
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() { _ = os.Unsetenv(key) })
+}
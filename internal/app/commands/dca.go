@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/durp/reticule/pkg/dca"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+type dcaCmd struct {
+	Schedule string `kong:"name='schedule',short='s',type='path',required,help='yaml file of the plans of product, funds and cadence'"`
+	Ledger   string `kong:"name='ledger',type='path',default='~/.reticule/dca.jsonl',help='json lines file of the executions of each plan, so that a restart does not repeat them'"`
+	Once     bool   `kong:"name='once',help='place the runs that are due and exit, such as from a system timer'"`
+}
+
+// Run places the runs of the plans of the schedule as they come due until interrupted, and prints each execution.
+// Orders that violate the guardrails fail, since there is no one to confirm them. With --dry-run the executions are
+// recorded in memory rather than in the ledger.
+func (d *dcaCmd) Run(ctx context.Context, cb coinbaser, enc encoder, fs afero.Fs, guard *guardrails, check *preflight) error {
+	source, err := afero.ReadFile(fs, d.Schedule)
+	if err != nil {
+		return err
+	}
+	var schedule dca.Schedule
+	if err = yaml.Unmarshal(source, &schedule); err != nil {
+		return err
+	}
+	if check.enabled {
+		fs = afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(fs), afero.NewMemMapFs())
+	}
+	ledger, err := dca.OpenLedger(fs, d.Ledger)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	if d.Once {
+		// a plan without executions starts from the run that the timer that runs the command is meant for
+		start = start.Add(-time.Minute)
+	}
+	scheduler, err := dca.NewScheduler(&guardedTrader{coinbaser: cb, guard: guard}, schedule, ledger, start)
+	if err != nil {
+		return err
+	}
+	report := func(execution dca.Execution) error { return enc.Encode(execution) }
+	if d.Once {
+		executions, err := scheduler.RunDue(ctx, time.Now())
+		for _, execution := range executions {
+			if reportErr := report(execution); reportErr != nil {
+				return reportErr
+			}
+		}
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	err = scheduler.Run(ctx, report)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// guardedTrader refuses orders that violate the guardrails.
+type guardedTrader struct {
+	coinbaser
+	guard *guardrails
+}
+
+func (g *guardedTrader) CreateLimitOrder(ctx context.Context, order coinbasepro.LimitOrder) (coinbasepro.Order, error) {
+	violations, err := g.guard.checkLimitOrder(ctx, g.coinbaser, order)
+	if err != nil {
+		return coinbasepro.Order{}, err
+	}
+	if err = g.guard.violated(violations); err != nil {
+		return coinbasepro.Order{}, err
+	}
+	return g.coinbaser.CreateLimitOrder(ctx, order)
+}
+
+func (g *guardedTrader) CreateMarketOrder(ctx context.Context, order coinbasepro.MarketOrder) (coinbasepro.Order, error) {
+	violations, err := g.guard.checkMarketOrder(ctx, g.coinbaser, order)
+	if err != nil {
+		return coinbasepro.Order{}, err
+	}
+	if err = g.guard.violated(violations); err != nil {
+		return coinbasepro.Order{}, err
+	}
+	return g.coinbaser.CreateMarketOrder(ctx, order)
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/durp/reticule/pkg/dca"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDCACmd(t *testing.T) {
+	var orders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/products/BTC-USD":
+			_, _ = w.Write([]byte(`{"id":"BTC-USD","base_currency":"BTC","quote_currency":"USD","quote_increment":"0.01"}`))
+		case "/orders/":
+			b, _ := ioutil.ReadAll(r.Body)
+			orders = append(orders, string(bytes.TrimSpace(b)))
+			_, _ = w.Write([]byte(`{"id":"o1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client, err := coinbasepro.NewClient(baseURL, baseURL, &coinbasepro.Auth{Key: "k", Passphrase: "p", Secret: "zZ=="})
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "dca.yaml", []byte("plans:\n  - product_id: BTC-USD\n    funds: 10\n    cadence: '* * * * *'\n"), 0600))
+	cmd := dcaCmd{Schedule: "dca.yaml", Ledger: "/dca/ledger.jsonl", Once: true}
+	var b bytes.Buffer
+	require.NoError(t, cmd.Run(context.Background(), client, &Output{Output: OutputTypeJSON, w: &b}, fs, &guardrails{}, &preflight{enabled: true}))
+	require.Len(t, orders, 1)
+	exists, err := afero.Exists(fs, "/dca/ledger.jsonl")
+	require.NoError(t, err)
+	assert.False(t, exists, "a dry run does not record its executions")
+
+	orders = nil
+	b.Reset()
+	require.NoError(t, cmd.Run(context.Background(), client, &Output{Output: OutputTypeJSON, w: &b}, fs, &guardrails{}, &preflight{}))
+	require.Len(t, orders, 1)
+	assert.Contains(t, orders[0], `"funds":"10"`)
+	var execution dca.Execution
+	require.NoError(t, json.Unmarshal(b.Bytes(), &execution))
+	assert.Equal(t, "o1", execution.OrderID)
+	require.NoError(t, cmd.Run(context.Background(), client, &Output{Output: OutputTypeJSON, w: &b}, fs, &guardrails{}, &preflight{}))
+	assert.Len(t, orders, 1, "the ledger keeps a run from repeating")
+
+	orders = nil
+	b.Reset()
+	cmd.Ledger = "/dca/guarded.jsonl"
+	maxNotional := decimal.NewFromInt(5)
+	require.NoError(t, cmd.Run(context.Background(), client, &Output{Output: OutputTypeJSON, w: &b}, fs, &guardrails{MaxNotional: &maxNotional}, &preflight{}))
+	assert.Empty(t, orders)
+	execution = dca.Execution{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &execution))
+	assert.Contains(t, execution.Error, "guardrails violated")
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+)
+
+// preflight is the client-side validation of create commands that --dry-run runs before printing the request it
+// would send: orders are checked against the increments and limits of their product, and every request against the
+// available balances from ListAccounts. Without --dry-run the api is left to reject invalid requests.
+type preflight struct {
+	enabled bool
+}
+
+func (p *preflight) checkLimitOrder(ctx context.Context, cb coinbaser, order coinbasepro.LimitOrder) error {
+	if !p.enabled {
+		return nil
+	}
+	if err := order.Validate(); err != nil {
+		return err
+	}
+	product, err := cb.GetProduct(ctx, order.ProductID)
+	if err != nil {
+		return err
+	}
+	if err = product.ValidateLimitOrder(order); err != nil {
+		return err
+	}
+	if order.Side == coinbasepro.SideBuy {
+		return p.checkBalance(ctx, cb, product.QuoteCurrency, order.Price.Mul(order.Size))
+	}
+	return p.checkBalance(ctx, cb, product.BaseCurrency, order.Size)
+}
+
+func (p *preflight) checkMarketOrder(ctx context.Context, cb coinbaser, order coinbasepro.MarketOrder) error {
+	if !p.enabled {
+		return nil
+	}
+	if err := order.Validate(); err != nil {
+		return err
+	}
+	product, err := cb.GetProduct(ctx, order.ProductID)
+	if err != nil {
+		return err
+	}
+	if err = product.ValidateMarketOrder(order); err != nil {
+		return err
+	}
+	switch {
+	case order.Side == coinbasepro.SideBuy && order.Funds != nil:
+		return p.checkBalance(ctx, cb, product.QuoteCurrency, *order.Funds)
+	case order.Side == coinbasepro.SideSell && order.Size != nil:
+		return p.checkBalance(ctx, cb, product.BaseCurrency, *order.Size)
+	}
+	// the order is sized in the other currency, so the balance it needs is estimated from the ticker
+	ticker, err := cb.GetProductTicker(ctx, order.ProductID)
+	if err != nil {
+		return err
+	}
+	if order.Side == coinbasepro.SideBuy {
+		return p.checkBalance(ctx, cb, product.QuoteCurrency, order.Size.Mul(ticker.Price))
+	}
+	if !ticker.Price.IsPositive() {
+		return nil
+	}
+	return p.checkBalance(ctx, cb, product.BaseCurrency, order.Funds.Div(ticker.Price))
+}
+
+// checkBalance fails when the available balance of the currency in the accounts of the profile is less than amount.
+func (p *preflight) checkBalance(ctx context.Context, cb coinbaser, currency coinbasepro.CurrencyName, amount decimal.Decimal) error {
+	if !p.enabled {
+		return nil
+	}
+	accounts, err := cb.ListAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	available := decimal.Zero
+	for _, account := range accounts {
+		if account.Currency == currency {
+			available = available.Add(account.Available)
+		}
+	}
+	if available.LessThan(amount) {
+		return fmt.Errorf("insufficient %s: %s is needed but %s is available", currency, amount, available)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreflight(t *testing.T) {
+	cb := &fakeCoinbaser{
+		price: decimal.NewFromInt(100),
+		product: coinbasepro.Product{
+			ID:             "BTC-USD",
+			BaseCurrency:   "BTC",
+			QuoteCurrency:  "USD",
+			BaseIncrement:  decimal.NewFromFloat(0.01),
+			BaseMinSize:    decimal.NewFromFloat(0.01),
+			QuoteIncrement: decimal.NewFromFloat(0.01),
+		},
+		accounts: []coinbasepro.Account{
+			{Currency: "USD", Available: decimal.NewFromInt(500)},
+			{Currency: "BTC", Available: decimal.NewFromInt(2)},
+		},
+	}
+	ctx := context.Background()
+	check := &preflight{enabled: true}
+	order := coinbasepro.LimitOrder{
+		ProductID: "BTC-USD",
+		Side:      coinbasepro.SideBuy,
+		Type:      coinbasepro.OrderTypeLimit,
+		Price:     decimal.NewFromInt(100),
+		Size:      decimal.NewFromInt(4),
+	}
+	assert.NoError(t, check.checkLimitOrder(ctx, cb, order))
+
+	order.Size = decimal.NewFromFloat(4.005)
+	assert.EqualError(t, check.checkLimitOrder(ctx, cb, order), "size 4.005 is not a multiple of increment 0.01")
+
+	order.Size = decimal.NewFromInt(6)
+	assert.EqualError(t, check.checkLimitOrder(ctx, cb, order), "insufficient USD: 600 is needed but 500 is available")
+
+	order.Side = coinbasepro.SideSell
+	assert.EqualError(t, check.checkLimitOrder(ctx, cb, order), "insufficient BTC: 6 is needed but 2 is available")
+
+	size := decimal.NewFromInt(6)
+	market := coinbasepro.MarketOrder{ProductID: "BTC-USD", Side: coinbasepro.SideBuy, Type: coinbasepro.OrderTypeMarket, Size: &size}
+	assert.EqualError(t, check.checkMarketOrder(ctx, cb, market), "insufficient USD: 600 is needed but 500 is available")
+
+	assert.NoError(t, check.checkBalance(ctx, cb, "BTC", decimal.NewFromInt(2)))
+	assert.Error(t, check.checkBalance(ctx, cb, "ETH", decimal.NewFromInt(1)))
+	assert.NoError(t, (&preflight{}).checkLimitOrder(ctx, cb, order))
+}
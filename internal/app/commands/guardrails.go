@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+)
+
+// guardrails are per-config limits on orders and withdrawals, meant to stop a fat-finger from reaching the api.
+// Unset limits are not checked. A command that violates a guardrail fails unless it is run with --force and the
+// violation is confirmed interactively.
+type guardrails struct {
+	// MaxNotional is the largest value of an order, in the quote currency of the product
+	MaxNotional *decimal.Decimal `yaml:"maxnotional,omitempty"`
+	// MaxPriceDeviation is the largest difference, in percent, between the price of a limit order and the ticker
+	MaxPriceDeviation *decimal.Decimal `yaml:"maxpricedeviation,omitempty"`
+	// DailyWithdrawal caps the amount of each currency withdrawn in any 24 hours
+	DailyWithdrawal map[coinbasepro.CurrencyName]decimal.Decimal `yaml:"dailywithdrawal,omitempty"`
+	// AllowedProducts, when not empty, lists the only products that can be ordered
+	AllowedProducts []coinbasepro.ProductID `yaml:"allowedproducts,omitempty"`
+}
+
+// guardrailOverride is embedded by the commands that guardrails check.
+type guardrailOverride struct {
+	Force bool `kong:"name='force',help='run despite guardrail violations, after interactive confirmation'"`
+}
+
+// override allows the command to proceed when there are no violations, or when they are forced and confirmed.
+func (g guardrailOverride) override(violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	message := "guardrails violated:\n  " + strings.Join(violations, "\n  ")
+	if !g.Force {
+		return fmt.Errorf("%s\nuse --force to override", message)
+	}
+	ok, err := confirm(os.Stdin, os.Stderr, message+"\noverride guardrails?")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("canceled")
+	}
+	return nil
+}
+
+// violated returns the violations as one error, or nil when there are none. Unlike override it cannot be forced, for
+// the callers that have no terminal to confirm on.
+func (g *guardrails) violated(violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("guardrails violated: %s", strings.Join(violations, "; "))
+}
+
+func (g *guardrails) checkLimitOrder(ctx context.Context, cb coinbaser, order coinbasepro.LimitOrder) ([]string, error) {
+	violations := g.checkProduct(order.ProductID)
+	if g.MaxNotional != nil {
+		violations = append(violations, g.checkNotional(order.ProductID, order.Price.Mul(order.Size))...)
+	}
+	if g.MaxPriceDeviation != nil {
+		ticker, err := cb.GetProductTicker(ctx, order.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if ticker.Price.IsPositive() {
+			deviation := order.Price.Sub(ticker.Price).Abs().Mul(decimal.NewFromInt(100)).Div(ticker.Price)
+			if deviation.GreaterThan(*g.MaxPriceDeviation) {
+				violations = append(violations, fmt.Sprintf("price %s is %s%% from ticker %s, more than max price deviation %s%%",
+					order.Price, deviation.StringFixed(2), ticker.Price, g.MaxPriceDeviation))
+			}
+		}
+	}
+	return violations, nil
+}
+
+func (g *guardrails) checkMarketOrder(ctx context.Context, cb coinbaser, order coinbasepro.MarketOrder) ([]string, error) {
+	violations := g.checkProduct(order.ProductID)
+	if g.MaxNotional == nil {
+		return violations, nil
+	}
+	switch {
+	case order.Funds != nil:
+		violations = append(violations, g.checkNotional(order.ProductID, *order.Funds)...)
+	case order.Size != nil:
+		ticker, err := cb.GetProductTicker(ctx, order.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, g.checkNotional(order.ProductID, order.Size.Mul(ticker.Price))...)
+	}
+	return violations, nil
+}
+
+// checkRebalance checks the order of each trade of the plan.
+func (g *guardrails) checkRebalance(ctx context.Context, cb coinbaser, plan coinbasepro.RebalancePlan) ([]string, error) {
+	var violations []string
+	for _, trade := range plan.Trades {
+		var tradeViolations []string
+		var err error
+		if trade.Type == coinbasepro.OrderTypeLimit {
+			tradeViolations, err = g.checkLimitOrder(ctx, cb, trade.LimitOrder())
+		} else {
+			tradeViolations, err = g.checkMarketOrder(ctx, cb, trade.MarketOrder())
+		}
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, tradeViolations...)
+	}
+	return violations, nil
+}
+
+func (g *guardrails) checkProduct(productID coinbasepro.ProductID) []string {
+	if len(g.AllowedProducts) == 0 {
+		return nil
+	}
+	for _, allowed := range g.AllowedProducts {
+		if allowed == productID {
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("product %s is not one of the allowed products %v", productID, g.AllowedProducts)}
+}
+
+func (g *guardrails) checkNotional(productID coinbasepro.ProductID, notional decimal.Decimal) []string {
+	if notional.GreaterThan(*g.MaxNotional) {
+		return []string{fmt.Sprintf("%s order value %s is more than max notional %s", productID, notional, g.MaxNotional)}
+	}
+	return nil
+}
+
+// checkWithdrawal adds the amount to the withdrawals of the currency completed or pending in the last 24 hours and
+// compares the total to the DailyWithdrawal cap. Transfers between profiles are not withdrawals from Coinbase and are
+// not counted.
+func (g *guardrails) checkWithdrawal(ctx context.Context, cb coinbaser, currency coinbasepro.CurrencyName, amount decimal.Decimal) ([]string, error) {
+	limit, ok := g.DailyWithdrawal[currency]
+	if !ok {
+		return nil, nil
+	}
+	since := time.Now().Add(-24 * time.Hour)
+	total := amount
+	filter := coinbasepro.WithdrawalFilter{Type: coinbasepro.WithdrawalTypeWithdraw}
+	pagination := coinbasepro.PaginationParams{Limit: 100}
+	for {
+		withdrawals, err := cb.GetWithdrawals(ctx, filter, pagination)
+		if err != nil {
+			return nil, err
+		}
+		recent := true
+		for _, withdrawal := range withdrawals.Withdrawals {
+			if withdrawal.CreatedAt.Time().Before(since) {
+				recent = false
+				break
+			}
+			if withdrawal.Currency == currency && withdrawal.CanceledAt == nil {
+				total = total.Add(withdrawal.Amount)
+			}
+		}
+		page := withdrawals.Page
+		if !recent || len(withdrawals.Withdrawals) == 0 || page == nil || page.After == "" || page.After == pagination.After {
+			break
+		}
+		pagination.After = page.After
+	}
+	if total.GreaterThan(limit) {
+		return []string{fmt.Sprintf("%s withdrawals of %s in 24 hours would be more than daily withdrawal cap %s", currency, total, limit)}, nil
+	}
+	return nil, nil
+}
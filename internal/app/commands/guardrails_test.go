@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCoinbaser answers the calls made by guardrails and preflight; other calls panic on the nil embedded interface.
+type fakeCoinbaser struct {
+	coinbaser
+	price       decimal.Decimal
+	withdrawals []*coinbasepro.Withdrawal
+	product     coinbasepro.Product
+	accounts    []coinbasepro.Account
+}
+
+func (g *fakeCoinbaser) GetProduct(_ context.Context, _ coinbasepro.ProductID) (coinbasepro.Product, error) {
+	return g.product, nil
+}
+
+func (g *fakeCoinbaser) ListAccounts(_ context.Context) ([]coinbasepro.Account, error) {
+	return g.accounts, nil
+}
+
+func (g *fakeCoinbaser) GetProductTicker(_ context.Context, _ coinbasepro.ProductID) (coinbasepro.ProductTicker, error) {
+	return coinbasepro.ProductTicker{Price: g.price}, nil
+}
+
+func (g *fakeCoinbaser) GetWithdrawals(_ context.Context, _ coinbasepro.WithdrawalFilter, _ coinbasepro.PaginationParams) (coinbasepro.Withdrawals, error) {
+	return coinbasepro.Withdrawals{Withdrawals: g.withdrawals}, nil
+}
+
+func TestGuardrails_CheckLimitOrder(t *testing.T) {
+	maxNotional := decimal.NewFromInt(1000)
+	maxDeviation := decimal.NewFromInt(5)
+	g := &guardrails{
+		MaxNotional:       &maxNotional,
+		MaxPriceDeviation: &maxDeviation,
+		AllowedProducts:   []coinbasepro.ProductID{"BTC-USD"},
+	}
+	cb := &fakeCoinbaser{price: decimal.NewFromInt(100)}
+	ctx := context.Background()
+
+	violations, err := g.checkLimitOrder(ctx, cb, coinbasepro.LimitOrder{ProductID: "BTC-USD", Price: decimal.NewFromInt(102), Size: decimal.NewFromInt(5)})
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	violations, err = g.checkLimitOrder(ctx, cb, coinbasepro.LimitOrder{ProductID: "ETH-USD", Price: decimal.NewFromInt(200), Size: decimal.NewFromInt(10)})
+	require.NoError(t, err)
+	assert.Len(t, violations, 3)
+}
+
+func TestGuardrails_CheckMarketOrder(t *testing.T) {
+	maxNotional := decimal.NewFromInt(1000)
+	g := &guardrails{MaxNotional: &maxNotional}
+	cb := &fakeCoinbaser{price: decimal.NewFromInt(100)}
+	ctx := context.Background()
+
+	funds := decimal.NewFromInt(500)
+	violations, err := g.checkMarketOrder(ctx, cb, coinbasepro.MarketOrder{ProductID: "BTC-USD", Funds: &funds})
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	size := decimal.NewFromInt(11)
+	violations, err = g.checkMarketOrder(ctx, cb, coinbasepro.MarketOrder{ProductID: "BTC-USD", Size: &size})
+	require.NoError(t, err)
+	assert.Len(t, violations, 1)
+}
+
+func TestGuardrails_CheckWithdrawal(t *testing.T) {
+	g := &guardrails{DailyWithdrawal: map[coinbasepro.CurrencyName]decimal.Decimal{"BTC": decimal.NewFromInt(1)}}
+	now := time.Now()
+	canceled := coinbasepro.Time(now)
+	cb := &fakeCoinbaser{withdrawals: []*coinbasepro.Withdrawal{
+		{Currency: "BTC", Amount: decimal.NewFromFloat(0.5), CreatedAt: coinbasepro.Time(now.Add(-time.Hour))},
+		{Currency: "BTC", Amount: decimal.NewFromInt(1), CreatedAt: coinbasepro.Time(now.Add(-2 * time.Hour)), CanceledAt: &canceled},
+		{Currency: "ETH", Amount: decimal.NewFromInt(10), CreatedAt: coinbasepro.Time(now.Add(-3 * time.Hour))},
+		{Currency: "BTC", Amount: decimal.NewFromInt(1), CreatedAt: coinbasepro.Time(now.Add(-48 * time.Hour))},
+	}}
+	ctx := context.Background()
+
+	violations, err := g.checkWithdrawal(ctx, cb, "BTC", decimal.NewFromFloat(0.5))
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	violations, err = g.checkWithdrawal(ctx, cb, "BTC", decimal.NewFromFloat(0.6))
+	require.NoError(t, err)
+	assert.Len(t, violations, 1)
+
+	violations, err = g.checkWithdrawal(ctx, cb, "ETH", decimal.NewFromInt(100))
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestGuardrailOverride(t *testing.T) {
+	assert.NoError(t, guardrailOverride{}.override(nil))
+	assert.Error(t, guardrailOverride{}.override([]string{"too big"}))
+}
+
+func TestGuardrails_Violated(t *testing.T) {
+	g := &guardrails{}
+	assert.NoError(t, g.violated(nil))
+	assert.EqualError(t, g.violated([]string{"too big", "not allowed"}), "guardrails violated: too big; not allowed")
+}
@@ -0,0 +1,536 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// jsonPath is a kubectl style JSONPath template: text with expressions in braces, such as
+//
+//	{.orders[*].id}
+//	{range .[*]}{.currency}{"\t"}{.available}{"\n"}{end}
+//	{[?(@.currency=="USD")].available}
+//
+// Expressions select from the json representation of a value with `.name`, `..name` (recursive descent), `.*` and
+// `[*]` (wildcards), `[n]` (index, negative from the end), `[start:end]` (slice) and `[?(@.path op literal)]`
+// (filter, op one of == != < <= > >=; both sides are compared as decimals when they can be, else as strings). An
+// expression starts at the current value, or at the root with `$`. The results of an expression are printed
+// separated by spaces.
+type jsonPath struct {
+	nodes []jsonPathNode
+}
+
+type jsonPathNode struct {
+	// text is printed as is when expr is nil
+	text string
+	expr *jsonPathExpr
+	// body is printed for each result of expr when the node is a range
+	body    []jsonPathNode
+	isRange bool
+}
+
+type jsonPathExpr struct {
+	root  bool
+	steps []jsonPathStep
+}
+
+type jsonPathStepKind int
+
+const (
+	jsonPathChild jsonPathStepKind = iota
+	jsonPathWildcard
+	jsonPathDescent
+	jsonPathIndex
+	jsonPathSlice
+	jsonPathFilter
+)
+
+type jsonPathStep struct {
+	kind       jsonPathStepKind
+	name       string
+	index      int
+	start, end *int
+	filter     *jsonPathFilterExpr
+}
+
+type jsonPathFilterExpr struct {
+	path    jsonPathExpr
+	op      string
+	literal string
+}
+
+// parseJSONPath parses a template. Braces may be omitted around a template that is a single expression.
+func parseJSONPath(template string) (*jsonPath, error) {
+	if !strings.Contains(template, "{") {
+		template = "{" + template + "}"
+	}
+	actions, err := splitJSONPath(template)
+	if err != nil {
+		return nil, err
+	}
+	nodes, rest, err := parseJSONPathNodes(actions, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("jsonpath has {end} without {range}")
+	}
+	return &jsonPath{nodes: nodes}, nil
+}
+
+// jsonPathAction is literal text or the contents of braces.
+type jsonPathAction struct {
+	text   string
+	action bool
+}
+
+func splitJSONPath(template string) ([]jsonPathAction, error) {
+	var actions []jsonPathAction
+	for len(template) > 0 {
+		open := strings.IndexByte(template, '{')
+		if open < 0 {
+			actions = append(actions, jsonPathAction{text: template})
+			break
+		}
+		if open > 0 {
+			actions = append(actions, jsonPathAction{text: template[:open]})
+		}
+		end := matching(template, open, '{', '}')
+		if end < 0 {
+			return nil, fmt.Errorf("jsonpath has unclosed action %q", template[open:])
+		}
+		actions = append(actions, jsonPathAction{text: strings.TrimSpace(template[open+1 : end]), action: true})
+		template = template[end+1:]
+	}
+	return actions, nil
+}
+
+// matching returns the index of the close that matches the open at s[start], skipping quoted strings, or -1.
+func matching(s string, start int, open byte, close byte) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == open:
+			depth++
+		case c == close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseJSONPathNodes(actions []jsonPathAction, inRange bool) ([]jsonPathNode, []jsonPathAction, error) {
+	var nodes []jsonPathNode
+	for len(actions) > 0 {
+		action := actions[0]
+		actions = actions[1:]
+		switch {
+		case !action.action:
+			nodes = append(nodes, jsonPathNode{text: action.text})
+		case action.text == "end":
+			if !inRange {
+				return nil, nil, errors.New("jsonpath has {end} without {range}")
+			}
+			return nodes, append([]jsonPathAction{action}, actions...), nil
+		case strings.HasPrefix(action.text, "range "):
+			expr, err := parseJSONPathExpr(strings.TrimSpace(strings.TrimPrefix(action.text, "range ")))
+			if err != nil {
+				return nil, nil, err
+			}
+			body, rest, err := parseJSONPathNodes(actions, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(rest) == 0 {
+				return nil, nil, errors.New("jsonpath has {range} without {end}")
+			}
+			nodes = append(nodes, jsonPathNode{expr: &expr, body: body, isRange: true})
+			actions = rest[1:]
+		case strings.HasPrefix(action.text, `"`) || strings.HasPrefix(action.text, "'"):
+			text, err := unquote(action.text)
+			if err != nil {
+				return nil, nil, fmt.Errorf("jsonpath has invalid string %s: %w", action.text, err)
+			}
+			nodes = append(nodes, jsonPathNode{text: text})
+		default:
+			expr, err := parseJSONPathExpr(action.text)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, jsonPathNode{expr: &expr})
+		}
+	}
+	return nodes, nil, nil
+}
+
+func parseJSONPathExpr(s string) (jsonPathExpr, error) {
+	var expr jsonPathExpr
+	original := s
+	switch {
+	case strings.HasPrefix(s, "$"):
+		expr.root = true
+		s = s[1:]
+	case strings.HasPrefix(s, "@"):
+		s = s[1:]
+	}
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			name, rest := jsonPathName(s[2:])
+			if name == "" {
+				return expr, fmt.Errorf("jsonpath %q has .. without a name", original)
+			}
+			expr.steps = append(expr.steps, jsonPathStep{kind: jsonPathDescent, name: name})
+			s = rest
+		case strings.HasPrefix(s, ".*"):
+			expr.steps = append(expr.steps, jsonPathStep{kind: jsonPathWildcard})
+			s = s[2:]
+		case strings.HasPrefix(s, "."):
+			name, rest := jsonPathName(s[1:])
+			if name != "" {
+				expr.steps = append(expr.steps, jsonPathStep{kind: jsonPathChild, name: name})
+			}
+			s = rest
+		case strings.HasPrefix(s, "["):
+			end := matching(s, 0, '[', ']')
+			if end < 0 {
+				return expr, fmt.Errorf("jsonpath %q has unclosed [", original)
+			}
+			step, err := parseJSONPathSubscript(strings.TrimSpace(s[1:end]))
+			if err != nil {
+				return expr, fmt.Errorf("jsonpath %q: %w", original, err)
+			}
+			expr.steps = append(expr.steps, step)
+			s = s[end+1:]
+		default:
+			return expr, fmt.Errorf("jsonpath %q is invalid at %q", original, s)
+		}
+	}
+	return expr, nil
+}
+
+func jsonPathName(s string) (string, string) {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+func parseJSONPathSubscript(s string) (jsonPathStep, error) {
+	switch {
+	case s == "*":
+		return jsonPathStep{kind: jsonPathWildcard}, nil
+	case strings.HasPrefix(s, "?(") && strings.HasSuffix(s, ")"):
+		filter, err := parseJSONPathFilter(strings.TrimSpace(s[2 : len(s)-1]))
+		if err != nil {
+			return jsonPathStep{}, err
+		}
+		return jsonPathStep{kind: jsonPathFilter, filter: &filter}, nil
+	case strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'"):
+		name, err := unquote(s)
+		if err != nil {
+			return jsonPathStep{}, err
+		}
+		return jsonPathStep{kind: jsonPathChild, name: name}, nil
+	case strings.Contains(s, ":"):
+		parts := strings.SplitN(s, ":", 2)
+		step := jsonPathStep{kind: jsonPathSlice}
+		for i, part := range parts {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return jsonPathStep{}, fmt.Errorf("invalid slice [%s]", s)
+			}
+			if i == 0 {
+				step.start = &n
+			} else {
+				step.end = &n
+			}
+		}
+		return step, nil
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return jsonPathStep{}, fmt.Errorf("invalid subscript [%s]", s)
+		}
+		return jsonPathStep{kind: jsonPathIndex, index: n}, nil
+	}
+}
+
+var jsonPathOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func parseJSONPathFilter(s string) (jsonPathFilterExpr, error) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+			continue
+		}
+		for _, op := range jsonPathOperators {
+			if !strings.HasPrefix(s[i:], op) {
+				continue
+			}
+			path, err := parseJSONPathExpr(strings.TrimSpace(s[:i]))
+			if err != nil {
+				return jsonPathFilterExpr{}, err
+			}
+			literal := strings.TrimSpace(s[i+len(op):])
+			if strings.HasPrefix(literal, `"`) || strings.HasPrefix(literal, "'") {
+				if literal, err = unquote(literal); err != nil {
+					return jsonPathFilterExpr{}, err
+				}
+			}
+			return jsonPathFilterExpr{path: path, op: op, literal: literal}, nil
+		}
+	}
+	path, err := parseJSONPathExpr(s)
+	return jsonPathFilterExpr{path: path}, err
+}
+
+// unquote unquotes a double or single quoted string.
+func unquote(s string) (string, error) {
+	if strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") && len(s) > 1 {
+		s = `"` + strings.ReplaceAll(s[1:len(s)-1], `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(s)
+}
+
+// Execute writes the template for the json representation of value.
+func (j *jsonPath) Execute(w io.Writer, value interface{}) error {
+	data, err := generic(value)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	j.execute(&buf, j.nodes, data, data)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func (j *jsonPath) execute(buf *bytes.Buffer, nodes []jsonPathNode, root interface{}, current interface{}) {
+	for _, node := range nodes {
+		switch {
+		case node.expr == nil:
+			buf.WriteString(node.text)
+		case node.isRange:
+			for _, result := range node.expr.evaluate(root, current) {
+				j.execute(buf, node.body, root, result)
+			}
+		default:
+			results := node.expr.evaluate(root, current)
+			texts := make([]string, len(results))
+			for i, result := range results {
+				texts[i] = jsonPathText(result)
+			}
+			buf.WriteString(strings.Join(texts, " "))
+		}
+	}
+}
+
+func (e jsonPathExpr) evaluate(root interface{}, current interface{}) []interface{} {
+	values := []interface{}{current}
+	if e.root {
+		values = []interface{}{root}
+	}
+	for _, step := range e.steps {
+		var next []interface{}
+		for _, value := range values {
+			next = append(next, step.apply(root, value)...)
+		}
+		values = next
+	}
+	return values
+}
+
+func (s jsonPathStep) apply(root interface{}, value interface{}) []interface{} {
+	switch s.kind {
+	case jsonPathChild:
+		if m, ok := value.(map[string]interface{}); ok {
+			if child, ok := m[s.name]; ok {
+				return []interface{}{child}
+			}
+		}
+		return nil
+	case jsonPathWildcard:
+		return children(value)
+	case jsonPathDescent:
+		var results []interface{}
+		if m, ok := value.(map[string]interface{}); ok {
+			if child, ok := m[s.name]; ok {
+				results = append(results, child)
+			}
+		}
+		for _, child := range children(value) {
+			results = append(results, s.apply(root, child)...)
+		}
+		return results
+	case jsonPathIndex:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		index := s.index
+		if index < 0 {
+			index += len(list)
+		}
+		if index < 0 || index >= len(list) {
+			return nil
+		}
+		return []interface{}{list[index]}
+	case jsonPathSlice:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		start, end := 0, len(list)
+		if s.start != nil {
+			start = bound(*s.start, len(list))
+		}
+		if s.end != nil {
+			end = bound(*s.end, len(list))
+		}
+		if start >= end {
+			return nil
+		}
+		return list[start:end]
+	case jsonPathFilter:
+		var results []interface{}
+		for _, child := range children(value) {
+			if s.filter.matches(root, child) {
+				results = append(results, child)
+			}
+		}
+		return results
+	}
+	return nil
+}
+
+func bound(n int, length int) int {
+	if n < 0 {
+		n += length
+	}
+	if n < 0 {
+		return 0
+	}
+	if n > length {
+		return length
+	}
+	return n
+}
+
+// children are the elements of a list or the values of a map, ordered by key.
+func children(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = v[key]
+		}
+		return values
+	}
+	return nil
+}
+
+func (f jsonPathFilterExpr) matches(root interface{}, value interface{}) bool {
+	results := f.path.evaluate(root, value)
+	if f.op == "" {
+		return len(results) > 0
+	}
+	if len(results) == 0 {
+		return false
+	}
+	return compare(jsonPathText(results[0]), f.op, f.literal)
+}
+
+// compare compares a and b as decimals when both are decimals, and otherwise as strings.
+func compare(a string, op string, b string) bool {
+	c := strings.Compare(a, b)
+	if x, err := decimal.NewFromString(a); err == nil {
+		if y, err := decimal.NewFromString(b); err == nil {
+			c = x.Cmp(y)
+		}
+	}
+	switch op {
+	case "==", "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func jsonPathText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// generic is the json representation of value as maps, lists and scalars, with numbers kept as json.Number.
+func generic(value interface{}) (interface{}, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var data interface{}
+	return data, decoder.Decode(&data)
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPath(t *testing.T) {
+	accounts := []coinbasepro.Account{
+		{ID: "a", Currency: "USD", Available: decimal.NewFromFloat(100.5)},
+		{ID: "b", Currency: "BTC", Available: decimal.NewFromInt(2)},
+		{ID: "c", Currency: "ETH", Available: decimal.Zero},
+	}
+	orders := coinbasepro.Orders{Orders: []*coinbasepro.Order{{ID: "1"}, {ID: "2"}, {ID: "3"}}}
+	tests := []struct {
+		name     string
+		template string
+		value    interface{}
+		expected string
+	}{
+		{"Wildcard", "{.orders[*].id}", orders, "1 2 3"},
+		{"NoBraces", ".orders[*].id", orders, "1 2 3"},
+		{"Index", "{.orders[-1].id}", orders, "3"},
+		{"Slice", "{.orders[0:2].id}", orders, "1 2"},
+		{"Descent", "{..id}", orders, "1 2 3"},
+		{"FilterString", `{[?(@.currency=="USD")].available}`, accounts, "100.5"},
+		{"FilterDecimal", "{[?(@.available>0)].currency}", accounts, "USD BTC"},
+		{"Range", `{range [*]}{.currency}{"\t"}{.available}{"\n"}{end}`, accounts, "USD\t100.5\nBTC\t2\nETH\t0\n"},
+		{"Root", `{range .orders[*]}{.id}/{$.orders[0].id} {end}`, orders, "1/1 2/1 3/1 "},
+		{"Text", "id: {.id}", accounts[0], "id: a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jsonPath, err := parseJSONPath(test.template)
+			require.NoError(t, err)
+			var buf bytes.Buffer
+			require.NoError(t, jsonPath.Execute(&buf, test.value))
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+	for _, invalid := range []string{"{.orders[*}", "{range .orders[*]}{.id}", "{end}", "{.orders[x]}", "{orders}"} {
+		_, err := parseJSONPath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestOutput_Template(t *testing.T) {
+	orders := coinbasepro.Orders{Orders: []*coinbasepro.Order{{ID: "1"}, {ID: "2"}}}
+	t.Run("JSONPath", func(t *testing.T) {
+		var w bytes.Buffer
+		o := Output{Output: "jsonpath={.orders[*].id}", w: &w}
+		require.NoError(t, o.Output.Validate())
+		require.NoError(t, o.Encode(orders))
+		assert.Equal(t, "1 2\n", w.String())
+	})
+	t.Run("GoTemplate", func(t *testing.T) {
+		var w bytes.Buffer
+		o := Output{Output: `go-template={{range .orders}}{{.id}}{{"\n"}}{{end}}`, w: &w}
+		require.NoError(t, o.Output.Validate())
+		require.NoError(t, o.Encode(orders))
+		assert.Equal(t, "1\n2\n", w.String())
+	})
+	t.Run("Validate", func(t *testing.T) {
+		assert.NoError(t, OutputTypeTable.Validate())
+		assert.Error(t, OutputType("xml").Validate())
+		assert.Error(t, OutputType("json=x").Validate())
+		assert.Error(t, OutputType("go-template={{.id").Validate())
+	})
+}
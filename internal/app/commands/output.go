@@ -1,18 +1,26 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
 
 	"github.com/alecthomas/kong"
 	"gopkg.in/yaml.v2"
 )
 
 type Output struct {
-	Output OutputType `kong:"name='output',short='o',default='json',enum='json,yaml,none'"`
-	w      io.Writer
+	Output  OutputType  `kong:"name='output',short='o',default='json',help='one of json, yaml, table, csv, tsv, none, jsonpath=<template> or go-template=<template>'"`
+	Columns []string    `kong:"name='columns',help='fields of table, csv and tsv output, by json name; nested fields are joined with a dot'"`
+	Where   []whereExpr `kong:"name='where',help='only output list elements whose field matches, such as balance>0 or side=buy; op one of == = != < <= > >='"`
+	SortBy  string      `kong:"name='sort-by',help='sort list output by a field, descending when prefixed with -'"`
+	w       io.Writer
 }
 
 func (o *Output) BeforeApply(ktx *kong.Context) error {
@@ -28,6 +36,12 @@ type encoder interface {
 	Encode(v interface{}) error
 }
 
+// streamer is implemented by an encoder that can encode a stream of values, such as the messages of a feed, with one
+// line per value.
+type streamer interface {
+	Stream() encoder
+}
+
 type OutputType string
 
 const (
@@ -37,11 +51,57 @@ const (
 	OutputTypeJSON OutputType = "json"
 	// OutputTypeYAML encodes all output to yaml
 	OutputTypeYAML OutputType = "yaml"
+	// OutputTypeTable encodes all output to aligned columns
+	OutputTypeTable OutputType = "table"
+	// OutputTypeCSV encodes all output to comma separated values
+	OutputTypeCSV OutputType = "csv"
+	// OutputTypeTSV encodes all output to tab separated values
+	OutputTypeTSV OutputType = "tsv"
+	// OutputTypeJSONPath writes the kubectl style JSONPath template that follows `jsonpath=` for all output
+	OutputTypeJSONPath OutputType = "jsonpath"
+	// OutputTypeGoTemplate executes the go text/template that follows `go-template=` for all output
+	OutputTypeGoTemplate OutputType = "go-template"
 )
 
+// split separates the OutputType from the template of jsonpath and go-template output.
+func (o OutputType) split() (OutputType, string) {
+	parts := strings.SplitN(string(o), "=", 2)
+	if len(parts) == 1 {
+		return o, ""
+	}
+	return OutputType(parts[0]), parts[1]
+}
+
+// Validate checks the OutputType and parses its template, so that a bad template fails before any request is made.
+func (o OutputType) Validate() error {
+	outputType, template := o.split()
+	switch outputType {
+	case OutputTypeNone, OutputTypeJSON, OutputTypeYAML, OutputTypeTable, OutputTypeCSV, OutputTypeTSV:
+		if template != "" {
+			return fmt.Errorf("output %s does not take a template", outputType)
+		}
+		return nil
+	case OutputTypeJSONPath:
+		_, err := parseJSONPath(template)
+		return err
+	case OutputTypeGoTemplate:
+		_, err := parseGoTemplate(template)
+		return err
+	default:
+		return fmt.Errorf("output must be one of json, yaml, table, csv, tsv, none, jsonpath=<template> or go-template=<template> but got %q", o)
+	}
+}
+
 func (o *Output) Encode(value interface{}) (capture error) {
 	var encoder encoder
-	switch o.Output {
+	outputType, template := o.Output.split()
+	if outputType != OutputTypeNone {
+		var err error
+		if value, err = query(value, o.Where, o.SortBy); err != nil {
+			return err
+		}
+	}
+	switch outputType {
 	case OutputTypeNone:
 		encoder = noopEncoder{}
 	case OutputTypeJSON:
@@ -50,14 +110,202 @@ func (o *Output) Encode(value interface{}) (capture error) {
 		encoder = jsonEncoder
 	case OutputTypeYAML:
 		encoder = yaml.NewEncoder(o.w)
+	case OutputTypeTable:
+		encoder = tableEncoder{w: o.w, columns: o.Columns}
+	case OutputTypeCSV:
+		encoder = separatedEncoder{w: o.w, comma: ',', columns: o.Columns}
+	case OutputTypeTSV:
+		encoder = separatedEncoder{w: o.w, comma: '\t', columns: o.Columns}
+	case OutputTypeJSONPath:
+		jsonPath, err := parseJSONPath(template)
+		if err != nil {
+			return err
+		}
+		encoder = templateEncoder{w: o.w, template: jsonPath}
+	case OutputTypeGoTemplate:
+		goTemplate, err := parseGoTemplate(template)
+		if err != nil {
+			return err
+		}
+		encoder = templateEncoder{w: o.w, template: goTemplate}
 	default:
 		panic(fmt.Sprintf("no encoder defined for output %s", o.Output))
 	}
 	return encoder.Encode(value)
 }
 
+// Stream returns an encoder that writes one line per value: compact json, or a row of table, csv or tsv output under
+// a header written once. Values that do not match --where are skipped.
+func (o *Output) Stream() encoder {
+	var stream encoder = o
+	outputType, _ := o.Output.split()
+	switch outputType {
+	case OutputTypeJSON:
+		stream = json.NewEncoder(o.w)
+	case OutputTypeTable, OutputTypeCSV, OutputTypeTSV:
+		stream = &streamEncoder{output: o}
+	}
+	if len(o.Where) > 0 {
+		return whereEncoder{where: o.Where, encoder: stream}
+	}
+	return stream
+}
+
+// templateEncoder writes a jsonpath or go-template template for each value, followed by a newline unless the
+// template ends with one.
+type templateEncoder struct {
+	w        io.Writer
+	template interface {
+		Execute(w io.Writer, value interface{}) error
+	}
+}
+
+func (t templateEncoder) Encode(value interface{}) error {
+	var buf bytes.Buffer
+	if err := t.template.Execute(&buf, value); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err := t.w.Write(buf.Bytes())
+	return err
+}
+
+// goTemplate executes a text/template with the json representation of a value, so that fields are named as in json
+// output: {{range .orders}}{{.id}}{{"\n"}}{{end}}.
+type goTemplate struct {
+	template *template.Template
+}
+
+func parseGoTemplate(text string) (*goTemplate, error) {
+	t, err := template.New("output").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &goTemplate{template: t}, nil
+}
+
+func (g *goTemplate) Execute(w io.Writer, value interface{}) error {
+	data, err := generic(value)
+	if err != nil {
+		return err
+	}
+	return g.template.Execute(w, data)
+}
+
 type noopEncoder struct{}
 
 func (n noopEncoder) Encode(_ interface{}) error {
 	return nil
 }
+
+// tableEncoder writes the records of each value as columns aligned under an upper case header.
+type tableEncoder struct {
+	w       io.Writer
+	columns []string
+}
+
+func (t tableEncoder) Encode(value interface{}) error {
+	rs := records(value)
+	columns := selectColumns(value, rs, t.columns)
+	tw := tabwriter.NewWriter(t.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header(columns), "\t"))
+	for _, r := range rs {
+		fmt.Fprintln(tw, strings.Join(r.row(columns), "\t"))
+	}
+	return tw.Flush()
+}
+
+// separatedEncoder writes the records of each value as csv, or as tsv when comma is a tab.
+type separatedEncoder struct {
+	w       io.Writer
+	comma   rune
+	columns []string
+}
+
+func (s separatedEncoder) Encode(value interface{}) error {
+	rs := records(value)
+	columns := selectColumns(value, rs, s.columns)
+	cw := csv.NewWriter(s.w)
+	cw.Comma = s.comma
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, r := range rs {
+		if err := cw.Write(r.row(columns)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// streamEncoder chooses the columns from the first value and writes each record as soon as it is encoded. Table
+// columns cannot be aligned ahead of time, so each is padded to the widest value seen so far.
+type streamEncoder struct {
+	output  *Output
+	columns []string
+	widths  []int
+	csv     *csv.Writer
+}
+
+func (s *streamEncoder) Encode(value interface{}) error {
+	rs := records(value)
+	if s.columns == nil {
+		s.columns = selectColumns(value, rs, s.output.Columns)
+		if s.output.Output == OutputTypeTable {
+			s.widths = make([]int, len(s.columns))
+			if err := s.writeTableRow(header(s.columns)); err != nil {
+				return err
+			}
+		} else {
+			s.csv = csv.NewWriter(s.output.w)
+			if s.output.Output == OutputTypeTSV {
+				s.csv.Comma = '\t'
+			}
+			if err := s.writeSeparatedRow(s.columns); err != nil {
+				return err
+			}
+		}
+	}
+	for _, r := range rs {
+		var err error
+		if s.csv == nil {
+			err = s.writeTableRow(r.row(s.columns))
+		} else {
+			err = s.writeSeparatedRow(r.row(s.columns))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *streamEncoder) writeTableRow(row []string) error {
+	for i, cell := range row {
+		if len(cell) > s.widths[i] {
+			s.widths[i] = len(cell)
+		}
+		row[i] = cell + strings.Repeat(" ", s.widths[i]-len(cell))
+	}
+	_, err := fmt.Fprintln(s.output.w, strings.TrimRight(strings.Join(row, "  "), " "))
+	return err
+}
+
+func (s *streamEncoder) writeSeparatedRow(row []string) error {
+	if err := s.csv.Write(row); err != nil {
+		return err
+	}
+	s.csv.Flush()
+	return s.csv.Error()
+}
+
+func header(columns []string) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = strings.ToUpper(column)
+	}
+	return names
+}
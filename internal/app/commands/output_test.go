@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -60,6 +63,20 @@ func TestOutput(t *testing.T) {
 			err = cli.Output.Encode(s)
 			require.NoError(t, err)
 		})
+		t.Run("Table", func(t *testing.T) {
+			var w bytes.Buffer
+			k := newParser(&w)
+			_, err := k.Parse([]string{`--output=table`})
+			require.NoError(t, err)
+			err = cli.Output.Encode(s)
+			require.NoError(t, err)
+			assert.Equal(t, "STRING\nstring\n", w.String())
+		})
+		t.Run("InvalidTemplate", func(t *testing.T) {
+			k := newParser(nil)
+			_, err := k.Parse([]string{`--output=jsonpath={.orders[`})
+			assert.Error(t, err)
+		})
 		t.Run("EncodeNoOutputTypePanic", func(t *testing.T) {
 			assert.Panics(t, func() {
 				cli.Output.Output = "blah"
@@ -68,3 +85,55 @@ func TestOutput(t *testing.T) {
 		})
 	})
 }
+
+func TestOutput_Tabular(t *testing.T) {
+	created := coinbasepro.Time(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	funds := decimal.NewFromInt(10)
+	price := decimal.NewFromInt(100)
+	orders := coinbasepro.Orders{
+		Orders: []*coinbasepro.Order{
+			{ID: "1", ProductID: "BTC-USD", Side: coinbasepro.SideBuy, Type: coinbasepro.OrderTypeLimit, Size: decimal.NewFromFloat(0.5), Price: &price, Status: "open", CreatedAt: created},
+			{ID: "22", ProductID: "ETH-USD", Side: coinbasepro.SideSell, Type: coinbasepro.OrderTypeMarket, Funds: &funds, Status: "done", CreatedAt: created},
+		},
+	}
+	t.Run("Table", func(t *testing.T) {
+		var w bytes.Buffer
+		o := Output{Output: OutputTypeTable, w: &w}
+		require.NoError(t, o.Encode(orders))
+		assert.Equal(t, ""+
+			"ID  PRODUCT_ID  SIDE  TYPE    SIZE  PRICE  FUNDS  FILLED_SIZE  STATUS  CREATED_AT\n"+
+			"1   BTC-USD     buy   limit   0.5   100                        open    2021-01-02T03:04:05Z\n"+
+			"22  ETH-USD     sell  market  0            10                  done    2021-01-02T03:04:05Z\n", w.String())
+	})
+	t.Run("CSVColumns", func(t *testing.T) {
+		var w bytes.Buffer
+		o := Output{Output: OutputTypeCSV, Columns: []string{"id", "status"}, w: &w}
+		require.NoError(t, o.Encode(orders))
+		assert.Equal(t, "id,status\n1,open\n22,done\n", w.String())
+	})
+	t.Run("TSVNested", func(t *testing.T) {
+		var w bytes.Buffer
+		o := Output{Output: OutputTypeTSV, w: &w}
+		require.NoError(t, o.Encode(map[string]interface{}{"a": map[string]interface{}{"b": 1.5}, "c": []int{1, 2}}))
+		assert.Equal(t, "a.b\tc\n1.5\t[1,2]\n", w.String())
+	})
+	t.Run("Stream", func(t *testing.T) {
+		var w bytes.Buffer
+		o := Output{Output: OutputTypeTable, w: &w}
+		enc := o.Stream()
+		require.NoError(t, enc.Encode(map[string]interface{}{"type": "ticker", "product_id": "BTC-USD", "sequence": 12345678901.0, "price": "100.00"}))
+		require.NoError(t, enc.Encode(map[string]interface{}{"type": "heartbeat", "product_id": "BTC-USD", "sequence": 12345678902.0}))
+		assert.Equal(t, ""+
+			"TYPE  PRODUCT_ID  SEQUENCE  TIME  SIDE  PRICE  SIZE  BEST_BID  BEST_ASK\n"+
+			"ticker  BTC-USD     12345678901              100.00\n"+
+			"heartbeat  BTC-USD     12345678902\n", w.String())
+	})
+	t.Run("StreamJSON", func(t *testing.T) {
+		var w bytes.Buffer
+		o := Output{Output: OutputTypeJSON, w: &w}
+		enc := o.Stream()
+		require.NoError(t, enc.Encode(map[string]interface{}{"type": "ticker"}))
+		require.NoError(t, enc.Encode(map[string]interface{}{"type": "heartbeat"}))
+		assert.Equal(t, "{\"type\":\"ticker\"}\n{\"type\":\"heartbeat\"}\n", w.String())
+	})
+}
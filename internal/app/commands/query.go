@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// whereOperators are the operators of a whereExpr, with those that begin with another operator first.
+var whereOperators = []string{"==", "!=", "<=", ">=", "=", "<", ">"}
+
+// whereExpr is a condition on a field of each element of list output, such as `balance>0`, `side=buy` or
+// `created_at>2021-01-01`. The field is named as in json output, with nested fields joined with a dot. Values are
+// compared as decimals when both are decimals, and otherwise as strings, which orders times by their ISO 8601 form.
+type whereExpr struct {
+	field string
+	op    string
+	value string
+}
+
+func (w *whereExpr) UnmarshalText(b []byte) error {
+	text := string(b)
+	for i := range text {
+		for _, op := range whereOperators {
+			if strings.HasPrefix(text[i:], op) {
+				w.field = strings.TrimPrefix(strings.TrimSpace(text[:i]), ".")
+				w.op = op
+				w.value = strings.TrimSpace(text[i+len(op):])
+				if w.field == "" {
+					return fmt.Errorf("where %q has no field", text)
+				}
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("where %q must be <field><op><value> with op one of %s", text, strings.Join(whereOperators, " "))
+}
+
+func (w whereExpr) matches(r record) bool {
+	value, ok := r.values[w.field]
+	return ok && compare(value, w.op, w.value)
+}
+
+// query returns the value with the elements of its list that match every whereExpr, sorted by the sortBy field, or in
+// descending order when sortBy begins with `-`. A value that is not a list is returned as is.
+func query(value interface{}, where []whereExpr, sortBy string) (interface{}, error) {
+	if len(where) == 0 && sortBy == "" {
+		return value, nil
+	}
+	v := indirect(reflect.ValueOf(value))
+	list, container := v, reflect.Value{}
+	field, ok := onlySlice(v)
+	if ok {
+		list, container = v.Field(field), v
+	}
+	if list.Kind() != reflect.Slice {
+		return value, nil
+	}
+	rs := records(list.Interface())
+	if err := known(rs, where, sortBy); err != nil {
+		return nil, err
+	}
+	var indexes []int
+	for i, r := range rs {
+		if matchesAll(r, where) {
+			indexes = append(indexes, i)
+		}
+	}
+	if sortBy != "" {
+		descending := strings.HasPrefix(sortBy, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(sortBy, "-"), ".")
+		sort.SliceStable(indexes, func(i, j int) bool {
+			a, b := rs[indexes[i]].values[name], rs[indexes[j]].values[name]
+			if descending {
+				return compare(b, "<", a)
+			}
+			return compare(a, "<", b)
+		})
+	}
+	selected := reflect.MakeSlice(list.Type(), len(indexes), len(indexes))
+	for i, index := range indexes {
+		selected.Index(i).Set(list.Index(index))
+	}
+	if !container.IsValid() {
+		return selected.Interface(), nil
+	}
+	result := reflect.New(container.Type()).Elem()
+	result.Set(container)
+	result.Field(field).Set(selected)
+	return result.Interface(), nil
+}
+
+func matchesAll(r record, where []whereExpr) bool {
+	for _, w := range where {
+		if !w.matches(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// known fails when a field of a whereExpr or sortBy is not a field of any of the records, which is most likely a typo.
+func known(rs []record, where []whereExpr, sortBy string) error {
+	if len(rs) == 0 {
+		return nil
+	}
+	fields := make(map[string]bool)
+	for _, r := range rs {
+		for _, name := range r.names {
+			fields[name] = true
+		}
+	}
+	for _, w := range where {
+		if !fields[w.field] {
+			return fmt.Errorf("where field %q is not a field of the output", w.field)
+		}
+	}
+	if name := strings.TrimPrefix(strings.TrimPrefix(sortBy, "-"), "."); name != "" && !fields[name] {
+		return fmt.Errorf("sort-by field %q is not a field of the output", name)
+	}
+	return nil
+}
+
+// whereEncoder encodes only the values of a stream that match every whereExpr.
+type whereEncoder struct {
+	where   []whereExpr
+	encoder encoder
+}
+
+func (w whereEncoder) Encode(value interface{}) error {
+	for _, r := range records(value) {
+		if !matchesAll(r, w.where) {
+			return nil
+		}
+	}
+	return w.encoder.Encode(value)
+}
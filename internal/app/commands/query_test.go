@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhereExpr(t *testing.T) {
+	var w whereExpr
+	require.NoError(t, w.UnmarshalText([]byte("balance>=0.5")))
+	assert.Equal(t, whereExpr{field: "balance", op: ">=", value: "0.5"}, w)
+	require.NoError(t, w.UnmarshalText([]byte(".details.currency = USD")))
+	assert.Equal(t, whereExpr{field: "details.currency", op: "=", value: "USD"}, w)
+	assert.Error(t, w.UnmarshalText([]byte("balance")))
+	assert.Error(t, w.UnmarshalText([]byte("=buy")))
+}
+
+func TestQuery(t *testing.T) {
+	day := func(d int) coinbasepro.Time { return coinbasepro.Time(time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC)) }
+	fills := coinbasepro.Fills{Fills: []*coinbasepro.Fill{
+		{TradeID: 1, Side: coinbasepro.SideBuy, Size: decimal.NewFromInt(10), CreatedAt: day(3)},
+		{TradeID: 2, Side: coinbasepro.SideSell, Size: decimal.NewFromInt(9), CreatedAt: day(1)},
+		{TradeID: 3, Side: coinbasepro.SideBuy, Size: decimal.NewFromInt(2), CreatedAt: day(2)},
+	}}
+	tradeIDs := func(value interface{}) []int64 {
+		var ids []int64
+		for _, fill := range value.(coinbasepro.Fills).Fills {
+			ids = append(ids, fill.TradeID)
+		}
+		return ids
+	}
+
+	value, err := query(fills, []whereExpr{{field: "side", op: "=", value: "buy"}}, "size")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 1}, tradeIDs(value))
+
+	value, err = query(fills, []whereExpr{{field: "created_at", op: ">", value: "2021-01-01T12:00:00Z"}}, "-created_at")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 3}, tradeIDs(value))
+
+	value, err = query(fills, nil, "size")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 2, 1}, tradeIDs(value))
+	assert.Equal(t, []int64{1, 2, 3}, tradeIDs(fills), "query does not change its value")
+
+	accounts := []coinbasepro.Account{
+		{Currency: "USD", Balance: decimal.NewFromInt(5)},
+		{Currency: "BTC", Balance: decimal.Zero},
+	}
+	value, err = query(accounts, []whereExpr{{field: "balance", op: ">", value: "0"}}, "")
+	require.NoError(t, err)
+	assert.Equal(t, accounts[:1], value)
+
+	_, err = query(accounts, []whereExpr{{field: "balnce", op: ">", value: "0"}}, "")
+	assert.EqualError(t, err, `where field "balnce" is not a field of the output`)
+	_, err = query(accounts, nil, "curency")
+	assert.Error(t, err)
+}
+
+func TestOutput_Where(t *testing.T) {
+	var cli struct {
+		Output
+	}
+	var w bytes.Buffer
+	cli.Output = Output{w: &w}
+	k := mustNew(t, &cli)
+	_, err := k.Parse([]string{"-o", "csv", "--columns", "currency", "--where", "balance>0", "--sort-by", "currency"})
+	require.NoError(t, err)
+	require.NoError(t, cli.Output.Encode([]coinbasepro.Account{
+		{Currency: "USD", Balance: decimal.NewFromInt(5)},
+		{Currency: "ETH", Balance: decimal.Zero},
+		{Currency: "BTC", Balance: decimal.NewFromInt(1)},
+	}))
+	assert.Equal(t, "currency\nBTC\nUSD\n", w.String())
+
+	w.Reset()
+	stream := cli.Output.Stream()
+	require.NoError(t, stream.Encode(map[string]interface{}{"currency": "USD", "balance": "1"}))
+	require.NoError(t, stream.Encode(map[string]interface{}{"currency": "ETH", "balance": "0"}))
+	assert.Equal(t, "currency\nUSD\n", w.String())
+}
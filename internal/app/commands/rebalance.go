@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+type rebalanceCmd struct {
+	Targets string                `kong:"name='targets',short='t',type='path',required,help='yaml file of the quote currency, weights, tolerance and order type'"`
+	Type    coinbasepro.OrderType `kong:"name='type',enum=',market,limit',help='type of the orders, one of [market,limit]; overrides the type of the targets file'"`
+	guardrailOverride
+}
+
+// rebalance is the RebalancePlan and the Orders placed to carry it out.
+type rebalance struct {
+	Plan   coinbasepro.RebalancePlan `json:"plan"`
+	Orders []coinbasepro.Order       `json:"orders"`
+}
+
+// Run plans the trades toward the targets, checks each against the guardrails, and places them, sells before buys.
+// With --dry-run the plan is printed with the signed orders instead of placing them.
+func (r *rebalanceCmd) Run(ctx context.Context, cb coinbaser, enc encoder, fs afero.Fs, guard *guardrails) error {
+	source, err := afero.ReadFile(fs, r.Targets)
+	if err != nil {
+		return err
+	}
+	var targets coinbasepro.RebalanceTargets
+	if err = yaml.Unmarshal(source, &targets); err != nil {
+		return err
+	}
+	if r.Type != "" {
+		targets.Type = r.Type
+	}
+	plan, err := cb.PlanRebalance(ctx, targets)
+	if err != nil {
+		return err
+	}
+	violations, err := guard.checkRebalance(ctx, cb, plan)
+	if err != nil {
+		return err
+	}
+	if err = r.override(violations); err != nil {
+		return err
+	}
+	orders, err := cb.Rebalance(ctx, plan)
+	if orders == nil {
+		orders = []coinbasepro.Order{}
+	}
+	if encodeErr := enc.Encode(rebalance{Plan: plan, Orders: orders}); encodeErr != nil {
+		return encodeErr
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebalanceCmd(t *testing.T) {
+	var orders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/":
+			_, _ = w.Write([]byte(`[{"currency":"USD","balance":"100","available":"100"},{"currency":"BTC","balance":"0.03","available":"0.03"},{"currency":"ETH","balance":"1","available":"1"}]`))
+		case "/products/":
+			_, _ = w.Write([]byte(`[{"id":"BTC-USD","base_currency":"BTC","quote_currency":"USD","base_increment":"0.001","quote_increment":"0.01"},` +
+				`{"id":"ETH-USD","base_currency":"ETH","quote_currency":"USD","base_increment":"0.01","quote_increment":"0.01"}]`))
+		case "/fees/":
+			_, _ = w.Write([]byte(`{"maker_fee_rate":"0.004","taker_fee_rate":"0.006"}`))
+		case "/products/BTC-USD/ticker":
+			_, _ = w.Write([]byte(`{"price":"10000"}`))
+		case "/products/ETH-USD/ticker":
+			_, _ = w.Write([]byte(`{"price":"100"}`))
+		case "/orders/":
+			b, _ := ioutil.ReadAll(r.Body)
+			var order map[string]interface{}
+			_ = json.Unmarshal(b, &order)
+			orders = append(orders, order["side"].(string)+" "+order["product_id"].(string))
+			_, _ = w.Write([]byte(`{"id":"` + order["product_id"].(string) + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client, err := coinbasepro.NewClient(baseURL, baseURL, &coinbasepro.Auth{Key: "k", Passphrase: "p", Secret: "zZ=="})
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "targets.yaml", []byte("quote: USD\ntolerance: 1\nweights:\n  BTC: 1\n  ETH: 1\n  USD: 2\n"), 0600))
+	var b bytes.Buffer
+	cmd := rebalanceCmd{Targets: "targets.yaml"}
+	require.NoError(t, cmd.Run(context.Background(), client, &Output{Output: OutputTypeJSON, w: &b}, fs, &guardrails{}))
+	assert.Equal(t, []string{"sell BTC-USD", "buy ETH-USD"}, orders, "sells are placed before buys")
+	var printed rebalance
+	require.NoError(t, json.Unmarshal(b.Bytes(), &printed))
+	assert.Equal(t, "500", printed.Plan.Total.String())
+	require.Len(t, printed.Plan.Trades, 2)
+	assert.Equal(t, "0.017", printed.Plan.Trades[0].Size.String())
+	assert.Len(t, printed.Orders, 2)
+
+	orders = nil
+	maxNotional := decimal.NewFromInt(10)
+	err = cmd.Run(context.Background(), client, &Output{Output: OutputTypeJSON, w: &b}, fs, &guardrails{MaxNotional: &maxNotional})
+	assert.Error(t, err)
+	assert.Empty(t, orders, "no order is placed when a trade violates the guardrails")
+
+	cmd.Targets = "missing.yaml"
+	assert.Error(t, cmd.Run(context.Background(), client, &Output{Output: OutputTypeJSON, w: &b}, fs, &guardrails{}))
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/term"
+)
+
+// Sealed Auth is stored in the config as `sealPrefix` followed by the base64 encoding of a random salt, a random
+// nonce and the AES-256-GCM ciphertext of the json Auth. The key is derived from a passphrase with
+// PBKDF2-HMAC-SHA256 and the salt, so the same passphrase never produces the same key twice.
+const (
+	sealPrefix     = "reticule-seal-v1:"
+	sealSaltSize   = 16
+	sealKeySize    = 32
+	sealIterations = 600000
+)
+
+// passphraseEnv names the environment variable from which the passphrase of sealed configs is read. When it is not
+// set, the passphrase is read from the terminal.
+const passphraseEnv = "RETICULE_PASSPHRASE"
+
+// errSealedAuth is returned when a sealed Auth cannot be opened with the passphrase.
+var errSealedAuth = errors.New("cannot unseal config auth: wrong passphrase or corrupt config")
+
+func sealAuth(auth *coinbasepro.Auth, passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+	plaintext, err := json.Marshal(auth)
+	if err != nil {
+		return "", err
+	}
+	salt := make([]byte, sealSaltSize)
+	if _, err = io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+	aead, err := sealCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := append(salt, nonce...)
+	sealed = aead.Seal(sealed, nonce, plaintext, []byte(sealPrefix))
+	return sealPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func openAuth(sealed string, passphrase string) (*coinbasepro.Auth, error) {
+	if !strings.HasPrefix(sealed, sealPrefix) {
+		return nil, fmt.Errorf("sealed auth is not in %q format", strings.TrimSuffix(sealPrefix, ":"))
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealPrefix))
+	if err != nil {
+		return nil, errSealedAuth
+	}
+	if len(b) < sealSaltSize {
+		return nil, errSealedAuth
+	}
+	aead, err := sealCipher(passphrase, b[:sealSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	b = b[sealSaltSize:]
+	if len(b) < aead.NonceSize() {
+		return nil, errSealedAuth
+	}
+	plaintext, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(sealPrefix))
+	if err != nil {
+		return nil, errSealedAuth
+	}
+	var auth coinbasepro.Auth
+	if err = json.Unmarshal(plaintext, &auth); err != nil {
+		return nil, errSealedAuth
+	}
+	return &auth, nil
+}
+
+func sealCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2.Key([]byte(passphrase), salt, sealIterations, sealKeySize, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readPassphrase returns the passphrase from the passphraseEnv environment variable or, when it is not set, prompts
+// for it on the terminal without echo.
+func readPassphrase(prompt string) (string, error) {
+	if passphrase, ok := os.LookupEnv(passphraseEnv); ok {
+		return passphrase, nil
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("cannot read passphrase from terminal, set %s: %w", passphraseEnv, err)
+	}
+	return string(passphrase), nil
+}
+
+// readNewPassphrase is readPassphrase for a passphrase that is about to seal a config, so a passphrase read from the
+// terminal must be entered twice.
+func readNewPassphrase(prompt string) (string, error) {
+	if _, ok := os.LookupEnv(passphraseEnv); ok {
+		return readPassphrase(prompt)
+	}
+	passphrase, err := readPassphrase(prompt)
+	if err != nil {
+		return "", err
+	}
+	confirm, err := readPassphrase("confirm " + prompt)
+	if err != nil {
+		return "", err
+	}
+	if passphrase != confirm {
+		return "", errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
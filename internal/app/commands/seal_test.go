@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealAuth(t *testing.T) {
+	auth := coinbasepro.NewAuth("key", "passphrase", "secret")
+	sealed, err := sealAuth(auth, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, sealPrefix))
+	assert.NotContains(t, sealed, "secret")
+	opened, err := openAuth(sealed, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, auth, opened)
+	_, err = openAuth(sealed, "battery staple")
+	assert.Equal(t, errSealedAuth, err)
+	_, err = sealAuth(auth, "")
+	assert.Error(t, err)
+}
+
+func TestSealCoinbaseConfigCmd(t *testing.T) {
+	setenv(t, passphraseEnv, "correct horse")
+	fs := afero.NewMemMapFs()
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	configPath := home + "/.reticule/coinbasepro"
+	require.NoError(t, afero.WriteFile(fs, configPath, []byte(`
+current: horatioHornblower
+configs:
+    horatioHornblower:
+        baseurl: https://api-public.sandbox.pro.coinbase.com
+        feedurl: wss://ws-feed-public.sandbox.pro.coinbase.com
+        auth:
+            key: key
+            passphrase: passphrase
+            secret: secret
+`), 0755))
+	cmd := sealCoinbaseConfigCmd{}
+	require.NoError(t, cmd.Run(fs))
+	info, err := fs.Stat(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, configFileMode, info.Mode().Perm())
+	configSet, err := readConfigSet(fs, configPath)
+	require.NoError(t, err)
+	cfg := configSet.Configs["horatioHornblower"]
+	assert.Nil(t, cfg.Auth)
+	assert.Equal(t, "https://api-public.sandbox.pro.coinbase.com", cfg.BaseURL)
+	auth, err := cfg.auth("horatioHornblower")
+	require.NoError(t, err)
+	assert.Equal(t, coinbasepro.NewAuth("key", "passphrase", "secret"), auth)
+}
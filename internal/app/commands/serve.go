@@ -0,0 +1,453 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"golang.org/x/sync/errgroup"
+)
+
+// serveCmd serves the coinbaser of the config to local services over http, so that they share its credentials, its
+// rate limit and one websocket connection without ever holding the secret. Prometheus metrics are served on /metrics.
+type serveCmd struct {
+	Listen       string        `kong:"name='listen',default='127.0.0.1:8086',help='address on which to serve the api'"`
+	Token        string        `kong:"name='token',env='RETICULE_SERVE_TOKEN',help='bearer token required of every request; one is generated and printed when empty'"`
+	AllowedHosts []string      `kong:"name='allowed-host',help='host, with port, by which the api may also be reached, such as through a proxy'"`
+	Keepalive    time.Duration `kong:"name='keepalive',default='15s',help='interval of comments sent to idle feed subscribers'"`
+
+	feedFlags
+}
+
+func (s *serveCmd) Run(ctx context.Context, client coinbaser, guard *guardrails, metrics *coinbasepro.PrometheusMetrics, logger coinbasepro.Logger) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	token := s.Token
+	if token == "" {
+		var err error
+		if token, err = generateToken(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "RETICULE_SERVE_TOKEN=%s\n", token)
+	}
+	hosts, err := gatewayHosts(s.Listen, s.AllowedHosts)
+	if err != nil {
+		return err
+	}
+	broker := coinbasepro.NewBroker(coinbasepro.WithLogger(logger))
+	broker.Instrument(metrics)
+	gateway := newGateway(client, guard, broker, token, hosts, s.Keepalive, logger)
+	gateway.mux.Handle("/metrics", metrics)
+	server := &http.Server{
+		Addr:    s.Listen,
+		Handler: gateway,
+	}
+	wg, ctx := errgroup.WithContext(ctx)
+	wg.Go(func() error {
+		logger.Info("serving api", "url", "http://"+s.Listen)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	})
+	wg.Go(func() error {
+		select {
+		case <-ctx.Done():
+		case sig := <-signals:
+			logger.Info("shutting down", "signal", sig)
+			cancel()
+		}
+		shutdown, done := context.WithTimeout(context.Background(), 5*time.Second)
+		defer done()
+		return server.Shutdown(shutdown)
+	})
+	wg.Go(func() error {
+		request := s.subscriptionRequest()
+		if len(request.Channels) == 0 {
+			return nil
+		}
+		return broker.Run(ctx, client, request)
+	})
+	err = wg.Wait()
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// generateToken returns a random bearer token for a gateway that was given none.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// gatewayHosts returns the hosts by which a gateway listening on the address may be reached, which are the address
+// itself and, on a loopback or unspecified address, the loopback names of its port, and the allowed hosts.
+func gatewayHosts(listen string, allowed []string) (map[string]bool, error) {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return nil, err
+	}
+	hosts := map[string]bool{listen: true}
+	if ip := net.ParseIP(host); host == "" || host == "localhost" || ip != nil && (ip.IsLoopback() || ip.IsUnspecified()) {
+		for _, loopback := range []string{"localhost", "127.0.0.1", "::1"} {
+			hosts[net.JoinHostPort(loopback, port)] = true
+		}
+	}
+	for _, host := range allowed {
+		hosts[host] = true
+	}
+	return hosts, nil
+}
+
+// gatewayMethods are the methods of the coinbaser that the gateway serves. Deposits, withdrawals, transfers and
+// conversions move funds and are left to the cli, where they are confirmed.
+var gatewayMethods = []string{
+	"ListAccounts", "GetAccount", "GetLedger", "GetHolds", "ListLedger",
+	"CreateLimitOrder", "CreateMarketOrder", "CancelOrder", "GetOrder", "GetClientOrder", "GetOrders",
+	"GetFills", "ListFills", "GetLimits", "GetFees",
+	"GetDeposits", "GetDeposit", "ListDeposits", "GetWithdrawals", "GetWithdrawal", "ListWithdrawals",
+	"GetWithdrawalFeeEstimate", "ListPaymentMethods", "ListCoinbaseAccounts",
+	"GetReport", "ListProfiles", "GetProfile",
+	"ListProducts", "GetProduct", "GetAggregatedOrderBook", "GetOrderBook", "GetProductTicker", "GetProductTrades",
+	"GetHistoricRates", "GetProductStats", "ListCurrencies", "GetCurrency", "GetServerTime",
+	"GetPortfolio", "PlanRebalance", "Rebalance",
+}
+
+// maxGatewayBody limits the body of a request to the gateway.
+const maxGatewayBody = 1 << 20
+
+// gateway serves the gatewayMethods of a coinbaser, such as
+//
+//	POST /v1/GetOrders  [{"status":["open"]}, {"limit":10}]
+//
+// with the arguments after the context as a json array, and the result or error as json. Methods without arguments may
+// also be called with GET. GET /v1/ lists the methods and their arguments, and GET /v1/feed streams feed messages as
+// server-sent events. Every request requires the bearer token, and requests from browsers of other origins or by
+// other hosts, such as after DNS rebinding, are refused. Orders that violate the guardrails of the config are refused,
+// as there is no one to confirm an override.
+type gateway struct {
+	methods   map[string]reflect.Value
+	client    coinbaser
+	guard     *guardrails
+	broker    *coinbasepro.Broker
+	token     string
+	hosts     map[string]bool
+	keepalive time.Duration
+	mux       *http.ServeMux
+	logger    coinbasepro.Logger
+}
+
+func newGateway(client coinbaser, guard *guardrails, broker *coinbasepro.Broker, token string, hosts map[string]bool, keepalive time.Duration, logger coinbasepro.Logger) *gateway {
+	g := &gateway{
+		methods:   make(map[string]reflect.Value),
+		client:    client,
+		guard:     guard,
+		broker:    broker,
+		token:     token,
+		hosts:     hosts,
+		keepalive: keepalive,
+		mux:       http.NewServeMux(),
+		logger:    logger,
+	}
+	api := reflect.TypeOf((*coinbaser)(nil)).Elem()
+	value := reflect.ValueOf(client)
+	for _, name := range gatewayMethods {
+		method, ok := api.MethodByName(name)
+		if !ok || !servable(method.Type) {
+			panic(fmt.Sprintf("gateway method %s cannot be served", name))
+		}
+		g.methods[name] = value.MethodByName(name)
+	}
+	g.mux.HandleFunc("/v1/", g.call)
+	g.mux.HandleFunc("/v1/feed", g.feed)
+	return g
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// servable indicates whether a method takes a context and arguments that can be decoded from json, and returns an
+// error, optionally after a result.
+func servable(method reflect.Type) bool {
+	if method.NumIn() == 0 || method.In(0) != contextType {
+		return false
+	}
+	for i := 1; i < method.NumIn(); i++ {
+		if method.In(i).Kind() == reflect.Interface || !decodable(method.In(i), map[reflect.Type]bool{}) {
+			return false
+		}
+	}
+	switch method.NumOut() {
+	case 1:
+		return method.Out(0) == errorType
+	case 2:
+		return method.Out(1) == errorType
+	}
+	return false
+}
+
+// decodable indicates whether json can be decoded into a value of the type, which it cannot when the value holds a
+// channel or a func.
+func decodable(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return false
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return decodable(t.Elem(), seen)
+	case reflect.Map:
+		return decodable(t.Key(), seen) && decodable(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath == "" && !decodable(t.Field(i).Type, seen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !g.hosts[r.Host] {
+		g.writeError(w, http.StatusMisdirectedRequest, fmt.Errorf("host %q is not served", r.Host))
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || !g.hosts[u.Host] {
+			g.writeError(w, http.StatusForbidden, fmt.Errorf("requests from origin %q are refused", origin))
+			return
+		}
+	}
+	if !g.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		g.writeError(w, http.StatusUnauthorized, errors.New("a valid bearer token is required"))
+		return
+	}
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *gateway) authorized(r *http.Request) bool {
+	if g.token == "" {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(g.token)) == 1
+}
+
+// gatewayMethod describes a method of the gateway by the types of its arguments.
+type gatewayMethod struct {
+	Method string   `json:"method"`
+	Args   []string `json:"args"`
+}
+
+func (g *gateway) call(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if name == "" {
+		g.list(w, r)
+		return
+	}
+	method, ok := g.methods[name]
+	if !ok {
+		g.writeError(w, http.StatusNotFound, fmt.Errorf("no method %q", name))
+		return
+	}
+	if r.Method != http.MethodPost && !(r.Method == http.MethodGet && method.Type().NumIn() == 1) {
+		w.Header().Set("Allow", http.MethodPost)
+		g.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s requires POST of its arguments", name))
+		return
+	}
+	if r.Method == http.MethodPost {
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+			g.writeError(w, http.StatusUnsupportedMediaType, errors.New("arguments require Content-Type: application/json"))
+			return
+		}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxGatewayBody)
+	// the requests of a call join the trace of the caller
+	if header := r.Header.Get("traceparent"); header != "" {
+		if ctx, err := coinbasepro.ContextWithTraceParent(r.Context(), header); err == nil {
+			r = r.WithContext(ctx)
+		}
+	}
+	args, err := arguments(r, method.Type())
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	violations, err := g.check(r.Context(), args[1:])
+	if err != nil {
+		g.writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if err = g.guard.violated(violations); err != nil {
+		g.writeError(w, http.StatusForbidden, err)
+		return
+	}
+	results := method.Call(args)
+	if err, _ := results[len(results)-1].Interface().(error); err != nil {
+		var apiErr coinbasepro.Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+			g.writeError(w, apiErr.StatusCode, err)
+			return
+		}
+		g.writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	var result interface{}
+	if len(results) == 2 {
+		result = results[0].Interface()
+	}
+	g.writeJSON(w, http.StatusOK, result)
+}
+
+// check returns the guardrail violations of the arguments of a call.
+func (g *gateway) check(ctx context.Context, args []reflect.Value) ([]string, error) {
+	if len(args) != 1 {
+		return nil, nil
+	}
+	switch arg := args[0].Interface().(type) {
+	case coinbasepro.LimitOrder:
+		return g.guard.checkLimitOrder(ctx, g.client, arg)
+	case coinbasepro.MarketOrder:
+		return g.guard.checkMarketOrder(ctx, g.client, arg)
+	case coinbasepro.RebalancePlan:
+		return g.guard.checkRebalance(ctx, g.client, arg)
+	}
+	return nil, nil
+}
+
+func (g *gateway) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		g.writeError(w, http.StatusMethodNotAllowed, errors.New("list methods with GET"))
+		return
+	}
+	methods := make([]gatewayMethod, 0, len(g.methods))
+	for name, method := range g.methods {
+		args := make([]string, 0, method.Type().NumIn()-1)
+		for i := 1; i < method.Type().NumIn(); i++ {
+			args = append(args, method.Type().In(i).String())
+		}
+		methods = append(methods, gatewayMethod{Method: name, Args: args})
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Method < methods[j].Method })
+	g.writeJSON(w, http.StatusOK, methods)
+}
+
+// arguments decodes the json array of the request body into the arguments of the method, after the context of the
+// request. Fields that are not fields of an argument are rejected, as they are most likely a typo.
+func arguments(r *http.Request, method reflect.Type) ([]reflect.Value, error) {
+	args := []reflect.Value{reflect.ValueOf(r.Context())}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	var raw []json.RawMessage
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err = json.Unmarshal(body, &raw); err != nil {
+			return nil, fmt.Errorf("arguments must be a json array: %w", err)
+		}
+	}
+	if len(raw) != method.NumIn()-1 {
+		return nil, fmt.Errorf("%d arguments are required but %d were provided", method.NumIn()-1, len(raw))
+	}
+	for i, message := range raw {
+		arg := reflect.New(method.In(i + 1))
+		decoder := json.NewDecoder(bytes.NewReader(message))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(arg.Interface()); err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		args = append(args, arg.Elem())
+	}
+	return args, nil
+}
+
+// feed streams the messages of the broker that match the channel, product_id and type query parameters, of which
+// each may be repeated, as server-sent events. A subscriber that falls behind misses the newest messages.
+func (g *gateway) feed(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		g.writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	query := r.URL.Query()
+	filter := coinbasepro.SubscriptionFilter{Types: query["type"]}
+	for _, channel := range query["channel"] {
+		filter.Channels = append(filter.Channels, coinbasepro.ChannelName(channel))
+	}
+	for _, productID := range query["product_id"] {
+		filter.ProductIDs = append(filter.ProductIDs, coinbasepro.ProductID(productID))
+	}
+	subscription := g.broker.Subscribe(coinbasepro.SubscriptionOptions{Filter: filter})
+	defer subscription.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	keepalive := time.NewTicker(g.keepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case message := <-subscription.Messages:
+			b, err := json.Marshal(message)
+			if err != nil {
+				g.logger.Warn("feed message cannot be encoded", "error", err)
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func (g *gateway) writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		g.logger.Warn("response cannot be encoded", "error", err)
+	}
+}
+
+func (g *gateway) writeError(w http.ResponseWriter, status int, err error) {
+	g.writeJSON(w, status, map[string]string{"message": err.Error()})
+}
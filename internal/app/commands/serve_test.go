@@ -0,0 +1,207 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway(t *testing.T) {
+	cb := &tracedCoinbaser{fakeCoinbaser: fakeCoinbaser{
+		accounts: []coinbasepro.Account{{ID: "a", Currency: "USD", Balance: decimal.NewFromInt(10)}},
+		product:  coinbasepro.Product{ID: "BTC-USD"},
+	}}
+	guard := &guardrails{AllowedProducts: []coinbasepro.ProductID{"ETH-USD"}}
+	logger, err := logLevel("info").logger(ioutil.Discard)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(nil)
+	hosts, err := gatewayHosts(server.Listener.Addr().String(), nil)
+	require.NoError(t, err)
+	server.Config.Handler = newGateway(cb, guard, coinbasepro.NewBroker(), "secret", hosts, time.Minute, logger)
+	server.Start()
+	defer server.Close()
+
+	send := func(req *http.Request, token string) (int, string) {
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, resp.Body.Close()) }()
+		var b strings.Builder
+		_, err = bufio.NewReader(resp.Body).WriteTo(&b)
+		require.NoError(t, err)
+		return resp.StatusCode, strings.TrimSpace(b.String())
+	}
+	call := func(method string, path string, body string, token string) (int, string) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if method == http.MethodPost {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return send(req, token)
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		status, _ := call("GET", "/v1/ListAccounts", "", "wrong")
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+	t.Run("ContentType", func(t *testing.T) {
+		req, err := http.NewRequest("POST", server.URL+"/v1/GetProduct", strings.NewReader(`["BTC-USD"]`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "text/plain")
+		status, _ := send(req, "secret")
+		assert.Equal(t, http.StatusUnsupportedMediaType, status, "a form post of a browser is refused")
+	})
+	t.Run("Origin", func(t *testing.T) {
+		req, err := http.NewRequest("GET", server.URL+"/v1/ListAccounts", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "https://example.com")
+		status, _ := send(req, "secret")
+		assert.Equal(t, http.StatusForbidden, status)
+		req.Header.Set("Origin", server.URL)
+		status, _ = send(req, "secret")
+		assert.Equal(t, http.StatusOK, status)
+	})
+	t.Run("Host", func(t *testing.T) {
+		req, err := http.NewRequest("GET", server.URL+"/v1/ListAccounts", nil)
+		require.NoError(t, err)
+		req.Host = "attacker.example.com"
+		status, _ := send(req, "secret")
+		assert.Equal(t, http.StatusMisdirectedRequest, status, "a rebound dns name is refused")
+	})
+	t.Run("BodyTooLarge", func(t *testing.T) {
+		status, body := call("POST", "/v1/GetProduct", `["`+strings.Repeat("a", maxGatewayBody)+`"]`, "secret")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, "too large")
+	})
+	t.Run("TraceParent", func(t *testing.T) {
+		req, err := http.NewRequest("GET", server.URL+"/v1/GetServerTime", nil)
+		require.NoError(t, err)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		status, _ := send(req, "secret")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", cb.traceParent, "a call joins the trace of the caller")
+	})
+	t.Run("GetWithoutArguments", func(t *testing.T) {
+		status, body := call("GET", "/v1/ListAccounts", "", "secret")
+		assert.Equal(t, http.StatusOK, status)
+		var accounts []coinbasepro.Account
+		require.NoError(t, json.Unmarshal([]byte(body), &accounts))
+		assert.Equal(t, "a", accounts[0].ID)
+	})
+	t.Run("PostArguments", func(t *testing.T) {
+		status, body := call("POST", "/v1/GetProduct", `["BTC-USD"]`, "secret")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, `"id":"BTC-USD"`)
+	})
+	t.Run("GetWithArguments", func(t *testing.T) {
+		status, _ := call("GET", "/v1/GetProduct", "", "secret")
+		assert.Equal(t, http.StatusMethodNotAllowed, status)
+	})
+	t.Run("ArgumentCount", func(t *testing.T) {
+		status, body := call("POST", "/v1/GetProduct", `[]`, "secret")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, "1 arguments are required but 0 were provided")
+	})
+	t.Run("UnknownField", func(t *testing.T) {
+		status, body := call("POST", "/v1/GetOrders", `[{"statuz":["open"]},{}]`, "secret")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, "statuz")
+	})
+	t.Run("UnknownMethod", func(t *testing.T) {
+		status, _ := call("POST", "/v1/Watch", `[]`, "secret")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+	t.Run("Guardrails", func(t *testing.T) {
+		status, body := call("POST", "/v1/CreateLimitOrder", `[{"product_id":"BTC-USD","side":"buy","price":"1","size":"1"}]`, "secret")
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Contains(t, body, "guardrails violated")
+		status, body = call("POST", "/v1/Rebalance", `[{"trades":[{"product_id":"BTC-USD","side":"buy","type":"limit","price":"1","size":"1"}]}]`, "secret")
+		assert.Equal(t, http.StatusForbidden, status, "each trade of a rebalance is checked")
+		assert.Contains(t, body, "guardrails violated")
+	})
+	t.Run("List", func(t *testing.T) {
+		status, body := call("GET", "/v1/", "", "secret")
+		assert.Equal(t, http.StatusOK, status)
+		var methods []gatewayMethod
+		require.NoError(t, json.Unmarshal([]byte(body), &methods))
+		names := make(map[string][]string)
+		for _, m := range methods {
+			names[m.Method] = m.Args
+		}
+		assert.Equal(t, []string{"coinbasepro.OrderFilter", "coinbasepro.PaginationParams"}, names["GetOrders"])
+		assert.Contains(t, names, "ListAccounts")
+		assert.NotContains(t, names, "Watch", "a feed cannot be decoded from json")
+		assert.NotContains(t, names, "DownloadReport", "a file system cannot be decoded from json")
+		assert.NotContains(t, names, "Close", "close takes no context")
+		assert.NotContains(t, names, "CreateCryptoAddressWithdrawal", "funds are not moved through the gateway")
+	})
+}
+
+// tracedCoinbaser records the traceparent of the context of GetServerTime.
+type tracedCoinbaser struct {
+	fakeCoinbaser
+	traceParent string
+}
+
+func (c *tracedCoinbaser) GetServerTime(ctx context.Context) (coinbasepro.ServerTime, error) {
+	c.traceParent = coinbasepro.TraceParent(ctx)
+	return coinbasepro.ServerTime{}, nil
+}
+
+func TestGateway_Feed(t *testing.T) {
+	broker := coinbasepro.NewBroker()
+	logger, err := logLevel("info").logger(ioutil.Discard)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(nil)
+	hosts, err := gatewayHosts(server.Listener.Addr().String(), nil)
+	require.NoError(t, err)
+	server.Config.Handler = newGateway(&fakeCoinbaser{}, &guardrails{}, broker, "secret", hosts, time.Minute, logger)
+	server.Start()
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL+"/v1/feed?channel=ticker&product_id=BTC-USD", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, resp.Body.Close()) }()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	require.Eventually(t, func() bool { return broker.Subscriptions() == 1 }, time.Second, time.Millisecond)
+	broker.Publish(map[string]interface{}{"type": "heartbeat", "product_id": "BTC-USD"})
+	broker.Publish(map[string]interface{}{"type": "ticker", "product_id": "ETH-USD"})
+	broker.Publish(map[string]interface{}{"type": "ticker", "product_id": "BTC-USD", "price": "100"})
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, `data: {"price":"100","product_id":"BTC-USD","type":"ticker"}`+"\n", line)
+}
+
+func TestGatewayHosts(t *testing.T) {
+	hosts, err := gatewayHosts("127.0.0.1:8086", []string{"reticule.internal:80"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"127.0.0.1:8086":       true,
+		"localhost:8086":       true,
+		"[::1]:8086":           true,
+		"reticule.internal:80": true,
+	}, hosts)
+	hosts, err = gatewayHosts("10.0.0.2:8086", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"10.0.0.2:8086": true}, hosts)
+	_, err = gatewayHosts("8086", nil)
+	assert.Error(t, err)
+}
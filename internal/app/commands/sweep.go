@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+)
+
+type sweepCmd struct {
+	To       string                     `kong:"name='to',help='id or name of the profile that receives the funds; the default profile when empty'"`
+	Currency []coinbasepro.CurrencyName `kong:"name='currency',short='c',required,help='currencies to sweep'"`
+	Keep     decimal.Decimal            `kong:"name='keep',default='0',help='amount of each currency to leave in each profile'"`
+	Every    time.Duration              `kong:"name='every',help='sweep again at this interval until interrupted; 0 sweeps once'"`
+}
+
+// Run transfers the available funds of each other Profile of the ProfileView to the destination Profile, and prints
+// the ProfileTransfers of each sweep.
+func (s *sweepCmd) Run(ctx context.Context, viewer profileViewer, enc encoder) error {
+	view, err := viewer(ctx)
+	if err != nil {
+		return err
+	}
+	to, err := s.destination(view)
+	if err != nil {
+		return err
+	}
+	rules := make([]coinbasepro.SweepRule, 0, len(s.Currency))
+	for _, currency := range s.Currency {
+		rules = append(rules, coinbasepro.SweepRule{Currency: currency, Keep: s.Keep})
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	for {
+		transfers, err := view.Sweep(ctx, to.ID, rules)
+		if transfers == nil {
+			transfers = []coinbasepro.ProfileTransfer{}
+		}
+		if encodeErr := enc.Encode(transfers); encodeErr != nil {
+			return encodeErr
+		}
+		if err != nil || s.Every <= 0 {
+			return err
+		}
+		timer := time.NewTimer(s.Every)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-signals:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// destination finds the Profile named by To, or the default Profile.
+func (s *sweepCmd) destination(view *coinbasepro.ProfileView) (coinbasepro.Profile, error) {
+	if s.To != "" {
+		profile, ok := view.FindProfile(s.To)
+		if !ok {
+			return coinbasepro.Profile{}, fmt.Errorf("no profile %q has a config of the same api", s.To)
+		}
+		return profile, nil
+	}
+	for _, profile := range view.Profiles {
+		if profile.IsDefault {
+			return profile, nil
+		}
+	}
+	return coinbasepro.Profile{}, errors.New("the default profile has no config of the same api, use --to to name a profile")
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweepCmd(t *testing.T) {
+	var transfers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the key of each client selects its profile
+		profileID := r.Header.Get("CB-ACCESS-KEY")
+		switch r.URL.Path {
+		case "/profiles/":
+			_, _ = w.Write([]byte(`[{"id":"main","name":"default","is_default":true},{"id":"bot","name":"trading"}]`))
+		case "/accounts/":
+			_, _ = w.Write([]byte(`[{"profile_id":"` + profileID + `","currency":"USD","balance":"30","available":"25"}]`))
+		case "/profiles/transfer":
+			b, _ := ioutil.ReadAll(r.Body)
+			transfers = append(transfers, profileID+" "+string(bytes.TrimSpace(b)))
+			_, _ = w.Write(b)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	var clients []*coinbasepro.Client
+	for _, key := range []string{"main", "bot"} {
+		client, err := coinbasepro.NewClient(baseURL, baseURL, &coinbasepro.Auth{Key: key, Passphrase: "p", Secret: "zZ=="})
+		require.NoError(t, err)
+		clients = append(clients, client)
+	}
+	viewer := profileViewer(func(ctx context.Context) (*coinbasepro.ProfileView, error) {
+		return coinbasepro.NewProfileView(ctx, clients...)
+	})
+
+	var b bytes.Buffer
+	cmd := sweepCmd{Currency: []coinbasepro.CurrencyName{"USD"}, Keep: decimal.NewFromInt(5)}
+	require.NoError(t, cmd.Run(context.Background(), viewer, &Output{Output: OutputTypeJSON, w: &b}))
+	assert.Equal(t, []string{`bot {"amount":"20","currency":"USD","from":"bot","to":"main"}`}, transfers, "the key of the profile the funds leave makes the transfer")
+	var printed []coinbasepro.ProfileTransfer
+	require.NoError(t, json.Unmarshal(b.Bytes(), &printed))
+	require.Len(t, printed, 1)
+	assert.Equal(t, "20", printed[0].Amount.String())
+
+	transfers = nil
+	cmd.To = "trading"
+	b.Reset()
+	require.NoError(t, cmd.Run(context.Background(), viewer, &Output{Output: OutputTypeJSON, w: &b}))
+	assert.Equal(t, []string{`main {"amount":"20","currency":"USD","from":"main","to":"bot"}`}, transfers)
+
+	cmd.To = "nobody"
+	assert.Error(t, cmd.Run(context.Background(), viewer, &Output{Output: OutputTypeJSON, w: &b}))
+}
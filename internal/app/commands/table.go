@@ -0,0 +1,242 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+)
+
+// defaultColumns are the columns of table, csv and tsv output for types that have too many fields to show them all.
+var defaultColumns = map[reflect.Type][]string{
+	reflect.TypeOf(coinbasepro.Account{}):     {"id", "currency", "balance", "available", "hold"},
+	reflect.TypeOf(coinbasepro.Deposit{}):     {"id", "type", "currency", "amount", "created_at", "completed_at", "canceled_at"},
+	reflect.TypeOf(coinbasepro.Fill{}):        {"trade_id", "product_id", "side", "size", "price", "fee", "liquidity", "created_at"},
+	reflect.TypeOf(coinbasepro.LedgerEntry{}): {"id", "type", "amount", "balance", "created_at"},
+	reflect.TypeOf(coinbasepro.Order{}):       {"id", "product_id", "side", "type", "size", "price", "funds", "filled_size", "status", "created_at"},
+	reflect.TypeOf(coinbasepro.Product{}):     {"id", "base_currency", "quote_currency", "base_min_size", "base_increment", "quote_increment", "status"},
+	reflect.TypeOf(coinbasepro.Withdrawal{}):  {"id", "type", "currency", "amount", "created_at", "completed_at", "canceled_at"},
+}
+
+// messageColumns are the default columns of feed messages, which are decoded into maps rather than typed.
+var messageColumns = []string{"type", "product_id", "sequence", "time", "side", "price", "size", "best_bid", "best_ask"}
+
+// record is a value flattened into named fields, in the order of the fields of the value.
+type record struct {
+	names  []string
+	values map[string]string
+}
+
+func (r *record) set(name string, value string) {
+	if r.values == nil {
+		r.values = make(map[string]string)
+	}
+	if _, ok := r.values[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.values[name] = value
+}
+
+func (r record) row(columns []string) []string {
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		row[i] = r.values[column]
+	}
+	return row
+}
+
+// records flattens a value into one record per row. A slice has a row per element, as does a struct whose only list
+// is a slice, such as a page of Orders; any other value is a single row.
+func records(value interface{}) []record {
+	items := indirect(reflect.ValueOf(value))
+	if field, ok := onlySlice(items); ok {
+		items = items.Field(field)
+	}
+	if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+		var r record
+		flatten(&r, "", items)
+		return []record{r}
+	}
+	rs := make([]record, items.Len())
+	for i := range rs {
+		flatten(&rs[i], "", items.Index(i))
+	}
+	return rs
+}
+
+// selectColumns returns the selected columns, or the default columns of the type of the records, or else every field
+// of the records.
+func selectColumns(value interface{}, rs []record, selected []string) []string {
+	if len(selected) > 0 {
+		return selected
+	}
+	switch t := elemType(value); {
+	case defaultColumns[t] != nil:
+		return defaultColumns[t]
+	case t != nil && t.Kind() == reflect.Map:
+		if _, ok := firstValue(rs, "type"); ok {
+			return messageColumns
+		}
+	}
+	var columns []string
+	seen := make(map[string]bool)
+	for _, r := range rs {
+		for _, name := range r.names {
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return []string{"value"}
+	}
+	return columns
+}
+
+func firstValue(rs []record, name string) (string, bool) {
+	if len(rs) == 0 {
+		return "", false
+	}
+	value, ok := rs[0].values[name]
+	return value, ok
+}
+
+// elemType is the type of the values of the rows of records(value).
+func elemType(value interface{}) reflect.Type {
+	items := indirect(reflect.ValueOf(value))
+	if field, ok := onlySlice(items); ok {
+		items = items.Field(field)
+	}
+	if !items.IsValid() {
+		return nil
+	}
+	t := items.Type()
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		return t
+	}
+	return t
+}
+
+// onlySlice returns the index of the one exported slice field of a struct, if it has exactly one.
+func onlySlice(v reflect.Value) (int, bool) {
+	if v.Kind() != reflect.Struct {
+		return 0, false
+	}
+	index, count := 0, 0
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		if v.Field(i).Kind() == reflect.Slice {
+			index = i
+			count++
+		}
+	}
+	return index, count == 1
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// flatten sets a field of the record for each scalar in v. Fields of nested structs and maps are named by their path,
+// joined with a dot. Values that marshal themselves to json, such as decimals and times, are scalars.
+func flatten(r *record, prefix string, v reflect.Value) {
+	if v.IsValid() && v.CanInterface() {
+		if marshaler, ok := v.Interface().(json.Marshaler); ok && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+			r.set(name(prefix), marshaled(marshaler))
+			return
+		}
+	}
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Invalid:
+		r.set(name(prefix), "")
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			tag := strings.Split(field.Tag.Get("json"), ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if field.Anonymous && tag == "" {
+				flatten(r, prefix, v.Field(i))
+				continue
+			}
+			if tag == "" {
+				tag = field.Name
+			}
+			flatten(r, join(prefix, tag), v.Field(i))
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			r.set(name(prefix), compact(v))
+			return
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			flatten(r, join(prefix, key.String()), v.MapIndex(key))
+		}
+	case reflect.Slice, reflect.Array:
+		r.set(name(prefix), compact(v))
+	case reflect.Float32, reflect.Float64:
+		r.set(name(prefix), strconv.FormatFloat(v.Float(), 'f', -1, 64))
+	default:
+		r.set(name(prefix), fmt.Sprint(v.Interface()))
+	}
+}
+
+func name(prefix string) string {
+	if prefix == "" {
+		return "value"
+	}
+	return prefix
+}
+
+func join(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// marshaled is the json of the value without the quotes of a json string.
+func marshaled(marshaler json.Marshaler) string {
+	b, err := marshaler.MarshalJSON()
+	if err != nil {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		return s
+	}
+	return string(b)
+}
+
+func compact(v reflect.Value) string {
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return string(b)
+}
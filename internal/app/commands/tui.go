@@ -0,0 +1,610 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"golang.org/x/term"
+)
+
+type tuiCmd struct {
+	ProductID coinbasepro.ProductID `kong:"arg,name='product-id',default='BTC-USD',help='product to trade'"`
+	Refresh   time.Duration         `kong:"name='refresh',default='5s',help='interval between polls of open orders and balances'"`
+	Depth     int                   `kong:"name='depth',default='10',help='levels of each side of the order book to show'"`
+}
+
+// Run shows a dashboard of the product that is updated from the ticker, level2 and matches channels of the feed and
+// by polling open orders and balances. Orders placed and canceled from the dashboard are confirmed first and pass
+// through the guardrails of the config.
+func (t *tuiCmd) Run(ctx context.Context, client coinbaser, guard *guardrails) error {
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("tui requires a terminal: %w", err)
+	}
+	defer func() { _ = term.Restore(int(os.Stdin.Fd()), state) }()
+	fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	d := newDashboard(t.ProductID, t.Depth)
+
+	// a level2 update that is dropped leaves the book wrong until the next snapshot, so the feed must not drop
+	feed := coinbasepro.NewBlockingFeed(256)
+	go d.watch(ctx, client, coinbasepro.NewSubscriptionRequest([]coinbasepro.ProductID{t.ProductID}, []coinbasepro.ChannelName{
+		coinbasepro.ChannelNameHeartbeat,
+		coinbasepro.ChannelNameTicker,
+		coinbasepro.ChannelNameLevel2,
+		coinbasepro.ChannelNameMatches,
+	}, nil), feed)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message := <-feed.Messages:
+				d.apply(message)
+			}
+		}
+	}()
+	go func() {
+		for {
+			d.poll(ctx, client)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(t.Refresh):
+			}
+		}
+	}()
+	keys := make(chan byte)
+	go func() {
+		b := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(b); err != nil {
+				close(keys)
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case keys <- b[0]:
+			}
+		}
+	}()
+
+	render := time.NewTicker(250 * time.Millisecond)
+	defer render.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-render.C:
+			width, height, err := term.GetSize(int(os.Stdout.Fd()))
+			if err != nil {
+				width, height = 80, 24
+			}
+			d.render(os.Stdout, width, height)
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch action := d.key(key); action.kind {
+			case tuiQuit:
+				return nil
+			case tuiPlaceOrder:
+				d.placeOrder(ctx, client, guard, action.order)
+			case tuiCancelOrder:
+				d.cancelOrder(ctx, client, action.orderID)
+			}
+		}
+	}
+}
+
+// maxMatches is the number of recent matches the dashboard keeps.
+const maxMatches = 50
+
+// dashboard is the state of the tui, updated concurrently by the feed, polling and key presses.
+type dashboard struct {
+	mu        sync.Mutex
+	productID coinbasepro.ProductID
+	depth     int
+	ticker    map[string]interface{}
+	synced    bool
+	bids      map[string]decimal.Decimal
+	asks      map[string]decimal.Decimal
+	matches   []map[string]interface{}
+	orders    []*coinbasepro.Order
+	accounts  []coinbasepro.Account
+	status    string
+	prompt    *tuiPrompt
+}
+
+func newDashboard(productID coinbasepro.ProductID, depth int) *dashboard {
+	return &dashboard{
+		productID: productID,
+		depth:     depth,
+		bids:      make(map[string]decimal.Decimal),
+		asks:      make(map[string]decimal.Decimal),
+		status:    "connecting",
+	}
+}
+
+// watch keeps the feed connected until the context is done. When the connection fails, the book and ticker are
+// cleared until the next connection sends them again, and watch reconnects after a delay that doubles from one second
+// up to thirty seconds, as a Broker does.
+func (d *dashboard) watch(ctx context.Context, client coinbaser, request coinbasepro.SubscriptionRequest, feed coinbasepro.Feed) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := client.Watch(ctx, request, feed)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		d.disconnected(err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+}
+
+// disconnected clears the state of the feed, so that a stale book is not shown while reconnecting.
+func (d *dashboard) disconnected(err error, backoff time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ticker = nil
+	d.synced = false
+	d.bids = make(map[string]decimal.Decimal)
+	d.asks = make(map[string]decimal.Decimal)
+	d.status = fmt.Sprintf("feed: %v, reconnecting in %s", err, backoff)
+}
+
+func (d *dashboard) setStatus(format string, args ...interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status = fmt.Sprintf(format, args...)
+}
+
+// apply updates the dashboard with a message of the feed.
+func (d *dashboard) apply(message interface{}) {
+	m, ok := message.(map[string]interface{})
+	if !ok {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch m["type"] {
+	case "ticker":
+		d.ticker = m
+	case "snapshot":
+		d.bids = levels(m["bids"])
+		d.asks = levels(m["asks"])
+		d.synced = true
+	case "l2update":
+		if !d.synced {
+			// an update is relative to the snapshot of its connection, which has not arrived
+			break
+		}
+		changes, _ := m["changes"].([]interface{})
+		for _, change := range changes {
+			c, ok := change.([]interface{})
+			if !ok || len(c) != 3 {
+				continue
+			}
+			side := d.asks
+			if c[0] == "buy" {
+				side = d.bids
+			}
+			price, _ := c[1].(string)
+			size, err := decimal.NewFromString(fmt.Sprint(c[2]))
+			if err != nil {
+				continue
+			}
+			if size.IsZero() {
+				delete(side, price)
+			} else {
+				side[price] = size
+			}
+		}
+	case "match", "last_match":
+		d.matches = append([]map[string]interface{}{m}, d.matches...)
+		if len(d.matches) > maxMatches {
+			d.matches = d.matches[:maxMatches]
+		}
+	case "error":
+		d.status = fmt.Sprintf("feed: %v %v", m["message"], m["reason"])
+	case "subscriptions":
+		d.status = "connected"
+	}
+}
+
+// levels reads the [price, size] pairs of a level2 snapshot.
+func levels(value interface{}) map[string]decimal.Decimal {
+	result := make(map[string]decimal.Decimal)
+	pairs, _ := value.([]interface{})
+	for _, pair := range pairs {
+		p, ok := pair.([]interface{})
+		if !ok || len(p) != 2 {
+			continue
+		}
+		size, err := decimal.NewFromString(fmt.Sprint(p[1]))
+		if err != nil {
+			continue
+		}
+		result[fmt.Sprint(p[0])] = size
+	}
+	return result
+}
+
+// poll refreshes the open orders of the product and the balances of the accounts.
+func (d *dashboard) poll(ctx context.Context, client coinbaser) {
+	orders, err := client.GetOrders(ctx, coinbasepro.OrderFilter{
+		ProductID: d.productID,
+		Status:    []coinbasepro.OrderStatusParam{coinbasepro.OrderStatusParamOpen, coinbasepro.OrderStatusParamPending},
+	}, coinbasepro.PaginationParams{Limit: 100})
+	if err != nil {
+		d.setStatus("orders: %v", err)
+		return
+	}
+	accounts, err := client.ListAccounts(ctx)
+	if err != nil {
+		d.setStatus("accounts: %v", err)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.orders = orders.Orders
+	d.accounts = accounts
+}
+
+func (d *dashboard) placeOrder(ctx context.Context, client coinbaser, guard *guardrails, order coinbasepro.LimitOrder) {
+	violations, err := guard.checkLimitOrder(ctx, client, order)
+	if err != nil {
+		d.setStatus("order: %v", err)
+		return
+	}
+	if err = guard.violated(violations); err != nil {
+		d.setStatus("order: %v", err)
+		return
+	}
+	created, err := client.CreateLimitOrder(ctx, order)
+	if err != nil {
+		d.setStatus("order: %v", err)
+		return
+	}
+	d.setStatus("created order %s", created.ID)
+	d.poll(ctx, client)
+}
+
+func (d *dashboard) cancelOrder(ctx context.Context, client coinbaser, orderID string) {
+	_, err := client.CancelOrder(ctx, coinbasepro.CancelOrderSpec{OrderID: orderID, ProductID: d.productID})
+	if err != nil {
+		d.setStatus("cancel: %v", err)
+		return
+	}
+	d.setStatus("canceled order %s", orderID)
+	d.poll(ctx, client)
+}
+
+type tuiActionKind int
+
+const (
+	tuiNone tuiActionKind = iota
+	tuiQuit
+	tuiPlaceOrder
+	tuiCancelOrder
+)
+
+// tuiAction is what a key press asks the tui to do.
+type tuiAction struct {
+	kind    tuiActionKind
+	order   coinbasepro.LimitOrder
+	orderID string
+}
+
+// tuiPrompt reads the answers to a sequence of questions on the bottom line, then calls done with them.
+type tuiPrompt struct {
+	questions []string
+	answers   []string
+	input     string
+	done      func(answers []string) tuiAction
+}
+
+// Keys of the dashboard.
+const (
+	keyCtrlC     = 3
+	keyBackspace = 127
+	keyEscape    = 27
+)
+
+// key handles a key press: b and s prompt for the price and size of a limit order to buy or sell, c prompts for the
+// number of an open order to cancel, and q quits. Every order and cancellation is confirmed before it is made.
+func (d *dashboard) key(key byte) tuiAction {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if key == keyCtrlC {
+		return tuiAction{kind: tuiQuit}
+	}
+	if d.prompt != nil {
+		return d.promptKey(key)
+	}
+	switch key {
+	case 'q':
+		return tuiAction{kind: tuiQuit}
+	case 'b', 's':
+		side := coinbasepro.SideBuy
+		if key == 's' {
+			side = coinbasepro.SideSell
+		}
+		d.prompt = &tuiPrompt{
+			questions: []string{string(side) + " price", string(side) + " size"},
+			done:      d.confirmOrder(side),
+		}
+	case 'c':
+		if len(d.orders) == 0 {
+			d.status = "no open orders to cancel"
+			break
+		}
+		d.prompt = &tuiPrompt{
+			questions: []string{fmt.Sprintf("cancel order number (1-%d)", len(d.orders))},
+			done:      d.confirmCancel,
+		}
+	}
+	return tuiAction{}
+}
+
+func (d *dashboard) promptKey(key byte) tuiAction {
+	p := d.prompt
+	switch key {
+	case keyEscape:
+		d.prompt = nil
+		d.status = "canceled"
+	case keyBackspace, '\b':
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case '\r', '\n':
+		p.answers = append(p.answers, strings.TrimSpace(p.input))
+		p.input = ""
+		if len(p.answers) == len(p.questions) {
+			d.prompt = nil
+			return p.done(p.answers)
+		}
+	default:
+		if key >= ' ' && key < keyBackspace {
+			p.input += string(key)
+		}
+	}
+	return tuiAction{}
+}
+
+// confirmOrder parses the price and size of the order and prompts to confirm it.
+func (d *dashboard) confirmOrder(side coinbasepro.Side) func([]string) tuiAction {
+	return func(answers []string) tuiAction {
+		price, err := decimal.NewFromString(answers[0])
+		if err != nil {
+			d.status = fmt.Sprintf("invalid price %q", answers[0])
+			return tuiAction{}
+		}
+		size, err := decimal.NewFromString(answers[1])
+		if err != nil {
+			d.status = fmt.Sprintf("invalid size %q", answers[1])
+			return tuiAction{}
+		}
+		order := coinbasepro.LimitOrder{
+			ProductID: d.productID,
+			Side:      side,
+			Type:      coinbasepro.OrderTypeLimit,
+			Price:     price,
+			Size:      size,
+		}
+		d.prompt = &tuiPrompt{
+			questions: []string{fmt.Sprintf("%s %s %s at %s? [y/N]", side, size, d.productID, price)},
+			done: func(answers []string) tuiAction {
+				if !yes(answers[0]) {
+					d.status = "order canceled"
+					return tuiAction{}
+				}
+				return tuiAction{kind: tuiPlaceOrder, order: order}
+			},
+		}
+		return tuiAction{}
+	}
+}
+
+// confirmCancel finds the numbered open order and prompts to confirm its cancellation.
+func (d *dashboard) confirmCancel(answers []string) tuiAction {
+	var n int
+	if _, err := fmt.Sscanf(answers[0], "%d", &n); err != nil || n < 1 || n > len(d.orders) {
+		d.status = fmt.Sprintf("no open order %q", answers[0])
+		return tuiAction{}
+	}
+	order := d.orders[n-1]
+	d.prompt = &tuiPrompt{
+		questions: []string{fmt.Sprintf("cancel %s %s at %s (%s)? [y/N]", order.Side, order.Size, decimalText(order.Price), order.ID)},
+		done: func(answers []string) tuiAction {
+			if !yes(answers[0]) {
+				d.status = "cancel canceled"
+				return tuiAction{}
+			}
+			return tuiAction{kind: tuiCancelOrder, orderID: order.ID}
+		},
+	}
+	return tuiAction{}
+}
+
+func yes(answer string) bool {
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+func decimalText(d *decimal.Decimal) string {
+	if d == nil {
+		return "-"
+	}
+	return d.String()
+}
+
+// render draws the dashboard on a cleared screen of the given size. The frame is drawn without holding the lock, so
+// that a slow terminal does not hold up the feed.
+func (d *dashboard) render(w io.Writer, width int, height int) {
+	_, _ = w.Write(d.frame(width, height))
+}
+
+// frame is the screen of the dashboard at the given size.
+func (d *dashboard) frame(width int, height int) []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var lines []string
+	lines = append(lines, d.header())
+	lines = append(lines, "")
+	book := d.book()
+	matches := d.recentMatches(len(book))
+	half := width / 2
+	for i := range book {
+		lines = append(lines, pad(book[i], half)+matches[i])
+	}
+	lines = append(lines, "")
+	lines = append(lines, d.openOrders()...)
+	lines = append(lines, "")
+	lines = append(lines, d.balances()...)
+
+	var buf bytes.Buffer
+	buf.WriteString("\x1b[H\x1b[2J")
+	for i := 0; i < len(lines) && i < height-2; i++ {
+		buf.WriteString(truncate(lines[i], width))
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString(fmt.Sprintf("\x1b[%d;1H", height-1))
+	buf.WriteString(truncate(d.status, width))
+	buf.WriteString(fmt.Sprintf("\x1b[%d;1H", height))
+	if d.prompt != nil {
+		buf.WriteString(truncate(d.prompt.questions[len(d.prompt.answers)]+": "+d.prompt.input, width))
+	} else {
+		buf.WriteString(truncate("[b]uy  [s]ell  [c]ancel  [q]uit", width))
+	}
+	return buf.Bytes()
+}
+
+func (d *dashboard) header() string {
+	if d.ticker == nil {
+		return fmt.Sprintf("%s  waiting for ticker", d.productID)
+	}
+	return fmt.Sprintf("%s  last %v  bid %v  ask %v  24h volume %v  %v",
+		d.productID, d.ticker["price"], d.ticker["best_bid"], d.ticker["best_ask"], d.ticker["volume_24h"], d.ticker["time"])
+}
+
+// book is the depth ladder: the asks above the bids, both nearest the spread in the middle.
+func (d *dashboard) book() []string {
+	if !d.synced {
+		return []string{"BOOK   waiting for snapshot"}
+	}
+	asks := sortedLevels(d.asks, false)
+	if len(asks) > d.depth {
+		asks = asks[:d.depth]
+	}
+	bids := sortedLevels(d.bids, true)
+	if len(bids) > d.depth {
+		bids = bids[:d.depth]
+	}
+	lines := []string{fmt.Sprintf("%-6s %14s %14s", "BOOK", "PRICE", "SIZE")}
+	for i := len(asks) - 1; i >= 0; i-- {
+		lines = append(lines, fmt.Sprintf("%-6s %14s %14s", "ask", asks[i], d.asks[asks[i]]))
+	}
+	for _, price := range bids {
+		lines = append(lines, fmt.Sprintf("%-6s %14s %14s", "bid", price, d.bids[price]))
+	}
+	return lines
+}
+
+// sortedLevels orders the prices of one side of the book from the spread outwards.
+func sortedLevels(side map[string]decimal.Decimal, descending bool) []string {
+	prices := make([]string, 0, len(side))
+	for price := range side {
+		prices = append(prices, price)
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		a, _ := decimal.NewFromString(prices[i])
+		b, _ := decimal.NewFromString(prices[j])
+		if descending {
+			return a.GreaterThan(b)
+		}
+		return a.LessThan(b)
+	})
+	return prices
+}
+
+// recentMatches returns n lines of matches, padded with empty lines.
+func (d *dashboard) recentMatches(n int) []string {
+	lines := make([]string, n)
+	if n == 0 {
+		return lines
+	}
+	lines[0] = fmt.Sprintf("%-8s %-4s %14s %14s", "MATCHES", "SIDE", "PRICE", "SIZE")
+	for i := 1; i < n && i-1 < len(d.matches); i++ {
+		m := d.matches[i-1]
+		clock := fmt.Sprint(m["time"])
+		if t, err := time.Parse(time.RFC3339Nano, clock); err == nil {
+			clock = t.Local().Format("15:04:05")
+		}
+		lines[i] = fmt.Sprintf("%-8s %-4v %14v %14v", clock, m["side"], m["price"], m["size"])
+	}
+	return lines
+}
+
+func (d *dashboard) openOrders() []string {
+	lines := []string{fmt.Sprintf("%-3s %-4s %14s %14s %14s %-8s %s", "#", "SIDE", "PRICE", "SIZE", "FILLED", "STATUS", "ID")}
+	for i, order := range d.orders {
+		lines = append(lines, fmt.Sprintf("%-3d %-4s %14s %14s %14s %-8s %s",
+			i+1, order.Side, decimalText(order.Price), order.Size, decimalText(order.FilledSize), order.Status, order.ID))
+	}
+	if len(d.orders) == 0 {
+		lines = append(lines, "no open orders")
+	}
+	return lines
+}
+
+func (d *dashboard) balances() []string {
+	lines := []string{fmt.Sprintf("%-8s %18s %18s %18s", "CURRENCY", "BALANCE", "AVAILABLE", "HOLD")}
+	for _, account := range d.accounts {
+		if account.Balance.IsZero() {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%-8s %18s %18s %18s", account.Currency, account.Balance, account.Available, account.Hold))
+	}
+	return lines
+}
+
+func pad(s string, width int) string {
+	if len(s) >= width {
+		return truncate(s, width-1) + " "
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+func truncate(s string, width int) string {
+	if width < 0 {
+		width = 0
+	}
+	if len(s) > width {
+		return s[:width]
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboard_Apply(t *testing.T) {
+	d := newDashboard("BTC-USD", 2)
+	d.apply(map[string]interface{}{
+		"type": "snapshot",
+		"bids": []interface{}{[]interface{}{"99.00", "1"}, []interface{}{"98.00", "2"}, []interface{}{"97.00", "3"}},
+		"asks": []interface{}{[]interface{}{"101.00", "1"}},
+	})
+	d.apply(map[string]interface{}{
+		"type":    "l2update",
+		"changes": []interface{}{[]interface{}{"buy", "99.00", "0"}, []interface{}{"sell", "100.50", "4"}},
+	})
+	d.apply(map[string]interface{}{"type": "match", "side": "buy", "price": "100.00", "size": "0.1"})
+	d.apply(map[string]interface{}{"type": "ticker", "price": "100.00", "best_bid": "98.00", "best_ask": "100.50"})
+
+	assert.Equal(t, []string{"98.00", "97.00"}, sortedLevels(d.bids, true))
+	assert.Equal(t, []string{"100.50", "101.00"}, sortedLevels(d.asks, false))
+	require.Len(t, d.matches, 1)
+	book := d.book()
+	require.Len(t, book, 5)
+	assert.Contains(t, book[1], "101.00")
+	assert.Contains(t, book[2], "100.50")
+	assert.Contains(t, book[3], "98.00")
+
+	var buf bytes.Buffer
+	d.render(&buf, 120, 30)
+	assert.Contains(t, buf.String(), "BTC-USD  last 100.00")
+	assert.Contains(t, buf.String(), "[b]uy  [s]ell  [c]ancel  [q]uit")
+}
+
+func TestDashboard_Disconnected(t *testing.T) {
+	d := newDashboard("BTC-USD", 2)
+	update := map[string]interface{}{
+		"type":    "l2update",
+		"changes": []interface{}{[]interface{}{"buy", "99.00", "1"}},
+	}
+	d.apply(update)
+	assert.Empty(t, d.bids)
+	assert.Equal(t, []string{"BOOK   waiting for snapshot"}, d.book())
+
+	d.apply(map[string]interface{}{"type": "snapshot", "bids": []interface{}{[]interface{}{"98.00", "2"}}})
+	d.apply(update)
+	d.apply(map[string]interface{}{"type": "ticker", "price": "100.00"})
+	assert.Equal(t, []string{"99.00", "98.00"}, sortedLevels(d.bids, true))
+
+	d.disconnected(errors.New("EOF"), time.Second)
+	assert.Equal(t, "feed: EOF, reconnecting in 1s", d.status)
+	assert.Nil(t, d.ticker)
+	d.apply(update)
+	assert.Empty(t, d.bids)
+	assert.Equal(t, []string{"BOOK   waiting for snapshot"}, d.book())
+}
+
+func TestDashboard_Render(t *testing.T) {
+	d := newDashboard("BTC-USD", 2)
+	// the feed updates the dashboard while a frame is written to the terminal
+	d.render(writerFunc(func(b []byte) (int, error) {
+		d.apply(map[string]interface{}{"type": "subscriptions"})
+		return len(b), nil
+	}), 80, 24)
+	assert.Equal(t, "connected", d.status)
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (w writerFunc) Write(b []byte) (int, error) {
+	return w(b)
+}
+
+func keys(d *dashboard, s string) tuiAction {
+	var action tuiAction
+	for i := 0; i < len(s); i++ {
+		action = d.key(s[i])
+	}
+	return action
+}
+
+func TestDashboard_Key(t *testing.T) {
+	d := newDashboard("BTC-USD", 10)
+	action := keys(d, "b100.5\r0.01\ry\r")
+	require.Equal(t, tuiPlaceOrder, action.kind)
+	assert.Equal(t, coinbasepro.SideBuy, action.order.Side)
+	assert.Equal(t, "100.5", action.order.Price.String())
+	assert.Equal(t, "0.01", action.order.Size.String())
+	assert.Nil(t, d.prompt)
+
+	assert.Equal(t, tuiNone, keys(d, "s100\r1\rn\r").kind)
+	assert.Equal(t, "order canceled", d.status)
+	assert.Equal(t, tuiNone, keys(d, "sx\r1\r").kind)
+	assert.Equal(t, `invalid price "x"`, d.status)
+	assert.Nil(t, d.prompt)
+
+	assert.Equal(t, tuiNone, keys(d, "c").kind)
+	assert.Equal(t, "no open orders to cancel", d.status)
+	price := decimal.NewFromInt(100)
+	d.orders = []*coinbasepro.Order{{ID: "a", Price: &price}, {ID: "b", Price: &price}}
+	action = keys(d, "c2\ry\r")
+	assert.Equal(t, tuiAction{kind: tuiCancelOrder, orderID: "b"}, action)
+
+	assert.Equal(t, tuiNone, keys(d, "b1\x1b").kind)
+	assert.Nil(t, d.prompt)
+	assert.Equal(t, tuiQuit, keys(d, "q").kind)
+}
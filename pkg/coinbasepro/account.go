@@ -58,13 +58,18 @@ const (
 	LedgerEntryTypeMatch LedgerEntryType = "match"
 	// LedgerEntryTypeRebate fee rebate as per coinbasepro fee schedule (see https://pro.coinbase.com/fees)
 	LedgerEntryTypeRebate LedgerEntryType = "rebate"
+	// LedgerEntryTypeTransfer funds deposited to or withdrawn from the Account
+	LedgerEntryTypeTransfer LedgerEntryType = "transfer"
 )
 
-// LedgerDetails contains additional details for LedgerEntryTypeFee and LedgerEntryTypeMatch trades.
+// LedgerDetails contains additional details for LedgerEntryTypeFee and LedgerEntryTypeMatch trades, and for
+// LedgerEntryTypeTransfer entries.
 type LedgerDetails struct {
-	OrderID   string `json:"order_id"`
-	ProductID string `json:"product_id"`
-	TradeID   string `json:"trade_id"`
+	OrderID      string `json:"order_id"`
+	ProductID    string `json:"product_id"`
+	TradeID      string `json:"trade_id"`
+	TransferID   string `json:"transfer_id,omitempty"`
+	TransferType string `json:"transfer_type,omitempty"`
 }
 
 // UnmarshalJSON allows the raw slice of entries to be mapped to a named field on the struct.
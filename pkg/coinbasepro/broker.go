@@ -0,0 +1,269 @@
+package coinbasepro
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// channelMessageTypes are the types of the messages of each channel of the feed. Messages do not name their channel,
+// so a Subscription selects channels by these types.
+var channelMessageTypes = map[ChannelName][]string{
+	ChannelNameHeartbeat: {"heartbeat"},
+	ChannelNameStatus:    {"status"},
+	ChannelNameTicker:    {"ticker"},
+	ChannelNameLevel2:    {"snapshot", "l2update"},
+	ChannelNameFull:      {"received", "open", "done", "match", "change", "activate"},
+	ChannelNameUser:      {"received", "open", "done", "match", "change", "activate"},
+	ChannelNameMatches:   {"match", "last_match"},
+}
+
+// OverflowPolicy decides what a Subscription does with a message that arrives when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest drops the message that arrived.
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest buffered message to make room for the message that arrived.
+	OverflowDropOldest
+	// OverflowBlock waits for the subscriber to make room, which holds up every other Subscription of the Broker.
+	OverflowBlock
+)
+
+// SubscriptionFilter selects the messages of a Subscription. Each list that is not empty must contain the channel,
+// product id or type of a message for it to be selected.
+type SubscriptionFilter struct {
+	Channels   []ChannelName
+	ProductIDs []ProductID
+	Types      []string
+}
+
+func (f SubscriptionFilter) matches(message interface{}) bool {
+	fields, _ := message.(map[string]interface{})
+	messageType, _ := fields["type"].(string)
+	productID, _ := fields["product_id"].(string)
+	if len(f.Types) > 0 && !containsString(f.Types, messageType) {
+		return false
+	}
+	if len(f.ProductIDs) > 0 && !containsProductID(f.ProductIDs, ProductID(productID)) {
+		return false
+	}
+	if len(f.Channels) == 0 {
+		return true
+	}
+	for _, channel := range f.Channels {
+		if containsString(channelMessageTypes[channel], messageType) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsProductID(values []ProductID, value ProductID) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionOptions configure a Subscription. A Buffer of zero buffers 256 messages.
+type SubscriptionOptions struct {
+	Filter   SubscriptionFilter
+	Buffer   int
+	Overflow OverflowPolicy
+}
+
+// Subscription receives the messages of a Broker selected by its filter on Messages, until it is unsubscribed.
+type Subscription struct {
+	Messages <-chan interface{}
+
+	messages chan interface{}
+	options  SubscriptionOptions
+	broker   *Broker
+	done     chan struct{}
+	once     sync.Once
+	mu       sync.Mutex
+	closed   bool
+	dropped  uint64
+}
+
+// Unsubscribe stops the delivery of messages and closes Messages. It is safe to call more than once.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		close(s.done)
+		s.broker.remove(s)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		close(s.messages)
+	})
+}
+
+// Dropped is the number of messages the Subscription has dropped because its buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *Subscription) deliver(message interface{}, metrics Metrics) {
+	if !s.options.Filter.matches(message) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.messages <- message:
+		return
+	default:
+	}
+	switch s.options.Overflow {
+	case OverflowBlock:
+		select {
+		case s.messages <- message:
+		case <-s.done:
+		}
+		return
+	case OverflowDropOldest:
+		select {
+		case <-s.messages:
+		default:
+		}
+		select {
+		case s.messages <- message:
+		default:
+		}
+	}
+	atomic.AddUint64(&s.dropped, 1)
+	metrics.ObserveDroppedMessage()
+	s.broker.logger.Debug("drop message of full subscription")
+}
+
+// Broker multiplexes one connection to the websocket feed to any number of Subscriptions in the process.
+type Broker struct {
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+	metrics       Metrics
+	logger        Logger
+}
+
+// NewBroker creates a Broker without Subscriptions. Of the options, only WithLogger applies to a Broker.
+func NewBroker(opts ...Option) *Broker {
+	return &Broker{
+		subscriptions: make(map[*Subscription]struct{}),
+		logger:        loggerOr(newOptions(opts).logger),
+	}
+}
+
+// Instrument observes the reconnects of the Broker, and the messages its Subscriptions drop, with metrics. Instrument
+// the Watcher to observe the messages of the feed.
+func (b *Broker) Instrument(metrics Metrics) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.metrics = metrics
+}
+
+// Watcher is the source of the messages of a Broker, such as a Client.
+type Watcher interface {
+	Watch(ctx context.Context, subscriptionRequest SubscriptionRequest, feed Feed) error
+}
+
+// Subscribe adds a Subscription that receives the messages published after it is added.
+func (b *Broker) Subscribe(options SubscriptionOptions) *Subscription {
+	if options.Buffer <= 0 {
+		options.Buffer = 256
+	}
+	messages := make(chan interface{}, options.Buffer)
+	s := &Subscription{
+		Messages: messages,
+		messages: messages,
+		options:  options,
+		broker:   b,
+		done:     make(chan struct{}),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions[s] = struct{}{}
+	return s
+}
+
+func (b *Broker) remove(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscriptions, s)
+}
+
+// Subscriptions is the number of current Subscriptions.
+func (b *Broker) Subscriptions() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscriptions)
+}
+
+// Publish delivers the message to every Subscription that selects it.
+func (b *Broker) Publish(message interface{}) {
+	b.mu.Lock()
+	subscriptions := make([]*Subscription, 0, len(b.subscriptions))
+	for s := range b.subscriptions {
+		subscriptions = append(subscriptions, s)
+	}
+	metrics := observe(b.metrics)
+	b.mu.Unlock()
+	for _, s := range subscriptions {
+		s.deliver(message, metrics)
+	}
+}
+
+// Run watches the feed of the subscription request and publishes its messages until the context is done. When the
+// connection fails, Run reconnects after a delay that doubles from one second up to thirty seconds. Messages are only
+// dropped by the Subscriptions, according to their OverflowPolicy, never before they are published.
+func (b *Broker) Run(ctx context.Context, watcher Watcher, subscriptionRequest SubscriptionRequest) error {
+	feed := NewBlockingFeed(256)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message := <-feed.Messages:
+				b.Publish(message)
+			}
+		}
+	}()
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := watcher.Watch(ctx, subscriptionRequest, feed)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		b.logger.Warn("feed disconnected", "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		b.mu.Lock()
+		metrics := observe(b.metrics)
+		b.mu.Unlock()
+		metrics.ObserveReconnect()
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+}
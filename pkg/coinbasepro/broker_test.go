@@ -0,0 +1,183 @@
+package coinbasepro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func message(messageType string, productID string) map[string]interface{} {
+	return map[string]interface{}{"type": messageType, "product_id": productID}
+}
+
+func TestSubscriptionFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  SubscriptionFilter
+		message interface{}
+		want    bool
+	}{
+		{"Empty", SubscriptionFilter{}, message("ticker", "BTC-USD"), true},
+		{"Type", SubscriptionFilter{Types: []string{"ticker"}}, message("ticker", "BTC-USD"), true},
+		{"OtherType", SubscriptionFilter{Types: []string{"ticker"}}, message("heartbeat", "BTC-USD"), false},
+		{"Product", SubscriptionFilter{ProductIDs: []ProductID{"BTC-USD"}}, message("ticker", "BTC-USD"), true},
+		{"OtherProduct", SubscriptionFilter{ProductIDs: []ProductID{"BTC-USD"}}, message("ticker", "ETH-USD"), false},
+		{"Channel", SubscriptionFilter{Channels: []ChannelName{ChannelNameLevel2}}, message("l2update", "BTC-USD"), true},
+		{"OtherChannel", SubscriptionFilter{Channels: []ChannelName{ChannelNameLevel2}}, message("match", "BTC-USD"), false},
+		{"Channels", SubscriptionFilter{Channels: []ChannelName{ChannelNameLevel2, ChannelNameMatches}}, message("match", "BTC-USD"), true},
+		{"NotMessage", SubscriptionFilter{Types: []string{"ticker"}}, "text", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.matches(tt.message))
+		})
+	}
+}
+
+func TestBroker(t *testing.T) {
+	broker := NewBroker()
+	tickers := broker.Subscribe(SubscriptionOptions{Filter: SubscriptionFilter{Channels: []ChannelName{ChannelNameTicker}}})
+	all := broker.Subscribe(SubscriptionOptions{})
+	assert.Equal(t, 2, broker.Subscriptions())
+
+	broker.Publish(message("heartbeat", "BTC-USD"))
+	broker.Publish(message("ticker", "BTC-USD"))
+	assert.Equal(t, message("ticker", "BTC-USD"), <-tickers.Messages)
+	assert.Equal(t, message("heartbeat", "BTC-USD"), <-all.Messages)
+	assert.Equal(t, message("ticker", "BTC-USD"), <-all.Messages)
+
+	tickers.Unsubscribe()
+	tickers.Unsubscribe()
+	_, ok := <-tickers.Messages
+	assert.False(t, ok, "messages are closed by unsubscribe")
+	assert.Equal(t, 1, broker.Subscriptions())
+	broker.Publish(message("ticker", "BTC-USD"))
+	assert.Equal(t, message("ticker", "BTC-USD"), <-all.Messages)
+	all.Unsubscribe()
+}
+
+func TestBroker_Overflow(t *testing.T) {
+	broker := NewBroker()
+	newest := broker.Subscribe(SubscriptionOptions{Buffer: 2, Overflow: OverflowDropNewest})
+	oldest := broker.Subscribe(SubscriptionOptions{Buffer: 2, Overflow: OverflowDropOldest})
+	for _, messageType := range []string{"a", "b", "c"} {
+		broker.Publish(message(messageType, ""))
+	}
+	assert.Equal(t, uint64(1), newest.Dropped())
+	assert.Equal(t, "a", (<-newest.Messages).(map[string]interface{})["type"])
+	assert.Equal(t, "b", (<-newest.Messages).(map[string]interface{})["type"])
+	assert.Equal(t, uint64(1), oldest.Dropped())
+	assert.Equal(t, "b", (<-oldest.Messages).(map[string]interface{})["type"])
+	assert.Equal(t, "c", (<-oldest.Messages).(map[string]interface{})["type"])
+	newest.Unsubscribe()
+	oldest.Unsubscribe()
+
+	blocking := broker.Subscribe(SubscriptionOptions{Buffer: 1, Overflow: OverflowBlock})
+	broker.Publish(message("a", ""))
+	published := make(chan struct{})
+	go func() {
+		broker.Publish(message("b", ""))
+		close(published)
+	}()
+	select {
+	case <-published:
+		t.Fatal("publish did not wait for a blocking subscription")
+	case <-time.After(10 * time.Millisecond):
+	}
+	assert.Equal(t, "a", (<-blocking.Messages).(map[string]interface{})["type"])
+	<-published
+	assert.Equal(t, "b", (<-blocking.Messages).(map[string]interface{})["type"])
+	assert.Equal(t, uint64(0), blocking.Dropped())
+
+	broker.Publish(message("c", ""))
+	go broker.Publish(message("d", ""))
+	time.Sleep(10 * time.Millisecond)
+	blocking.Unsubscribe()
+}
+
+func TestBroker_Run(t *testing.T) {
+	broker := NewBroker()
+	subscription := broker.Subscribe(SubscriptionOptions{})
+	defer subscription.Unsubscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcher := watchFunc(func(ctx context.Context, _ SubscriptionRequest, feed Feed) error {
+		feed.Messages <- message("ticker", "BTC-USD")
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	done := make(chan error)
+	go func() { done <- broker.Run(ctx, watcher, SubscriptionRequest{}) }()
+	assert.Equal(t, message("ticker", "BTC-USD"), <-subscription.Messages)
+	cancel()
+	require.True(t, errors.Is(<-done, context.Canceled))
+}
+
+func TestBroker_Run_SlowSubscriber(t *testing.T) {
+	for _, overflow := range []OverflowPolicy{OverflowDropNewest, OverflowBlock} {
+		broker := NewBroker()
+		slow := broker.Subscribe(SubscriptionOptions{Buffer: 1, Overflow: overflow})
+		fast := broker.Subscribe(SubscriptionOptions{Buffer: 1000})
+		ctx, cancel := context.WithCancel(context.Background())
+		messages := make([]string, 500)
+		for i := range messages {
+			messages[i] = fmt.Sprintf(`{"type":"ticker","sequence":%d}`, i)
+		}
+		reader := &sliceReader{messages: messages, done: ctx.Done()}
+		var client Client
+		watcher := watchFunc(func(ctx context.Context, _ SubscriptionRequest, feed Feed) error {
+			return client.watch(ctx, reader, feed, nil)
+		})
+		done := make(chan error)
+		go func() { done <- broker.Run(ctx, watcher, SubscriptionRequest{}) }()
+		if overflow == OverflowBlock {
+			go func() {
+				for range slow.Messages {
+					time.Sleep(time.Millisecond / 10)
+				}
+			}()
+		}
+		for i := range messages {
+			select {
+			case m := <-fast.Messages:
+				assert.Equal(t, float64(i), m.(map[string]interface{})["sequence"])
+			case <-time.After(5 * time.Second):
+				t.Fatalf("overflow %d: message %d was lost", overflow, i)
+			}
+		}
+		assert.Equal(t, uint64(0), fast.Dropped())
+		cancel()
+		require.True(t, errors.Is(<-done, context.Canceled))
+		slow.Unsubscribe()
+		fast.Unsubscribe()
+	}
+}
+
+// sliceReader reads its messages in order, then waits for done.
+type sliceReader struct {
+	messages []string
+	done     <-chan struct{}
+}
+
+func (r *sliceReader) ReadJSON(v interface{}) error {
+	if len(r.messages) == 0 {
+		<-r.done
+		return io.EOF
+	}
+	message := r.messages[0]
+	r.messages = r.messages[1:]
+	return json.Unmarshal([]byte(message), v)
+}
+
+type watchFunc func(ctx context.Context, subscriptionRequest SubscriptionRequest, feed Feed) error
+
+func (w watchFunc) Watch(ctx context.Context, subscriptionRequest SubscriptionRequest, feed Feed) error {
+	return w(ctx, subscriptionRequest, feed)
+}
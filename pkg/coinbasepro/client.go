@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,7 +19,6 @@ import (
 	"github.com/durp/reticule/pkg/phizog"
 	"github.com/gorilla/websocket"
 	"github.com/mitchellh/mapstructure"
-	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"golang.org/x/sync/errgroup"
 )
@@ -46,6 +47,22 @@ func (c *Client) GetLedger(ctx context.Context, accountID string, pagination Pag
 	return ledger, c.api.Get(ctx, fmt.Sprintf("/accounts/%s/ledger/%s", accountID, query), &ledger)
 }
 
+// ListLedger retrieves every page of GetLedger for the Account, newest first.
+func (c *Client) ListLedger(ctx context.Context, accountID string) ([]*LedgerEntry, error) {
+	var all []*LedgerEntry
+	pagination := PaginationParams{Limit: 100}
+	for {
+		ledger, err := c.GetLedger(ctx, accountID, pagination)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, ledger.Entries...)
+		if !nextPage(ledger.Page, len(ledger.Entries), &pagination) {
+			return all, nil
+		}
+	}
+}
+
 // GetHolds retrieves the list of Holds for the Account. The requested Account must belong to the current Profile.
 func (c *Client) GetHolds(ctx context.Context, accountID string, pagination PaginationParams) (Holds, error) {
 	if err := pagination.Validate(); err != nil {
@@ -115,6 +132,23 @@ func (c *Client) GetFills(ctx context.Context, filter FillFilter, pagination Pag
 	return fills, c.api.Get(ctx, fmt.Sprintf("/fills/%s", query(params)), &fills)
 }
 
+// ListFills retrieves every page of GetFills, newest first. ListFills can make many requests for a busy Profile;
+// narrow the FillFilter where possible.
+func (c *Client) ListFills(ctx context.Context, filter FillFilter) ([]*Fill, error) {
+	var all []*Fill
+	pagination := PaginationParams{Limit: 100}
+	for {
+		fills, err := c.GetFills(ctx, filter, pagination)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, fills.Fills...)
+		if !nextPage(fills.Page, len(fills.Fills), &pagination) {
+			return all, nil
+		}
+	}
+}
+
 // GetLimits retrieves the payment method transfer limits and per currency buy/sell limits for the current Profile.
 func (c *Client) GetLimits(ctx context.Context) (Limits, error) {
 	var limits Limits
@@ -144,6 +178,35 @@ func (c *Client) GetDeposits(ctx context.Context, filter DepositFilter, paginati
 	return deposits, nil
 }
 
+// ListDeposits retrieves every page of GetDeposits, newest first. Unless the DepositFilter specifies a Type, both
+// DepositTypeDeposit and DepositTypeInternal Deposits are retrieved, each with their own requests, so that the
+// Transfers filtering done by GetDeposits cannot cut pagination short.
+func (c *Client) ListDeposits(ctx context.Context, filter DepositFilter) ([]*Deposit, error) {
+	types := []DepositType{filter.Type}
+	if filter.Type == "" {
+		types = []DepositType{DepositTypeDeposit, DepositTypeInternal}
+	}
+	var all []*Deposit
+	for _, depositType := range types {
+		filter.Type = depositType
+		pagination := PaginationParams{Limit: 100}
+		for {
+			deposits, err := c.GetDeposits(ctx, filter, pagination)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, deposits.Deposits...)
+			if !nextPage(deposits.Page, len(deposits.Deposits), &pagination) {
+				break
+			}
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].CreatedAt.Time().After(all[j].CreatedAt.Time())
+	})
+	return all, nil
+}
+
 // GetDeposit retrieves the details for a single Deposit. The Deposit must belong to the current Profile.
 func (c *Client) GetDeposit(ctx context.Context, depositID string) (Deposit, error) {
 	var deposit Deposit
@@ -208,6 +271,35 @@ func (c *Client) GetWithdrawals(ctx context.Context, filter WithdrawalFilter, pa
 	return withdrawals, nil
 }
 
+// ListWithdrawals retrieves every page of GetWithdrawals, newest first. Unless the WithdrawalFilter specifies a Type,
+// both WithdrawalTypeWithdraw and WithdrawalTypeInternal Withdrawals are retrieved, each with their own requests, so
+// that the Transfers filtering done by GetWithdrawals cannot cut pagination short.
+func (c *Client) ListWithdrawals(ctx context.Context, filter WithdrawalFilter) ([]*Withdrawal, error) {
+	types := []WithdrawalType{filter.Type}
+	if filter.Type == "" {
+		types = []WithdrawalType{WithdrawalTypeWithdraw, WithdrawalTypeInternal}
+	}
+	var all []*Withdrawal
+	for _, withdrawalType := range types {
+		filter.Type = withdrawalType
+		pagination := PaginationParams{Limit: 100}
+		for {
+			withdrawals, err := c.GetWithdrawals(ctx, filter, pagination)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, withdrawals.Withdrawals...)
+			if !nextPage(withdrawals.Page, len(withdrawals.Withdrawals), &pagination) {
+				break
+			}
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].CreatedAt.Time().After(all[j].CreatedAt.Time())
+	})
+	return all, nil
+}
+
 // GetWithdrawal retrieves the details of a single Withdrawal. The Withdrawal must belong to the current Profile.
 func (c *Client) GetWithdrawal(ctx context.Context, withdrawalID string) (Withdrawal, error) {
 	var withdrawal Withdrawal
@@ -308,6 +400,62 @@ func (c *Client) GetReport(ctx context.Context, reportID string) (Report, error)
 	return report, c.api.Get(ctx, fmt.Sprintf("/reports/%s", reportID), &report)
 }
 
+// WaitForReport polls GetReport until the Report is ready, waiting between polls according to the ReportBackoff.
+// WaitForReport returns ErrReportExpired if the Report expires before it is ready, and the context error if ctx is
+// done first.
+func (c *Client) WaitForReport(ctx context.Context, reportID string, backoff ReportBackoff) (Report, error) {
+	backoff = backoff.withDefaults()
+	delay := backoff.Initial
+	for {
+		report, err := c.GetReport(ctx, reportID)
+		if err != nil {
+			return Report{}, err
+		}
+		if report.Status == ReportStatusReady {
+			return report, nil
+		}
+		if expiresAt := report.ExpiresAt.Time(); !expiresAt.IsZero() && expiresAt.Before(c.now()) {
+			return report, ErrReportExpired
+		}
+		loggerOr(c.logger).Debug("report is not ready", "report_id", reportID, "status", report.Status, "delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return report, ctx.Err()
+		case <-timer.C:
+		}
+		delay = backoff.next(delay)
+	}
+}
+
+// DownloadReport writes the file of a ready Report to the path on fs. The FileURL is pre-signed, so the download is
+// not authenticated, but it is sent with the http.Client of the options of the Client.
+func (c *Client) DownloadReport(ctx context.Context, report Report, fs afero.Fs, path string) (capture error) {
+	if report.Status != ReportStatusReady || report.FileURL == "" {
+		return fmt.Errorf("report %s is %s and cannot be downloaded", report.ID, report.Status)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", report.FileURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { Capture(&capture, resp.Body.Close()) }()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("download of report %s failed: %s", report.ID, resp.Status)
+	}
+	f, err := fs.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { Capture(&capture, f.Close()) }()
+	_, err = io.Copy(f, resp.Body)
+	return err
+}
+
 // ListProfiles retrieves a list of Profiles (portfolio equivalents). A given user can have a maximum of 10 profiles.
 // The list is not paginated.
 func (c *Client) ListProfiles(ctx context.Context, filter ProfileFilter) ([]Profile, error) {
@@ -399,27 +547,39 @@ func (c *Client) GetServerTime(ctx context.Context) (ServerTime, error) {
 	return serverTime, c.api.Get(ctx, "/time", &serverTime)
 }
 
-// Watch provides a feed of real-time market data updates for orders and trades.
+// Watch provides a feed of real-time market data updates for orders and trades. Messages the consumer of the feed is
+// not ready to receive are dropped, unless the feed blocks.
 func (c *Client) Watch(ctx context.Context, subscriptionRequest SubscriptionRequest, feed Feed) (capture error) {
 	wsConn, err := c.dialer.Dial()
 	if err != nil {
 		return err
 	}
+	defer func() { Capture(&capture, wsConn.Close()) }()
 	// subscription request must be sent within 5 seconds of open or socket will auto-close
 	err = wsConn.WriteJSON(subscriptionRequest)
 	if err != nil {
 		return err
 	}
-	return c.watch(ctx, wsConn, feed)
+	var gaps sequences
+	if subscribes(subscriptionRequest, ChannelNameFull) {
+		// the user channel is a subset of the full channel, so only the full channel has no gaps in its sequence
+		gaps = make(sequences)
+	}
+	return c.watch(ctx, wsConn, feed, gaps)
 }
 
 type websocketFeedDialer struct {
-	FeedURL string
+	FeedURL          string
+	Proxy            *url.URL
+	HandshakeTimeout time.Duration
 }
 
 // Dial returns a connection to the FeedURL websocket.
 func (w websocketFeedDialer) Dial() (*websocket.Conn, error) {
-	var wsDialer websocket.Dialer
+	wsDialer := websocket.Dialer{HandshakeTimeout: w.HandshakeTimeout}
+	if w.Proxy != nil {
+		wsDialer.Proxy = http.ProxyURL(w.Proxy)
+	}
 	wsConn, resp, err := wsDialer.Dial(w.FeedURL, nil)
 	if err != nil {
 		return nil, err
@@ -433,38 +593,54 @@ type jsonReader interface {
 	ReadJSON(v interface{}) error
 }
 
-func (c *Client) watch(ctx context.Context, r jsonReader, feed Feed) (capture error) {
+func (c *Client) watch(ctx context.Context, r jsonReader, feed Feed, gaps sequences) (capture error) {
+	metrics, logger := observe(c.metrics), loggerOr(c.logger)
 	messages := make(chan interface{})
 	wg, ctx := errgroup.WithContext(ctx)
 	wg.Go(func() error {
 		defer close(messages)
 		for {
+			logger.Debug("receive message on socket")
+			// TODO: Does message have a real structure
+			var message interface{}
+			err := r.ReadJSON(&message)
+			if err != nil {
+				// a failed read ends the watch, so that the caller can reconnect
+				return err
+			}
+			if fields, ok := message.(map[string]interface{}); ok {
+				messageType, _ := fields["type"].(string)
+				metrics.ObserveMessage(messageType)
+			}
+			if productID, missed := gaps.gap(message); missed > 0 {
+				metrics.ObserveSequenceGap(productID, missed)
+			}
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-				// TODO: Does this prevent blocking?
-			case messages <- func() interface{} {
-				logrus.Debug("receive message on socket")
-				// TODO: Does message have a real structure
-				var message interface{}
-				err := r.ReadJSON(&message)
-				if err != nil {
-					return err
-				}
-				return message
-			}():
+			case messages <- message:
 			}
 		}
 	})
 
 	wg.Go(func() error {
 		for message := range messages {
+			if feed.Block {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case feed.Messages <- message:
+					logger.Debug("publish message on channel")
+				}
+				continue
+			}
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
 			case feed.Messages <- message:
-				logrus.Debug("publish message on channel")
+				logger.Debug("publish message on channel")
 			default:
+				metrics.ObserveDroppedMessage()
 			}
 		}
 		return nil
@@ -476,17 +652,27 @@ func (c *Client) Close() error {
 	return c.api.Close()
 }
 
-// NewClient creates a high-level Coinbase Pro API client.
-func NewClient(baseURL *url.URL, feedURL *url.URL, auth *Auth) (*Client, error) {
-	apiClient, err := NewAPIClient(baseURL, feedURL, auth)
+// NewClient creates a high-level Coinbase Pro API client, configured by the options.
+func NewClient(baseURL *url.URL, feedURL *url.URL, auth *Auth, opts ...Option) (*Client, error) {
+	apiClient, err := NewAPIClient(baseURL, feedURL, auth, opts...)
 	if err != nil {
 		return nil, err
 	}
+	o := newOptions(opts)
+	now := o.clock
+	if apiClient.serverClock != nil {
+		now = apiClient.serverClock.now
+	}
 	return &Client{
 		api: apiClient,
 		dialer: websocketFeedDialer{
-			FeedURL: feedURL.String(),
+			FeedURL:          feedURL.String(),
+			Proxy:            o.proxy,
+			HandshakeTimeout: o.timeout,
 		},
+		httpClient: apiClient.httpClient,
+		clock:      now,
+		logger:     o.logger,
 	}, nil
 }
 
@@ -498,6 +684,21 @@ func DevelopmentMode(client *Client) {
 	client.api = devClient
 }
 
+// DryRunMode replaces the api of the client with a DryRunClient that passes each request that would change state to
+// sink instead of sending it.
+func DryRunMode(client *Client, sink func(SignedRequest) error) {
+	var signer *APIClient
+	switch api := client.api.(type) {
+	case *APIClient:
+		signer = api
+	case *DevelopmentClient:
+		signer = api.api
+	default:
+		panic(fmt.Sprintf("dry run is not supported for %T", client.api))
+	}
+	client.api = NewDryRunClient(client.api, signer, sink)
+}
+
 type apier interface {
 	Get(ctx context.Context, relativePath string, result interface{}) error
 	Do(ctx context.Context, method string, relativePath string, content interface{}, result interface{}) (capture error)
@@ -512,6 +713,25 @@ type dialer interface {
 type Client struct {
 	api    apier
 	dialer dialer
+	// httpClient downloads the files of Reports, which are not requests of the api
+	httpClient *http.Client
+	clock      func() time.Time
+	metrics    Metrics
+	logger     Logger
+}
+
+func (c *Client) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock()
+}
+
+func (c *Client) client() *http.Client {
+	if c.httpClient == nil {
+		return http.DefaultClient
+	}
+	return c.httpClient
 }
 
 func query(params []string) string {
@@ -521,25 +741,50 @@ func query(params []string) string {
 	return "?" + strings.Join(params, "&")
 }
 
-func NewAPIClient(baseURL *url.URL, feedURL *url.URL, auth *Auth) (*APIClient, error) {
+// NewAPIClient creates a Coinbase Pro API client that signs its requests with auth, configured by the options.
+func NewAPIClient(baseURL *url.URL, feedURL *url.URL, auth *Auth, opts ...Option) (*APIClient, error) {
+	o := newOptions(opts)
+	httpClient, err := o.client()
+	if err != nil {
+		return nil, err
+	}
+	now := o.clock
+	var clock *serverClock
+	if o.serverTime > 0 {
+		clock, err = newServerClock(baseURL, httpClient, o.clock, o.logger, o.serverTime)
+		if err != nil {
+			return nil, err
+		}
+		now = clock.now
+	}
 	apiClient := APIClient{
 		auth:       auth,
 		baseURL:    baseURL,
 		feedURL:    feedURL,
-		httpClient: http.DefaultClient,
+		httpClient: httpClient,
 		timestamp: func() string {
-			return strconv.FormatInt(time.Now().Unix(), 10)
+			return strconv.FormatInt(now().Unix(), 10)
 		},
+		userAgent:   o.userAgent,
+		limiter:     o.limiter,
+		logger:      o.logger,
+		serverClock: clock,
 	}
 	return &apiClient, nil
 }
 
 type APIClient struct {
-	auth       *Auth
-	baseURL    *url.URL
-	feedURL    *url.URL
-	httpClient *http.Client
-	timestamp  func() string
+	auth        *Auth
+	baseURL     *url.URL
+	feedURL     *url.URL
+	httpClient  *http.Client
+	timestamp   func() string
+	userAgent   string
+	limiter     RateLimiter
+	logger      Logger
+	serverClock *serverClock
+	metrics     Metrics
+	tracer      Tracer
 }
 
 func (a *APIClient) Get(ctx context.Context, relativePath string, result interface{}) error {
@@ -547,7 +792,9 @@ func (a *APIClient) Get(ctx context.Context, relativePath string, result interfa
 }
 
 func (a *APIClient) Do(ctx context.Context, method string, relativePath string, content interface{}, result interface{}) (capture error) {
+	ctx, span := startRequest(ctx, a.tracer, a.baseURL, method, relativePath)
 	resp, err := a.do(ctx, method, relativePath, content, result)
+	defer func() { span.finish(resp, capture) }()
 	if err != nil {
 		return err
 	}
@@ -561,33 +808,28 @@ func (a *APIClient) Do(ctx context.Context, method string, relativePath string,
 }
 
 func (a *APIClient) do(ctx context.Context, method string, relativePath string, content interface{}, result interface{}) (resp *http.Response, capture error) {
-	uri, err := a.baseURL.Parse(relativePath)
-	if err != nil {
-		return nil, err
-	}
-	logrus.Debugf("%s %s", method, relativePath)
-	var b bytes.Buffer
-	if content != nil {
-		err = json.NewEncoder(&b).Encode(content)
-		if err != nil {
+	if a.limiter != nil {
+		waited := time.Now()
+		if err := a.limiter.Wait(ctx); err != nil {
 			return nil, err
 		}
+		observe(a.metrics).ObserveRateLimitWait(time.Since(waited))
 	}
-	timestamp := a.timestamp()
-	msg := fmt.Sprintf("%s%s%s%s", timestamp, method, relativePath, b.Bytes())
-	signature, err := a.auth.Sign(msg)
+	req, err := a.request(ctx, method, relativePath, content)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, method, uri.String(), &b)
-	if err != nil {
-		return nil, err
-	}
-	a.addHeaders(req, timestamp, signature)
+	started, status := time.Now(), 0
+	defer func() {
+		duration := time.Since(started)
+		observe(a.metrics).ObserveRequest(method, endpoint(relativePath), status, duration)
+		loggerOr(a.logger).Debug("request", "method", method, "path", relativePath, "status", status, "duration", duration)
+	}()
 	resp, err = a.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	status = resp.StatusCode
 	if resp.StatusCode >= 300 {
 		coinbaseErr := Error{StatusCode: resp.StatusCode}
 		decoder := json.NewDecoder(resp.Body)
@@ -606,6 +848,33 @@ func (a *APIClient) do(ctx context.Context, method string, relativePath string,
 	return resp, err
 }
 
+// request creates the signed http.Request for the api call.
+func (a *APIClient) request(ctx context.Context, method string, relativePath string, content interface{}) (*http.Request, error) {
+	uri, err := a.baseURL.Parse(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if content != nil {
+		err = json.NewEncoder(&b).Encode(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	timestamp := a.timestamp()
+	msg := fmt.Sprintf("%s%s%s%s", timestamp, method, relativePath, b.Bytes())
+	signature, err := a.auth.Sign(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri.String(), &b)
+	if err != nil {
+		return nil, err
+	}
+	a.addHeaders(req, timestamp, signature)
+	return req, nil
+}
+
 func isPaged(resp *http.Response) bool {
 	return resp.Header.Get("CB-BEFORE") != "" && resp.Header.Get("CB-AFTER") != ""
 }
@@ -629,18 +898,31 @@ func paginate(resp *http.Response, result interface{}) error {
 func (a *APIClient) addHeaders(req *http.Request, timestamp string, signature string) {
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("User-Agent", "Golang Reticule v0.1")
+	userAgent := a.userAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	req.Header.Add("User-Agent", userAgent)
 	req.Header.Add("CB-ACCESS-KEY", a.auth.Key)
 	req.Header.Add("CB-ACCESS-PASSPHRASE", a.auth.Passphrase)
 	req.Header.Add("CB-ACCESS-TIMESTAMP", timestamp)
 	req.Header.Add("CB-ACCESS-SIGN", signature)
+	if traceParent := TraceParent(req.Context()); traceParent != "" {
+		req.Header.Add("traceparent", traceParent)
+	}
 }
 
 func (a *APIClient) Post(ctx context.Context, relativePath string, content interface{}, result interface{}) error {
 	return a.Do(ctx, "POST", relativePath, content, result)
 }
 
-func (a *APIClient) Close() error { return nil }
+// Close stops the measurement of the server time.
+func (a *APIClient) Close() error {
+	if a.serverClock != nil {
+		a.serverClock.close()
+	}
+	return nil
+}
 
 func NewDevelopmentClient(client *APIClient, fs afero.Fs) (d *DevelopmentClient, capture error) {
 	file, err := fs.OpenFile("store.json", os.O_CREATE|os.O_RDONLY, 0644)
@@ -655,7 +937,7 @@ func NewDevelopmentClient(client *APIClient, fs afero.Fs) (d *DevelopmentClient,
 	if len(b) == 0 {
 		return &DevelopmentClient{
 			api:   client,
-			store: phizog.NewStore(),
+			store: phizog.NewStore(loggerOr(client.logger)),
 			fs:    fs,
 		}, nil
 	}
@@ -664,19 +946,20 @@ func NewDevelopmentClient(client *APIClient, fs afero.Fs) (d *DevelopmentClient,
 	if err != nil {
 		return nil, err
 	}
-	var store phizog.Store
+	store := phizog.NewStore(loggerOr(client.logger))
 	err = store.Load(raw)
 	if err != nil {
 		return nil, err
 	}
 	return &DevelopmentClient{
 		api:   client,
-		store: &store,
+		store: store,
 		fs:    fs,
 	}, nil
 }
 
 func (d *DevelopmentClient) Close() (capture error) {
+	defer func() { Capture(&capture, d.api.Close()) }()
 	file, err := d.fs.OpenFile("store.json", os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
@@ -686,9 +969,10 @@ func (d *DevelopmentClient) Close() (capture error) {
 }
 
 type DevelopmentClient struct {
-	api   *APIClient
-	store *phizog.Store
-	fs    afero.Fs
+	api    *APIClient
+	store  *phizog.Store
+	fs     afero.Fs
+	tracer Tracer
 }
 
 func (d *DevelopmentClient) Get(ctx context.Context, relativePath string, result interface{}) error {
@@ -699,9 +983,11 @@ func (d *DevelopmentClient) Post(ctx context.Context, relativePath string, conte
 	return d.Do(ctx, "POST", relativePath, content, result)
 }
 func (d *DevelopmentClient) Do(ctx context.Context, method string, relativePath string, content interface{}, result interface{}) (capture error) {
-	logrus.Debugf("DeveloperMode %s %s", method, relativePath)
+	loggerOr(d.api.logger).Debug("development request", "method", method, "path", relativePath)
+	ctx, span := startRequest(ctx, d.tracer, d.api.baseURL, method, relativePath)
 	var rawMessage json.RawMessage
 	resp, err := d.api.do(ctx, method, relativePath, content, &rawMessage)
+	defer func() { span.finish(resp, capture) }()
 	if err != nil {
 		return err
 	}
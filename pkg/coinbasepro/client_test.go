@@ -86,6 +86,20 @@ func TestClient_Accounts(t *testing.T) {
 		_, err := c.GetLedger(context.Background(), "account-id", pagination)
 		require.NoError(t, err)
 	})
+	t.Run("ListLedger", func(t *testing.T) {
+		var api mockAPI
+		defer api.AssertExpectations(t)
+		api.On("Get", "/accounts/account-id/ledger/?limit=100", mock.IsType(&Ledger{})).Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(1).(*Ledger) = Ledger{Entries: []*LedgerEntry{{ID: "2"}}, Page: &Pagination{After: "1"}}
+		})
+		api.On("Get", "/accounts/account-id/ledger/?after=1&limit=100", mock.IsType(&Ledger{})).Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(1).(*Ledger) = Ledger{Entries: []*LedgerEntry{{ID: "1"}}, Page: &Pagination{After: "1"}}
+		})
+		c := Client{api: &api}
+		entries, err := c.ListLedger(context.Background(), "account-id")
+		require.NoError(t, err)
+		assert.Equal(t, []*LedgerEntry{{ID: "2"}, {ID: "1"}}, entries)
+	})
 	t.Run("GetHolds", func(t *testing.T) {
 		var api mockAPI
 		defer api.AssertExpectations(t)
@@ -193,6 +207,22 @@ func TestClient_Fills(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestClient_ListFills(t *testing.T) {
+	var api mockAPI
+	defer api.AssertExpectations(t)
+	api.On("Get", "/fills/?product_id=BTC-USD&limit=100", mock.IsType(&Fills{})).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*Fills) = Fills{Fills: []*Fill{{TradeID: 2}}, Page: &Pagination{After: "1", Before: "2"}}
+	})
+	api.On("Get", "/fills/?product_id=BTC-USD&after=1&limit=100", mock.IsType(&Fills{})).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*Fills) = Fills{Fills: []*Fill{{TradeID: 1}}, Page: &Pagination{After: "0", Before: "1"}}
+	})
+	api.On("Get", "/fills/?product_id=BTC-USD&after=0&limit=100", mock.IsType(&Fills{})).Return(nil)
+	c := Client{api: &api}
+	fills, err := c.ListFills(context.Background(), FillFilter{ProductID: "BTC-USD"})
+	require.NoError(t, err)
+	assert.Equal(t, []*Fill{{TradeID: 2}, {TradeID: 1}}, fills)
+}
+
 func TestClient_Limits(t *testing.T) {
 	var api mockAPI
 	defer api.AssertExpectations(t)
@@ -279,6 +309,38 @@ func TestClient_Deposits(t *testing.T) {
 	})
 }
 
+func TestClient_ListTransfers(t *testing.T) {
+	older := Time(time.Unix(1, 0))
+	newer := Time(time.Unix(2, 0))
+	t.Run("ListDeposits", func(t *testing.T) {
+		var api mockAPI
+		defer api.AssertExpectations(t)
+		api.On("Get", "/transfers/?type=deposit&limit=100", mock.IsType(&Deposits{})).Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(1).(*Deposits) = Deposits{Deposits: []*Deposit{{ID: "older", Type: DepositTypeDeposit, CreatedAt: older}}}
+		})
+		api.On("Get", "/transfers/?type=internal_deposit&limit=100", mock.IsType(&Deposits{})).Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(1).(*Deposits) = Deposits{Deposits: []*Deposit{{ID: "newer", Type: DepositTypeInternal, CreatedAt: newer}}}
+		})
+		c := Client{api: &api}
+		deposits, err := c.ListDeposits(context.Background(), DepositFilter{})
+		require.NoError(t, err)
+		require.Len(t, deposits, 2)
+		assert.Equal(t, "newer", deposits[0].ID)
+		assert.Equal(t, "older", deposits[1].ID)
+	})
+	t.Run("ListWithdrawals", func(t *testing.T) {
+		var api mockAPI
+		defer api.AssertExpectations(t)
+		api.On("Get", "/transfers/?profile_id=profile_id&type=withdraw&limit=100", mock.IsType(&Withdrawals{})).Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(1).(*Withdrawals) = Withdrawals{Withdrawals: []*Withdrawal{{ID: "newer", Type: WithdrawalTypeWithdraw, CreatedAt: newer}}}
+		})
+		c := Client{api: &api}
+		withdrawals, err := c.ListWithdrawals(context.Background(), WithdrawalFilter{ProfileID: "profile_id", Type: WithdrawalTypeWithdraw})
+		require.NoError(t, err)
+		require.Len(t, withdrawals, 1)
+	})
+}
+
 func TestClient_Withdrawals(t *testing.T) {
 	t.Run("Get", func(t *testing.T) {
 		t.Run("GetWithdrawals", func(t *testing.T) {
@@ -459,6 +521,52 @@ func TestClient_Report(t *testing.T) {
 		_, err := c.GetReport(context.Background(), "report_id")
 		require.NoError(t, err)
 	})
+	backoff := ReportBackoff{Initial: time.Millisecond, Max: 2 * time.Millisecond, Multiplier: 2}
+	t.Run("WaitForReport", func(t *testing.T) {
+		var api mockAPI
+		defer api.AssertExpectations(t)
+		api.On("Get", "/reports/report_id", mock.IsType(&Report{})).Return(nil).Twice().Run(func(args mock.Arguments) {
+			args.Get(1).(*Report).Status = ReportStatusPending
+		})
+		api.On("Get", "/reports/report_id", mock.IsType(&Report{})).Return(nil).Once().Run(func(args mock.Arguments) {
+			args.Get(1).(*Report).Status = ReportStatusReady
+		})
+		c := Client{api: &api}
+		report, err := c.WaitForReport(context.Background(), "report_id", backoff)
+		require.NoError(t, err)
+		assert.Equal(t, ReportStatusReady, report.Status)
+	})
+	t.Run("WaitForReportExpired", func(t *testing.T) {
+		var api mockAPI
+		defer api.AssertExpectations(t)
+		api.On("Get", "/reports/report_id", mock.IsType(&Report{})).Return(nil).Once().Run(func(args mock.Arguments) {
+			report := args.Get(1).(*Report)
+			report.Status = ReportStatusPending
+			report.ExpiresAt = Time(time.Date(2021, 4, 9, 12, 0, 0, 0, time.UTC))
+		})
+		c := Client{api: &api, clock: func() time.Time { return time.Date(2021, 4, 9, 12, 1, 0, 0, time.UTC) }}
+		_, err := c.WaitForReport(context.Background(), "report_id", backoff)
+		assert.True(t, errors.Is(err, ErrReportExpired), "expiry is checked against the clock of the client")
+	})
+	t.Run("DownloadReport", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("a,b\n"))
+		}))
+		defer ts.Close()
+		fs := afero.NewMemMapFs()
+		c := Client{}
+		report := Report{ID: "report_id", Status: ReportStatusPending, FileURL: ts.URL}
+		assert.Error(t, c.DownloadReport(context.Background(), report, fs, "report.csv"))
+		report.Status = ReportStatusReady
+		require.NoError(t, c.DownloadReport(context.Background(), report, fs, "report.csv"))
+		b, err := afero.ReadFile(fs, "report.csv")
+		require.NoError(t, err)
+		assert.Equal(t, "a,b\n", string(b))
+		transport := &countingTransport{}
+		c = Client{httpClient: &http.Client{Transport: transport}}
+		require.NoError(t, c.DownloadReport(context.Background(), report, fs, "report.csv"))
+		assert.Equal(t, 1, transport.trips, "the download is sent with the http.Client of the options")
+	})
 }
 
 func TestClient_Profile(t *testing.T) {
@@ -628,7 +736,7 @@ func TestWebsocket_watch(t *testing.T) {
 		cancel()
 	}()
 
-	err := c.watch(ctx, &r, f)
+	err := c.watch(ctx, &r, f, nil)
 	assert.True(t, errors.Is(err, context.Canceled))
 }
 
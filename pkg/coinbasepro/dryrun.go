@@ -0,0 +1,88 @@
+package coinbasepro
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+)
+
+// ErrDryRun is returned by a DryRunClient in place of the result of a request that was not sent.
+var ErrDryRun = errors.New("dry run: request was not sent")
+
+// redacted replaces the values of headers that authenticate a SignedRequest.
+const redacted = "REDACTED"
+
+// SignedRequest is a request exactly as it would be sent to the api.
+type SignedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Redacted returns a copy of the SignedRequest without the key, passphrase and signature that would authenticate it.
+func (s SignedRequest) Redacted() SignedRequest {
+	header := s.Header.Clone()
+	for _, key := range []string{"CB-ACCESS-KEY", "CB-ACCESS-PASSPHRASE", "CB-ACCESS-SIGN"} {
+		if header.Get(key) != "" {
+			header.Set(key, redacted)
+		}
+	}
+	s.Header = header
+	return s
+}
+
+// DryRunClient sends GET requests with its api, but never sends a request that could change state. Such a request
+// is signed, redacted and passed to the sink, and the call returns ErrDryRun.
+type DryRunClient struct {
+	api    apier
+	signer *APIClient
+	sink   func(SignedRequest) error
+}
+
+// NewDryRunClient creates a DryRunClient that reads with api and signs with signer.
+func NewDryRunClient(api apier, signer *APIClient, sink func(SignedRequest) error) *DryRunClient {
+	return &DryRunClient{
+		api:    api,
+		signer: signer,
+		sink:   sink,
+	}
+}
+
+func (d *DryRunClient) Get(ctx context.Context, relativePath string, result interface{}) error {
+	return d.Do(ctx, "GET", relativePath, nil, result)
+}
+
+func (d *DryRunClient) Post(ctx context.Context, relativePath string, content interface{}, result interface{}) error {
+	return d.Do(ctx, "POST", relativePath, content, result)
+}
+
+func (d *DryRunClient) Do(ctx context.Context, method string, relativePath string, content interface{}, result interface{}) (capture error) {
+	if method == http.MethodGet {
+		return d.api.Do(ctx, method, relativePath, content, result)
+	}
+	req, err := d.signer.request(ctx, method, relativePath, content)
+	if err != nil {
+		return err
+	}
+	defer func() { Capture(&capture, req.Body.Close()) }()
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	signed := SignedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header,
+		Body:   string(body),
+	}
+	if err = d.sink(signed.Redacted()); err != nil {
+		return err
+	}
+	return ErrDryRun
+}
+
+func (d *DryRunClient) Close() error {
+	return d.api.Close()
+}
@@ -0,0 +1,59 @@
+package coinbasepro
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunClient(t *testing.T) {
+	baseURL, err := url.Parse("https://api.example.com")
+	require.NoError(t, err)
+	signer := &APIClient{
+		auth: &Auth{
+			Key:        "k",
+			Passphrase: "p",
+			Secret:     "zZ==",
+		},
+		baseURL: baseURL,
+		timestamp: func() string {
+			return "1"
+		},
+	}
+	api := &mockAPI{}
+	var requests []SignedRequest
+	client := Client{api: NewDryRunClient(api, signer, func(signed SignedRequest) error {
+		requests = append(requests, signed)
+		return nil
+	})}
+	ctx := context.Background()
+
+	api.On("Do", "GET", "/accounts/", nil, mock.IsType(&[]Account{})).Return(nil)
+	_, err = client.ListAccounts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, requests)
+
+	_, err = client.CreateLimitOrder(ctx, LimitOrder{
+		ProductID: "BTC-USD",
+		Side:      SideBuy,
+		Type:      OrderTypeLimit,
+		Price:     decimal.NewFromInt(100),
+		Size:      decimal.NewFromInt(1),
+	})
+	assert.ErrorIs(t, err, ErrDryRun)
+	require.Len(t, requests, 1)
+	signed := requests[0]
+	assert.Equal(t, "POST", signed.Method)
+	assert.Equal(t, "https://api.example.com/orders/", signed.URL)
+	assert.Contains(t, signed.Body, `"product_id":"BTC-USD"`)
+	assert.Equal(t, "1", signed.Header.Get("CB-ACCESS-TIMESTAMP"))
+	for _, key := range []string{"CB-ACCESS-KEY", "CB-ACCESS-PASSPHRASE", "CB-ACCESS-SIGN"} {
+		assert.Equal(t, "REDACTED", signed.Header.Get(key))
+	}
+	api.AssertExpectations(t)
+}
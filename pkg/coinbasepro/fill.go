@@ -24,6 +24,8 @@ type Fill struct {
 	Price decimal.Decimal `json:"price"`
 	// ProductID identifies the Product associated with the Order
 	ProductID ProductID `json:"product_id"`
+	// ProfileID identifies the Profile of the Order associated with the Fill
+	ProfileID string `json:"profile_id,omitempty"`
 	// Settled indicates if the Fill has been settled and the counterparties credited
 	Settled bool `json:"settled"`
 	// Side of Order, `buy` or `sell`
@@ -48,6 +50,8 @@ type FillFilter struct {
 	OrderID string `json:"order-id"`
 	// ProductID limits the list of Fills to those with the specified ProductID
 	ProductID ProductID `json:"product-id"`
+	// ProfileID limits the list of Fills to the ProfileID. By default, Fills are listed for the Profile of the key.
+	ProfileID string `json:"profile-id"`
 }
 
 func (f FillFilter) Params() []string {
@@ -58,6 +62,9 @@ func (f FillFilter) Params() []string {
 	if f.ProductID != "" {
 		params = append(params, fmt.Sprintf("product_id=%s", f.ProductID))
 	}
+	if f.ProfileID != "" {
+		params = append(params, fmt.Sprintf("profile_id=%s", f.ProfileID))
+	}
 	return params
 }
 
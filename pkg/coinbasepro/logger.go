@@ -0,0 +1,66 @@
+package coinbasepro
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger receives the log of a Client as a message followed by alternating keys and values, such as
+// `Debug("request", "method", "GET", "path", "/accounts/")`. It is the method set of a log/slog Logger.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// NewLogrusLogger creates a Logger that logs to a logrus logger, with the keys and values as fields.
+func NewLogrusLogger(logger logrus.FieldLogger) Logger {
+	return logrusLogger{logger: logger}
+}
+
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+func (l logrusLogger) Debug(msg string, args ...interface{}) {
+	l.logger.WithFields(fields(args)).Debug(msg)
+}
+
+func (l logrusLogger) Info(msg string, args ...interface{}) {
+	l.logger.WithFields(fields(args)).Info(msg)
+}
+
+func (l logrusLogger) Warn(msg string, args ...interface{}) {
+	l.logger.WithFields(fields(args)).Warn(msg)
+}
+
+func (l logrusLogger) Error(msg string, args ...interface{}) {
+	l.logger.WithFields(fields(args)).Error(msg)
+}
+
+// fields pairs alternating keys and values. A key without a value is kept with a nil value.
+func fields(args []interface{}) logrus.Fields {
+	f := make(logrus.Fields, (len(args)+1)/2)
+	for i := 0; i < len(args); i += 2 {
+		key := fmt.Sprint(args[i])
+		if i+1 < len(args) {
+			f[key] = args[i+1]
+		} else {
+			f[key] = nil
+		}
+	}
+	return f
+}
+
+// defaultLogger logs to the standard logrus logger.
+var defaultLogger = NewLogrusLogger(logrus.StandardLogger())
+
+// loggerOr returns the logger, or the default Logger when there is none.
+func loggerOr(logger Logger) Logger {
+	if logger == nil {
+		return defaultLogger
+	}
+	return logger
+}
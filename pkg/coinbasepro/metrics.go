@@ -0,0 +1,138 @@
+package coinbasepro
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Metrics observes the api requests and feed messages of a Client. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest observes a request to an endpoint, such as `/accounts/:id/ledger/`, that ended with the http
+	// status, or with a status of zero when no response was received.
+	ObserveRequest(method string, endpoint string, status int, duration time.Duration)
+	// ObserveRateLimitWait observes the time a request waited for its RateLimiter.
+	ObserveRateLimitWait(duration time.Duration)
+	// ObserveMessage observes a message of the feed by its type.
+	ObserveMessage(messageType string)
+	// ObserveDroppedMessage observes a message of the feed that was not delivered because its reader was not ready.
+	ObserveDroppedMessage()
+	// ObserveReconnect observes a new connection to the feed after a connection failed.
+	ObserveReconnect()
+	// ObserveSequenceGap observes messages of the full channel for a product that were missed, by the gap between
+	// the sequence numbers of consecutive messages.
+	ObserveSequenceGap(productID ProductID, missed int64)
+	// ObserveClockSkew observes the difference between the server time and the local clock, which is positive when
+	// the local clock is behind.
+	ObserveClockSkew(skew time.Duration)
+}
+
+// MetricsMode observes the requests and feed messages of the client with metrics.
+func MetricsMode(client *Client, metrics Metrics) {
+	client.metrics = metrics
+	if api, ok := client.api.(instrumentable); ok {
+		api.instrument(metrics)
+	}
+}
+
+// instrumentable is an api that passes Metrics and a Tracer to the api that it wraps.
+type instrumentable interface {
+	instrument(metrics Metrics)
+	trace(tracer Tracer)
+}
+
+func (a *APIClient) instrument(metrics Metrics) {
+	a.metrics = metrics
+	if a.serverClock != nil {
+		a.serverClock.instrument(metrics)
+	}
+}
+
+func (d *DevelopmentClient) instrument(metrics Metrics) {
+	d.api.instrument(metrics)
+}
+
+func (d *DryRunClient) instrument(metrics Metrics) {
+	if api, ok := d.api.(instrumentable); ok {
+		api.instrument(metrics)
+	}
+}
+
+// observe returns the metrics, or Metrics that observe nothing when there are none.
+func observe(metrics Metrics) Metrics {
+	if metrics == nil {
+		return noMetrics{}
+	}
+	return metrics
+}
+
+type noMetrics struct{}
+
+func (noMetrics) ObserveRequest(string, string, int, time.Duration) {}
+func (noMetrics) ObserveRateLimitWait(time.Duration)                {}
+func (noMetrics) ObserveMessage(string)                             {}
+func (noMetrics) ObserveDroppedMessage()                            {}
+func (noMetrics) ObserveReconnect()                                 {}
+func (noMetrics) ObserveSequenceGap(ProductID, int64)               {}
+func (noMetrics) ObserveClockSkew(time.Duration)                    {}
+
+// endpoint is the relative path of a request without its query, and with each segment that identifies a resource,
+// such as an id, product or currency, replaced by `:id`, so that requests for different resources share an endpoint.
+// The static segments of the api are lowercase words.
+func endpoint(relativePath string) string {
+	if i := strings.IndexByte(relativePath, '?'); i >= 0 {
+		relativePath = relativePath[:i]
+	}
+	segments := strings.Split(relativePath, "/")
+	for i, segment := range segments {
+		if strings.IndexFunc(segment, func(r rune) bool { return unicode.IsDigit(r) || unicode.IsUpper(r) || r == ':' }) >= 0 {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// fullMessageTypes are the types of the messages of the full channel, which are numbered by a sequence per product.
+var fullMessageTypes = channelMessageTypes[ChannelNameFull]
+
+// sequences holds the last sequence number of the full channel messages of each product.
+type sequences map[ProductID]int64
+
+// gap returns the number of messages of the product missed before the message, if it is a full channel message.
+func (s sequences) gap(message interface{}) (ProductID, int64) {
+	if s == nil {
+		return "", 0
+	}
+	fields, _ := message.(map[string]interface{})
+	messageType, _ := fields["type"].(string)
+	productID, _ := fields["product_id"].(string)
+	sequence, ok := fields["sequence"].(float64)
+	if !ok || productID == "" || !containsString(fullMessageTypes, messageType) {
+		return "", 0
+	}
+	last, seen := s[ProductID(productID)]
+	if !seen || int64(sequence) > last {
+		s[ProductID(productID)] = int64(sequence)
+	}
+	if !seen || int64(sequence) <= last+1 {
+		return ProductID(productID), 0
+	}
+	return ProductID(productID), int64(sequence) - last - 1
+}
+
+// subscribes indicates whether the request subscribes to the channel.
+func subscribes(request SubscriptionRequest, name ChannelName) bool {
+	for _, channel := range request.Channels {
+		switch c := channel.(type) {
+		case ChannelName:
+			if c == name {
+				return true
+			}
+		case Channel:
+			if c.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
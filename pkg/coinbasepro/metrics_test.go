@@ -0,0 +1,135 @@
+package coinbasepro
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"/accounts/": "/accounts/",
+		"/accounts/7d0f7d8e-dd34-4d9c-a846-06f431c381ba": "/accounts/:id",
+		"/accounts/7d0f7d8e-dd34/ledger/?limit=100":      "/accounts/:id/ledger/",
+		"/orders/client:abc":                             "/orders/:id",
+		"/products/BTC-USD/ticker":                       "/products/:id/ticker",
+		"/currencies/BTC":                                "/currencies/:id",
+		"/users/self/exchange-limits/":                   "/users/self/exchange-limits/",
+	}
+	for relativePath, want := range tests {
+		assert.Equal(t, want, endpoint(relativePath), relativePath)
+	}
+}
+
+func TestSequences_gap(t *testing.T) {
+	s := make(sequences)
+	full := func(productID string, sequence float64) interface{} {
+		return map[string]interface{}{"type": "match", "product_id": productID, "sequence": sequence}
+	}
+	_, missed := s.gap(full("BTC-USD", 10))
+	assert.Equal(t, int64(0), missed, "the first message starts the sequence")
+	_, missed = s.gap(full("BTC-USD", 11))
+	assert.Equal(t, int64(0), missed)
+	productID, missed := s.gap(full("BTC-USD", 15))
+	assert.Equal(t, ProductID("BTC-USD"), productID)
+	assert.Equal(t, int64(3), missed)
+	_, missed = s.gap(full("BTC-USD", 12))
+	assert.Equal(t, int64(0), missed, "a late message is not a gap")
+	_, missed = s.gap(full("ETH-USD", 100))
+	assert.Equal(t, int64(0), missed, "each product has its own sequence")
+	_, missed = s.gap(map[string]interface{}{"type": "ticker", "product_id": "BTC-USD", "sequence": float64(30)})
+	assert.Equal(t, int64(0), missed, "only full channel messages are sequenced")
+	_, missed = sequences(nil).gap(full("BTC-USD", 100))
+	assert.Equal(t, int64(0), missed)
+}
+
+func TestSubscribes(t *testing.T) {
+	request := NewSubscriptionRequest(nil, []ChannelName{ChannelNameTicker}, []Channel{{Name: ChannelNameFull}})
+	assert.True(t, subscribes(request, ChannelNameTicker))
+	assert.True(t, subscribes(request, ChannelNameFull))
+	assert.False(t, subscribes(request, ChannelNameUser))
+}
+
+func TestMetricsMode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"NotFound"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"iso":"2021-01-01T00:00:00Z","epoch":1609459200}`))
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client, err := NewClient(baseURL, baseURL, &Auth{Key: "k", Passphrase: "p", Secret: "zZ=="}, WithRateLimiter(NewTokenBucket(100, 10)))
+	require.NoError(t, err)
+	metrics := &recordingMetrics{}
+	MetricsMode(client, metrics)
+
+	_, err = client.GetServerTime(context.Background())
+	require.NoError(t, err)
+	err = client.api.Get(context.Background(), "/missing", nil)
+	require.Error(t, err)
+	assert.Equal(t, []string{"GET /time 200", "GET /missing 404"}, metrics.requests)
+	assert.Equal(t, 2, metrics.waits, "waits for the rate limiter of the options are observed")
+}
+
+func TestWebsocket_watchMetrics(t *testing.T) {
+	var r mockJSONReader
+	r.On("ReadJSON", mock.Anything).Return([]byte(`{"type":"match","product_id":"BTC-USD","sequence":1}`), nil).Once()
+	r.On("ReadJSON", mock.Anything).Return([]byte(`{"type":"match","product_id":"BTC-USD","sequence":3}`), nil).Once()
+	r.On("ReadJSON", mock.Anything).Return([]byte(`{}`), errors.New("closed")).Once()
+	metrics := &recordingMetrics{}
+	c := Client{metrics: metrics}
+	feed := NewFeed()
+
+	err := c.watch(context.Background(), &r, feed, make(sequences))
+	assert.EqualError(t, err, "closed")
+	assert.Equal(t, []string{"match", "match"}, metrics.messages)
+	assert.Equal(t, int64(1), metrics.missed)
+	assert.GreaterOrEqual(t, metrics.dropped, 1, "no one reads the feed")
+}
+
+type recordingMetrics struct {
+	noMetrics
+	requests []string
+	waits    int
+	messages []string
+	dropped  int
+	missed   int64
+	skews    []time.Duration
+}
+
+func (m *recordingMetrics) ObserveRequest(method string, endpoint string, status int, _ time.Duration) {
+	m.requests = append(m.requests, method+" "+endpoint+" "+strconv.Itoa(status))
+}
+
+func (m *recordingMetrics) ObserveRateLimitWait(time.Duration) {
+	m.waits++
+}
+
+func (m *recordingMetrics) ObserveMessage(messageType string) {
+	m.messages = append(m.messages, messageType)
+}
+
+func (m *recordingMetrics) ObserveDroppedMessage() {
+	m.dropped++
+}
+
+func (m *recordingMetrics) ObserveSequenceGap(_ ProductID, missed int64) {
+	m.missed += missed
+}
+
+func (m *recordingMetrics) ObserveClockSkew(skew time.Duration) {
+	m.skews = append(m.skews, skew)
+}
@@ -0,0 +1,129 @@
+package coinbasepro
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// defaultUserAgent is the User-Agent of requests to the api unless WithUserAgent sets another.
+const defaultUserAgent = "Golang Reticule v0.1"
+
+// Option configures a Client or APIClient. Each Option works with both NewClient and NewAPIClient, and WithLogger
+// also with NewBroker and NewExportingTracer.
+type Option func(*options)
+
+type options struct {
+	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    time.Duration
+	proxy      *url.URL
+	userAgent  string
+	logger     Logger
+	limiter    RateLimiter
+	clock      func() time.Time
+	serverTime time.Duration
+}
+
+// WithHTTPClient sends requests with a copy of the http.Client instead of http.DefaultClient. WithTransport,
+// WithTimeout and WithProxy change the copy rather than the http.Client.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithTransport sends requests with the http.RoundTripper.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithTimeout limits the time of each request to the api, including reading its response, and of the handshake of
+// the websocket feed.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithProxy sends requests, and connects to the websocket feed, through the proxy. The transport of the requests must
+// be an *http.Transport.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.proxy = proxy
+	}
+}
+
+// WithUserAgent sets the User-Agent header of requests to the api.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithLogger logs to the Logger instead of the standard logrus logger.
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithRateLimiter waits for the RateLimiter before each request to the api.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(o *options) {
+		o.limiter = limiter
+	}
+}
+
+// WithClock reads the time that signs requests from the clock instead of time.Now.
+func WithClock(clock func() time.Time) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		userAgent: defaultUserAgent,
+		clock:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// client builds the http.Client of the options.
+func (o options) client() (*http.Client, error) {
+	if o.httpClient == nil && o.transport == nil && o.timeout == 0 && o.proxy == nil {
+		return http.DefaultClient, nil
+	}
+	client := &http.Client{}
+	if o.httpClient != nil {
+		copied := *o.httpClient
+		client = &copied
+	}
+	if o.transport != nil {
+		client.Transport = o.transport
+	}
+	if o.timeout != 0 {
+		client.Timeout = o.timeout
+	}
+	if o.proxy != nil {
+		transport := client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		t, ok := transport.(*http.Transport)
+		if !ok {
+			return nil, errors.New("a proxy requires an *http.Transport")
+		}
+		t = t.Clone()
+		t.Proxy = http.ProxyURL(o.proxy)
+		client.Transport = t
+	}
+	return client, nil
+}
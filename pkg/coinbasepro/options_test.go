@@ -0,0 +1,114 @@
+package coinbasepro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIClient_Options(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		_, _ = w.Write([]byte(`{"iso":"2021-01-01T00:00:00Z","epoch":1609459200}`))
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	auth := &Auth{Key: "k", Passphrase: "p", Secret: "zZ=="}
+
+	t.Run("Defaults", func(t *testing.T) {
+		api, err := NewAPIClient(baseURL, baseURL, auth)
+		require.NoError(t, err)
+		assert.Same(t, http.DefaultClient, api.httpClient)
+		_, err = (&Client{api: api}).GetServerTime(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, defaultUserAgent, headers.Get("User-Agent"))
+	})
+	t.Run("Options", func(t *testing.T) {
+		limiter := &countingLimiter{}
+		logger := &recordingLogger{}
+		transport := &countingTransport{}
+		client, err := NewClient(baseURL, baseURL, auth,
+			WithTransport(transport),
+			WithTimeout(time.Minute),
+			WithUserAgent("service/1.0"),
+			WithLogger(logger),
+			WithRateLimiter(limiter),
+			WithClock(func() time.Time { return time.Unix(1609459200, 0) }),
+		)
+		require.NoError(t, err)
+		_, err = client.GetServerTime(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "service/1.0", headers.Get("User-Agent"))
+		assert.Equal(t, "1609459200", headers.Get("CB-ACCESS-TIMESTAMP"))
+		assert.Equal(t, 1, transport.trips)
+		assert.Equal(t, 1, limiter.waits)
+		require.Len(t, logger.lines, 1)
+		assert.Regexp(t, `^DEBUG request method=GET path=/time status=200 duration=\S+$`, logger.lines[0])
+		assert.Equal(t, time.Minute, client.dialer.(websocketFeedDialer).HandshakeTimeout)
+		assert.Same(t, transport, client.httpClient.Transport, "reports are downloaded with the transport")
+		assert.Equal(t, int64(1609459200), client.now().Unix())
+
+		limiter.err = context.Canceled
+		_, err = client.GetServerTime(context.Background())
+		assert.True(t, errors.Is(err, context.Canceled), "a request is not sent when the limiter fails")
+		assert.Equal(t, 1, transport.trips)
+	})
+	t.Run("HTTPClient", func(t *testing.T) {
+		httpClient := &http.Client{}
+		api, err := NewAPIClient(baseURL, baseURL, auth, WithHTTPClient(httpClient), WithTimeout(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, time.Second, api.httpClient.Timeout)
+		assert.Zero(t, httpClient.Timeout, "the http.Client of the option is not changed")
+	})
+	t.Run("Proxy", func(t *testing.T) {
+		proxy, err := url.Parse("http://proxy.example:3128")
+		require.NoError(t, err)
+		client, err := NewClient(baseURL, baseURL, auth, WithProxy(proxy))
+		require.NoError(t, err)
+		transport, ok := client.api.(*APIClient).httpClient.Transport.(*http.Transport)
+		require.True(t, ok)
+		proxied, err := transport.Proxy(httptest.NewRequest("GET", server.URL, nil))
+		require.NoError(t, err)
+		assert.Equal(t, proxy, proxied)
+		assert.Equal(t, proxy, client.dialer.(websocketFeedDialer).Proxy)
+
+		_, err = NewAPIClient(baseURL, baseURL, auth, WithTransport(&countingTransport{}), WithProxy(proxy))
+		assert.Error(t, err, "a proxy requires an *http.Transport")
+	})
+}
+
+type countingTransport struct {
+	trips int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.trips++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (r *recordingLogger) log(level string, msg string, args []interface{}) {
+	line := level + " " + msg
+	for i := 0; i+1 < len(args); i += 2 {
+		line += fmt.Sprintf(" %v=%v", args[i], args[i+1])
+	}
+	r.lines = append(r.lines, line)
+}
+
+func (r *recordingLogger) Debug(msg string, args ...interface{}) { r.log("DEBUG", msg, args) }
+func (r *recordingLogger) Info(msg string, args ...interface{})  { r.log("INFO", msg, args) }
+func (r *recordingLogger) Warn(msg string, args ...interface{})  { r.log("WARN", msg, args) }
+func (r *recordingLogger) Error(msg string, args ...interface{}) { r.log("ERROR", msg, args) }
@@ -52,8 +52,12 @@ type Order struct {
 	// PostOnly indicates whether only maker orders can be placed. No orders will be matched when post_only mode is active.
 	// When PostOnly is true, if any part of the order results in taking liquidity the order will be rejected and no part of it will execute.
 	PostOnly bool `json:"post_only"`
+	// Price per unit of a limit Order
+	Price *decimal.Decimal `json:"price,omitempty"`
 	// ProductID identifies the Product associated with the Order
 	ProductID ProductID `json:"product_id"`
+	// ProfileID identifies the Profile that placed the Order
+	ProfileID string `json:"profile_id,omitempty"`
 	// Settled indicates settlement status
 	Settled bool `json:"settled"`
 	// Side of order, `buy` or `sell`
@@ -77,6 +81,8 @@ type OrderFilter struct {
 	ProductID ProductID `json:"product-id"`
 	// Status limits list of Orders to the provided OrderStatuses. The default, OrderStatusParamAll, returns orders of all statuses
 	Status []OrderStatusParam `json:"status"`
+	// ProfileID limits the list of Orders to the ProfileID. By default, Orders are listed for the Profile of the key.
+	ProfileID string `json:"profile-id"`
 }
 
 func (o OrderFilter) Validate() error {
@@ -90,13 +96,16 @@ func (o OrderFilter) Validate() error {
 }
 
 func (o OrderFilter) Params() []string {
-	if len(o.Status) == 0 && o.ProductID == "" {
+	if len(o.Status) == 0 && o.ProductID == "" && o.ProfileID == "" {
 		return nil
 	}
-	params := make([]string, 0, len(o.Status)+1)
+	params := make([]string, 0, len(o.Status)+2)
 	if o.ProductID != "" {
 		params = append(params, fmt.Sprintf("product_id=%s", o.ProductID))
 	}
+	if o.ProfileID != "" {
+		params = append(params, fmt.Sprintf("profile_id=%s", o.ProfileID))
+	}
 	for _, s := range o.Status {
 		params = append(params, fmt.Sprintf("status=%s", s))
 	}
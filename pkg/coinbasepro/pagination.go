@@ -72,3 +72,13 @@ type Pagination struct {
 func (p *Pagination) NotEmpty() bool {
 	return !(p.After == "" && p.Before == "")
 }
+
+// nextPage advances the PaginationParams to the page after the current Page, towards older results. nextPage returns
+// false when there are no more pages: the current page was empty, had no After token, or repeated the previous token.
+func nextPage(page *Pagination, count int, pagination *PaginationParams) bool {
+	if count == 0 || page == nil || page.After == "" || page.After == pagination.After {
+		return false
+	}
+	pagination.After = page.After
+	return true
+}
@@ -0,0 +1,186 @@
+package coinbasepro
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// Portfolio values the Accounts of the current Profile in a single Quote Currency.
+type Portfolio struct {
+	// Quote is the Currency in which all values are expressed
+	Quote CurrencyName `json:"quote"`
+	// Total is the value of all PortfolioAssets
+	Total decimal.Decimal `json:"total"`
+	// Available is the value of funds available for withdrawal or trade
+	Available decimal.Decimal `json:"available"`
+	// Hold is the value of funds on Hold
+	Hold decimal.Decimal `json:"hold"`
+	// Assets are the priced Accounts with a non-zero Balance, largest value first
+	Assets []*PortfolioAsset `json:"assets"`
+	// Unpriced lists Currencies with a Balance but no ProductRoute to the Quote Currency
+	Unpriced []CurrencyName `json:"unpriced,omitempty"`
+}
+
+// PortfolioAsset is the value of a single Account in the Quote Currency of the Portfolio.
+type PortfolioAsset struct {
+	Currency  CurrencyName    `json:"currency"`
+	Balance   decimal.Decimal `json:"balance"`
+	Available decimal.Decimal `json:"available"`
+	Hold      decimal.Decimal `json:"hold"`
+	// Price of one unit of Currency in the Quote Currency
+	Price decimal.Decimal `json:"price"`
+	// Value is the Balance in the Quote Currency
+	Value decimal.Decimal `json:"value"`
+	// AvailableValue is the Available funds in the Quote Currency
+	AvailableValue decimal.Decimal `json:"available_value"`
+	// HoldValue is the Hold funds in the Quote Currency
+	HoldValue decimal.Decimal `json:"hold_value"`
+	// Allocation is the percentage of the Portfolio Total made up by this asset
+	Allocation decimal.Decimal `json:"allocation"`
+	// Route lists the Products used to price the Currency, in order of conversion
+	Route ProductRoute `json:"route"`
+}
+
+// ProductRoute is a sequence of conversions through Products, such as XLM-BTC then BTC-USD to price XLM in USD.
+type ProductRoute []ProductHop
+
+// ProductHop is a single conversion through a Product. A conversion from the BaseCurrency to the QuoteCurrency
+// multiplies by the Product price; an Inverse conversion, from the QuoteCurrency to the BaseCurrency, divides by it.
+type ProductHop struct {
+	ProductID ProductID `json:"product_id"`
+	Inverse   bool      `json:"inverse"`
+}
+
+// FindProductRoute finds the shortest ProductRoute that converts the from Currency to the to Currency using the
+// tradeable Products. An empty ProductRoute is returned when from and to are the same Currency, and false is returned
+// when no ProductRoute exists. Direct Products are preferred over Inverse Products when routes have equal length.
+func FindProductRoute(products []Product, from CurrencyName, to CurrencyName) (ProductRoute, bool) {
+	if from == to {
+		return ProductRoute{}, true
+	}
+	type edge struct {
+		hop  ProductHop
+		next CurrencyName
+	}
+	sorted := make([]Product, len(products))
+	copy(sorted, products)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	edges := make(map[CurrencyName][]edge)
+	for _, product := range sorted {
+		if product.TradingDisabled {
+			continue
+		}
+		productID := ProductID(product.ID)
+		edges[product.BaseCurrency] = append(edges[product.BaseCurrency], edge{ProductHop{ProductID: productID}, product.QuoteCurrency})
+	}
+	for _, product := range sorted {
+		if product.TradingDisabled {
+			continue
+		}
+		productID := ProductID(product.ID)
+		edges[product.QuoteCurrency] = append(edges[product.QuoteCurrency], edge{ProductHop{ProductID: productID, Inverse: true}, product.BaseCurrency})
+	}
+	routes := map[CurrencyName]ProductRoute{from: {}}
+	queue := []CurrencyName{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, e := range edges[current] {
+			if _, seen := routes[e.next]; seen {
+				continue
+			}
+			route := make(ProductRoute, len(routes[current]), len(routes[current])+1)
+			copy(route, routes[current])
+			routes[e.next] = append(route, e.hop)
+			if e.next == to {
+				return routes[e.next], true
+			}
+			queue = append(queue, e.next)
+		}
+	}
+	return nil, false
+}
+
+// GetPortfolio values the Balance of every Account of the current Profile in the quote Currency. Each Currency is
+// priced with the last trade price from GetProductTicker along the shortest ProductRoute, so a Currency without a
+// direct Product can still be priced through an intermediate Currency, such as XLM through BTC to USD.
+func (c *Client) GetPortfolio(ctx context.Context, quote CurrencyName) (Portfolio, error) {
+	accounts, err := c.ListAccounts(ctx)
+	if err != nil {
+		return Portfolio{}, err
+	}
+	products, err := c.ListProducts(ctx)
+	if err != nil {
+		return Portfolio{}, err
+	}
+	tickers := make(map[ProductID]decimal.Decimal)
+	portfolio := Portfolio{Quote: quote}
+	for _, account := range accounts {
+		if account.Balance.IsZero() {
+			continue
+		}
+		route, ok := FindProductRoute(products, account.Currency, quote)
+		if !ok {
+			portfolio.Unpriced = append(portfolio.Unpriced, account.Currency)
+			continue
+		}
+		price, err := c.routePrice(ctx, route, tickers)
+		if err != nil {
+			return Portfolio{}, err
+		}
+		asset := PortfolioAsset{
+			Currency:       account.Currency,
+			Balance:        account.Balance,
+			Available:      account.Available,
+			Hold:           account.Hold,
+			Price:          price,
+			Value:          account.Balance.Mul(price),
+			AvailableValue: account.Available.Mul(price),
+			HoldValue:      account.Hold.Mul(price),
+			Route:          route,
+		}
+		portfolio.Total = portfolio.Total.Add(asset.Value)
+		portfolio.Available = portfolio.Available.Add(asset.AvailableValue)
+		portfolio.Hold = portfolio.Hold.Add(asset.HoldValue)
+		portfolio.Assets = append(portfolio.Assets, &asset)
+	}
+	if portfolio.Total.IsPositive() {
+		hundred := decimal.NewFromInt(100)
+		for _, asset := range portfolio.Assets {
+			asset.Allocation = asset.Value.Mul(hundred).DivRound(portfolio.Total, 4)
+		}
+	}
+	sort.SliceStable(portfolio.Assets, func(i, j int) bool {
+		return portfolio.Assets[i].Value.GreaterThan(portfolio.Assets[j].Value)
+	})
+	return portfolio, nil
+}
+
+// routePrice multiplies the prices along the ProductRoute, caching tickers so that Products shared between routes
+// are only retrieved once.
+func (c *Client) routePrice(ctx context.Context, route ProductRoute, tickers map[ProductID]decimal.Decimal) (decimal.Decimal, error) {
+	price := decimal.NewFromInt(1)
+	for _, hop := range route {
+		last, ok := tickers[hop.ProductID]
+		if !ok {
+			ticker, err := c.GetProductTicker(ctx, hop.ProductID)
+			if err != nil {
+				return decimal.Decimal{}, err
+			}
+			last = ticker.Price
+			tickers[hop.ProductID] = last
+		}
+		if last.IsZero() {
+			return decimal.Decimal{}, fmt.Errorf("product %q has no last trade price", hop.ProductID)
+		}
+		if hop.Inverse {
+			price = price.Div(last)
+			continue
+		}
+		price = price.Mul(last)
+	}
+	return price, nil
+}
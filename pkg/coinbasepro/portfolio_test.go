@@ -0,0 +1,90 @@
+package coinbasepro
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindProductRoute(t *testing.T) {
+	products := []Product{
+		{ID: "BTC-USD", BaseCurrency: "BTC", QuoteCurrency: "USD"},
+		{ID: "XLM-BTC", BaseCurrency: "XLM", QuoteCurrency: "BTC"},
+		{ID: "USDC-EUR", BaseCurrency: "USDC", QuoteCurrency: "EUR"},
+		{ID: "DOGE-USD", BaseCurrency: "DOGE", QuoteCurrency: "USD", TradingDisabled: true},
+	}
+	t.Run("Same", func(t *testing.T) {
+		route, ok := FindProductRoute(products, "USD", "USD")
+		assert.True(t, ok)
+		assert.Empty(t, route)
+	})
+	t.Run("Direct", func(t *testing.T) {
+		route, ok := FindProductRoute(products, "BTC", "USD")
+		assert.True(t, ok)
+		assert.Equal(t, ProductRoute{{ProductID: "BTC-USD"}}, route)
+	})
+	t.Run("Inverse", func(t *testing.T) {
+		route, ok := FindProductRoute(products, "USD", "BTC")
+		assert.True(t, ok)
+		assert.Equal(t, ProductRoute{{ProductID: "BTC-USD", Inverse: true}}, route)
+	})
+	t.Run("MultiHop", func(t *testing.T) {
+		route, ok := FindProductRoute(products, "XLM", "USD")
+		assert.True(t, ok)
+		assert.Equal(t, ProductRoute{{ProductID: "XLM-BTC"}, {ProductID: "BTC-USD"}}, route)
+	})
+	t.Run("NoRoute", func(t *testing.T) {
+		_, ok := FindProductRoute(products, "EUR", "USD")
+		assert.False(t, ok)
+		_, ok = FindProductRoute(products, "DOGE", "USD")
+		assert.False(t, ok)
+	})
+}
+
+func TestClient_GetPortfolio(t *testing.T) {
+	var api mockAPI
+	defer api.AssertExpectations(t)
+	api.On("Get", "/accounts/", mock.IsType(&[]Account{})).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]Account) = []Account{
+			{Currency: "USD", Balance: decimal.NewFromInt(100), Available: decimal.NewFromInt(60), Hold: decimal.NewFromInt(40)},
+			{Currency: "BTC", Balance: decimal.NewFromInt(2), Available: decimal.NewFromInt(2)},
+			{Currency: "XLM", Balance: decimal.NewFromInt(1000), Available: decimal.NewFromInt(1000)},
+			{Currency: "EUR", Balance: decimal.NewFromInt(5)},
+			{Currency: "ETH"},
+		}
+	})
+	api.On("Get", "/products/", mock.IsType(&[]Product{})).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]Product) = []Product{
+			{ID: "BTC-USD", BaseCurrency: "BTC", QuoteCurrency: "USD"},
+			{ID: "XLM-BTC", BaseCurrency: "XLM", QuoteCurrency: "BTC"},
+		}
+	})
+	api.On("Get", "/products/BTC-USD/ticker", mock.IsType(&ProductTicker{})).Return(nil).Once().Run(func(args mock.Arguments) {
+		args.Get(1).(*ProductTicker).Price = decimal.NewFromInt(200)
+	})
+	api.On("Get", "/products/XLM-BTC/ticker", mock.IsType(&ProductTicker{})).Return(nil).Once().Run(func(args mock.Arguments) {
+		args.Get(1).(*ProductTicker).Price = decimal.NewFromFloat(0.0005)
+	})
+	c := Client{api: &api}
+	portfolio, err := c.GetPortfolio(context.Background(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, CurrencyName("USD"), portfolio.Quote)
+	assert.Equal(t, "600", portfolio.Total.String())
+	assert.Equal(t, "560", portfolio.Available.String())
+	assert.Equal(t, "40", portfolio.Hold.String())
+	assert.Equal(t, []CurrencyName{"EUR"}, portfolio.Unpriced)
+	require.Len(t, portfolio.Assets, 3)
+	btc := portfolio.Assets[0]
+	assert.Equal(t, CurrencyName("BTC"), btc.Currency)
+	assert.Equal(t, "400", btc.Value.String())
+	assert.Equal(t, "66.6667", btc.Allocation.String())
+	xlm := portfolio.Assets[2]
+	assert.Equal(t, CurrencyName("XLM"), xlm.Currency)
+	assert.Equal(t, "0.1", xlm.Price.String())
+	assert.Equal(t, "100", xlm.Value.String())
+	assert.Len(t, xlm.Route, 2)
+}
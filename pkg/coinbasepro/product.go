@@ -46,6 +46,95 @@ type Product struct {
 	TradingDisabled bool `json:"trading_disabled"`
 }
 
+// ValidateLimitOrder checks the LimitOrder against the trading status, increments and size limits of the Product.
+func (p Product) ValidateLimitOrder(order LimitOrder) error {
+	if err := p.validateTrading(); err != nil {
+		return err
+	}
+	if err := validateIncrement("price", order.Price, p.QuoteIncrement); err != nil {
+		return err
+	}
+	return p.validateSize(order.Size)
+}
+
+// ValidateMarketOrder checks the MarketOrder against the trading status, increments and size and funds limits of
+// the Product.
+func (p Product) ValidateMarketOrder(order MarketOrder) error {
+	if err := p.validateTrading(); err != nil {
+		return err
+	}
+	if p.LimitOnly {
+		return fmt.Errorf("product %s only accepts limit orders", p.ID)
+	}
+	if order.Size != nil {
+		if err := p.validateSize(*order.Size); err != nil {
+			return err
+		}
+	}
+	if order.Funds != nil {
+		if err := validateIncrement("funds", *order.Funds, p.QuoteIncrement); err != nil {
+			return err
+		}
+		if order.Funds.LessThan(p.MinMarketFunds) {
+			return fmt.Errorf("funds %s is less than min market funds %s", order.Funds, p.MinMarketFunds)
+		}
+		if p.MaxMarketFunds.IsPositive() && order.Funds.GreaterThan(p.MaxMarketFunds) {
+			return fmt.Errorf("funds %s is more than max market funds %s", order.Funds, p.MaxMarketFunds)
+		}
+	}
+	return nil
+}
+
+func (p Product) validateTrading() error {
+	switch {
+	case p.TradingDisabled:
+		return fmt.Errorf("trading is disabled for product %s", p.ID)
+	case p.CancelOnly:
+		return fmt.Errorf("product %s only accepts cancel requests", p.ID)
+	}
+	return nil
+}
+
+func (p Product) validateSize(size decimal.Decimal) error {
+	if err := validateIncrement("size", size, p.BaseIncrement); err != nil {
+		return err
+	}
+	if size.LessThan(p.BaseMinSize) {
+		return fmt.Errorf("size %s is less than base min size %s", size, p.BaseMinSize)
+	}
+	if p.BaseMaxSize.IsPositive() && size.GreaterThan(p.BaseMaxSize) {
+		return fmt.Errorf("size %s is more than base max size %s", size, p.BaseMaxSize)
+	}
+	return nil
+}
+
+// validateIncrement checks that the value is a whole number of increments; a zero increment is not checked.
+func validateIncrement(name string, value decimal.Decimal, increment decimal.Decimal) error {
+	if increment.IsPositive() && !value.Mod(increment).IsZero() {
+		return fmt.Errorf("%s %s is not a multiple of increment %s", name, value, increment)
+	}
+	return nil
+}
+
+// RoundQuote rounds an amount of the QuoteCurrency, such as a price or funds, down to a whole number of
+// QuoteIncrements.
+func (p Product) RoundQuote(value decimal.Decimal) decimal.Decimal {
+	return roundDown(value, p.QuoteIncrement)
+}
+
+// RoundBase rounds a size of the BaseCurrency down to a whole number of BaseIncrements.
+func (p Product) RoundBase(size decimal.Decimal) decimal.Decimal {
+	return roundDown(size, p.BaseIncrement)
+}
+
+// roundDown rounds the value down to a whole number of increments; a zero increment leaves the value unchanged.
+func roundDown(value decimal.Decimal, increment decimal.Decimal) decimal.Decimal {
+	if !increment.IsPositive() {
+		return value
+	}
+	return value.Div(increment).Floor().Mul(increment)
+}
+
 // ProductID values could perhaps be dynamically validated from '/products' endpoint
 type ProductID string
 
@@ -161,3 +161,47 @@ func mustParseDuration(t *testing.T, s string) time.Duration {
 	require.NoError(t, err)
 	return d
 }
+
+func TestProduct_ValidateOrder(t *testing.T) {
+	product := Product{
+		ID:             "BTC-USD",
+		BaseIncrement:  decimal.NewFromFloat(0.001),
+		BaseMinSize:    decimal.NewFromFloat(0.001),
+		BaseMaxSize:    decimal.NewFromInt(10),
+		QuoteIncrement: decimal.NewFromFloat(0.01),
+		MinMarketFunds: decimal.NewFromInt(10),
+		MaxMarketFunds: decimal.NewFromInt(1000000),
+	}
+	t.Run("Limit", func(t *testing.T) {
+		order := LimitOrder{Price: decimal.NewFromFloat(100.01), Size: decimal.NewFromFloat(0.5)}
+		assert.NoError(t, product.ValidateLimitOrder(order))
+		order.Price = decimal.NewFromFloat(100.001)
+		assert.EqualError(t, product.ValidateLimitOrder(order), "price 100.001 is not a multiple of increment 0.01")
+		order.Price = decimal.NewFromInt(100)
+		order.Size = decimal.NewFromFloat(0.0005)
+		assert.Error(t, product.ValidateLimitOrder(order))
+		order.Size = decimal.NewFromInt(11)
+		assert.EqualError(t, product.ValidateLimitOrder(order), "size 11 is more than base max size 10")
+	})
+	t.Run("Market", func(t *testing.T) {
+		funds := decimal.NewFromInt(5)
+		assert.EqualError(t, product.ValidateMarketOrder(MarketOrder{Funds: &funds}), "funds 5 is less than min market funds 10")
+		funds = decimal.NewFromInt(50)
+		assert.NoError(t, product.ValidateMarketOrder(MarketOrder{Funds: &funds}))
+		limitOnly := product
+		limitOnly.LimitOnly = true
+		assert.Error(t, limitOnly.ValidateMarketOrder(MarketOrder{Funds: &funds}))
+		disabled := product
+		disabled.TradingDisabled = true
+		assert.Error(t, disabled.ValidateMarketOrder(MarketOrder{Funds: &funds}))
+	})
+}
+
+func TestProduct_Round(t *testing.T) {
+	d := decimal.RequireFromString
+	product := Product{BaseIncrement: d("0.001"), QuoteIncrement: d("0.01")}
+	assert.Equal(t, "100.01", product.RoundQuote(d("100.019")).String())
+	assert.Equal(t, "0", product.RoundQuote(d("0.004")).String())
+	assert.Equal(t, "0.123", product.RoundBase(d("0.1239")).String())
+	assert.Equal(t, "0.1239", Product{}.RoundBase(d("0.1239")).String(), "a zero increment leaves the value unchanged")
+}
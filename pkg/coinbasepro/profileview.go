@@ -0,0 +1,248 @@
+package coinbasepro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// ProfileView aggregates the Accounts, open Orders and Fills of several Profiles of a user. The api scopes Accounts,
+// Orders and Fills to the Profile of the key that signs the request, so a ProfileView holds a Client signed by a key
+// of each Profile.
+type ProfileView struct {
+	// Profiles are the Profiles of the view in the order of their Clients
+	Profiles []Profile
+	clients  map[string]*Client
+}
+
+// NewProfileView finds the Profile of each Client from the ProfileID of its Accounts. The Profiles must be listed by
+// ListProfiles of the first Client, so that a Client of another user cannot join the view. A second Client of the same
+// Profile is ignored.
+func NewProfileView(ctx context.Context, clients ...*Client) (*ProfileView, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("a profile view requires a client")
+	}
+	profiles, err := clients[0].ListProfiles(ctx, ProfileFilter{})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Profile, len(profiles))
+	for _, profile := range profiles {
+		byID[profile.ID] = profile
+	}
+	view := ProfileView{clients: make(map[string]*Client, len(clients))}
+	for i, client := range clients {
+		accounts, err := client.ListAccounts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(accounts) == 0 {
+			return nil, fmt.Errorf("profile of client %d cannot be found without accounts", i)
+		}
+		profile, ok := byID[accounts[0].ProfileID]
+		if !ok {
+			return nil, fmt.Errorf("profile %s of client %d is not a profile of the user", accounts[0].ProfileID, i)
+		}
+		if _, ok = view.clients[profile.ID]; ok {
+			continue
+		}
+		view.clients[profile.ID] = client
+		view.Profiles = append(view.Profiles, profile)
+	}
+	return &view, nil
+}
+
+// Client returns the Client of the Profile.
+func (p *ProfileView) Client(profileID string) (*Client, bool) {
+	client, ok := p.clients[profileID]
+	return client, ok
+}
+
+// FindProfile finds a Profile of the view by its ID or Name.
+func (p *ProfileView) FindProfile(idOrName string) (Profile, bool) {
+	for _, profile := range p.Profiles {
+		if profile.ID == idOrName || profile.Name == idOrName {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// ListAccounts retrieves the Accounts of every Profile, each with the ProfileID of its Profile.
+func (p *ProfileView) ListAccounts(ctx context.Context) ([]Account, error) {
+	var all []Account
+	for _, profile := range p.Profiles {
+		accounts, err := p.clients[profile.ID].ListAccounts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, accounts...)
+	}
+	return all, nil
+}
+
+// ProfileBalance is the funds of a Currency summed across Profiles.
+type ProfileBalance struct {
+	Currency  CurrencyName    `json:"currency"`
+	Balance   decimal.Decimal `json:"balance"`
+	Available decimal.Decimal `json:"available"`
+	Hold      decimal.Decimal `json:"hold"`
+	// Profiles is the Balance in each Profile that holds the Currency, by ProfileID
+	Profiles map[string]decimal.Decimal `json:"profiles"`
+}
+
+// GetBalances sums the Accounts of every Profile by Currency. Currencies without a Balance in any Profile are left
+// out.
+func (p *ProfileView) GetBalances(ctx context.Context) ([]ProfileBalance, error) {
+	accounts, err := p.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byCurrency := make(map[CurrencyName]*ProfileBalance)
+	var currencies []CurrencyName
+	for _, account := range accounts {
+		if account.Balance.IsZero() {
+			continue
+		}
+		balance, ok := byCurrency[account.Currency]
+		if !ok {
+			balance = &ProfileBalance{Currency: account.Currency, Profiles: make(map[string]decimal.Decimal)}
+			byCurrency[account.Currency] = balance
+			currencies = append(currencies, account.Currency)
+		}
+		balance.Balance = balance.Balance.Add(account.Balance)
+		balance.Available = balance.Available.Add(account.Available)
+		balance.Hold = balance.Hold.Add(account.Hold)
+		balance.Profiles[account.ProfileID] = balance.Profiles[account.ProfileID].Add(account.Balance)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i] < currencies[j] })
+	balances := make([]ProfileBalance, 0, len(currencies))
+	for _, currency := range currencies {
+		balances = append(balances, *byCurrency[currency])
+	}
+	return balances, nil
+}
+
+// ListOpenOrders retrieves every page of the open, pending and active Orders of every Profile, optionally limited to
+// a Product, each with the ProfileID of its Profile.
+func (p *ProfileView) ListOpenOrders(ctx context.Context, productID ProductID) ([]*Order, error) {
+	filter := OrderFilter{
+		ProductID: productID,
+		Status:    []OrderStatusParam{OrderStatusParamOpen, OrderStatusParamPending, OrderStatusParamActive},
+	}
+	var all []*Order
+	for _, profile := range p.Profiles {
+		pagination := PaginationParams{Limit: 100}
+		for {
+			orders, err := p.clients[profile.ID].GetOrders(ctx, filter, pagination)
+			if err != nil {
+				return nil, err
+			}
+			for _, order := range orders.Orders {
+				if order.ProfileID == "" {
+					order.ProfileID = profile.ID
+				}
+			}
+			all = append(all, orders.Orders...)
+			if !nextPage(orders.Page, len(orders.Orders), &pagination) {
+				break
+			}
+		}
+	}
+	return all, nil
+}
+
+// ListFills retrieves every page of the Fills of every Profile that match the FillFilter, newest first, each with the
+// ProfileID of its Profile. The ProfileID of the FillFilter is ignored.
+func (p *ProfileView) ListFills(ctx context.Context, filter FillFilter) ([]*Fill, error) {
+	filter.ProfileID = ""
+	var all []*Fill
+	for _, profile := range p.Profiles {
+		fills, err := p.clients[profile.ID].ListFills(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, fill := range fills {
+			if fill.ProfileID == "" {
+				fill.ProfileID = profile.ID
+			}
+		}
+		all = append(all, fills...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].CreatedAt.Time().After(all[j].CreatedAt.Time())
+	})
+	return all, nil
+}
+
+// SweepRule moves the Available funds of a Currency beyond Keep out of each Profile.
+type SweepRule struct {
+	Currency CurrencyName    `json:"currency" yaml:"currency"`
+	Keep     decimal.Decimal `json:"keep" yaml:"keep"`
+}
+
+// PlanSweep lists the ProfileTransferSpecs that would move the funds of every other Profile to the Profile to, as
+// limited by the SweepRules.
+func (p *ProfileView) PlanSweep(ctx context.Context, to string, rules []SweepRule) ([]ProfileTransferSpec, error) {
+	if _, ok := p.clients[to]; !ok {
+		return nil, fmt.Errorf("profile %s is not in the view", to)
+	}
+	var specs []ProfileTransferSpec
+	for _, profile := range p.Profiles {
+		if profile.ID == to {
+			continue
+		}
+		accounts, err := p.clients[profile.ID].ListAccounts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range rules {
+			for _, account := range accounts {
+				if account.Currency != rule.Currency {
+					continue
+				}
+				amount := account.Available.Sub(rule.Keep)
+				if !amount.IsPositive() {
+					continue
+				}
+				specs = append(specs, ProfileTransferSpec{
+					Amount:   amount,
+					Currency: rule.Currency,
+					From:     profile.ID,
+					To:       to,
+				})
+			}
+		}
+	}
+	return specs, nil
+}
+
+// Sweep makes the ProfileTransfers of PlanSweep, each with the Client of the Profile the funds leave. A transfer that
+// fails ends the Sweep with the ProfileTransfers made so far. Transfers that a DryRunClient does not send do not end
+// the Sweep, which then returns ErrDryRun.
+func (p *ProfileView) Sweep(ctx context.Context, to string, rules []SweepRule) ([]ProfileTransfer, error) {
+	specs, err := p.PlanSweep(ctx, to, rules)
+	if err != nil {
+		return nil, err
+	}
+	var transfers []ProfileTransfer
+	var dryRun bool
+	for _, spec := range specs {
+		transfer, err := p.clients[spec.From].CreateProfileTransfer(ctx, spec)
+		switch {
+		case errors.Is(err, ErrDryRun):
+			dryRun = true
+		case err != nil:
+			return transfers, err
+		default:
+			transfers = append(transfers, transfer)
+		}
+	}
+	if dryRun {
+		return transfers, ErrDryRun
+	}
+	return transfers, nil
+}
@@ -0,0 +1,113 @@
+package coinbasepro
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileView(t *testing.T) {
+	ctx := context.Background()
+	profiles := []Profile{{ID: "main", Name: "default", IsDefault: true}, {ID: "bot", Name: "trading"}}
+	accounts := func(profileID string, usd int64, btc int64) func(mock.Arguments) {
+		return func(args mock.Arguments) {
+			*args.Get(1).(*[]Account) = []Account{
+				{ProfileID: profileID, Currency: "USD", Balance: decimal.NewFromInt(usd), Available: decimal.NewFromInt(usd)},
+				{ProfileID: profileID, Currency: "BTC", Balance: decimal.NewFromInt(btc), Available: decimal.NewFromInt(btc)},
+				{ProfileID: profileID, Currency: "ETH"},
+			}
+		}
+	}
+	var main, bot mockAPI
+	defer main.AssertExpectations(t)
+	defer bot.AssertExpectations(t)
+	main.On("Get", "/profiles/", mock.IsType(&[]Profile{})).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]Profile) = profiles
+	})
+	main.On("Get", "/accounts/", mock.IsType(&[]Account{})).Return(nil).Run(accounts("main", 100, 0))
+	bot.On("Get", "/accounts/", mock.IsType(&[]Account{})).Return(nil).Run(accounts("bot", 250, 1))
+	view, err := NewProfileView(ctx, &Client{api: &main}, &Client{api: &bot}, &Client{api: &bot})
+	require.NoError(t, err)
+	assert.Equal(t, profiles, view.Profiles, "a second client of a profile is ignored")
+	found, ok := view.FindProfile("trading")
+	assert.True(t, ok)
+	assert.Equal(t, "bot", found.ID)
+
+	t.Run("GetBalances", func(t *testing.T) {
+		balances, err := view.GetBalances(ctx)
+		require.NoError(t, err)
+		require.Len(t, balances, 2)
+		assert.Equal(t, CurrencyName("BTC"), balances[0].Currency)
+		assert.Equal(t, "1", balances[0].Balance.String())
+		assert.Equal(t, CurrencyName("USD"), balances[1].Currency)
+		assert.Equal(t, "350", balances[1].Available.String())
+		assert.Equal(t, "250", balances[1].Profiles["bot"].String())
+	})
+	t.Run("ListOpenOrders", func(t *testing.T) {
+		main.On("Get", "/orders/?status=open&status=pending&status=active&limit=100", mock.IsType(&Orders{})).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*Orders).Orders = []*Order{{ID: "a"}}
+		})
+		bot.On("Get", "/orders/?status=open&status=pending&status=active&limit=100", mock.IsType(&Orders{})).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*Orders).Orders = []*Order{{ID: "b", ProfileID: "bot"}}
+		})
+		orders, err := view.ListOpenOrders(ctx, "")
+		require.NoError(t, err)
+		require.Len(t, orders, 2)
+		assert.Equal(t, "main", orders[0].ProfileID)
+		assert.Equal(t, "bot", orders[1].ProfileID)
+	})
+	t.Run("ListFills", func(t *testing.T) {
+		older, newer := Time(time.Unix(100, 0)), Time(time.Unix(200, 0))
+		main.On("Get", "/fills/?product_id=BTC-USD&limit=100", mock.IsType(&Fills{})).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*Fills).Fills = []*Fill{{TradeID: 1, CreatedAt: older}}
+		})
+		bot.On("Get", "/fills/?product_id=BTC-USD&limit=100", mock.IsType(&Fills{})).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*Fills).Fills = []*Fill{{TradeID: 2, CreatedAt: newer}}
+		})
+		fills, err := view.ListFills(ctx, FillFilter{ProductID: "BTC-USD", ProfileID: "ignored"})
+		require.NoError(t, err)
+		require.Len(t, fills, 2)
+		assert.Equal(t, int64(2), fills[0].TradeID, "fills are newest first")
+		assert.Equal(t, "main", fills[1].ProfileID)
+	})
+	t.Run("Sweep", func(t *testing.T) {
+		rules := []SweepRule{{Currency: "USD", Keep: decimal.NewFromInt(50)}, {Currency: "BTC"}, {Currency: "ETH"}}
+		expected := []ProfileTransferSpec{
+			{Amount: decimal.NewFromInt(200), Currency: "USD", From: "bot", To: "main"},
+			{Amount: decimal.NewFromInt(1), Currency: "BTC", From: "bot", To: "main"},
+		}
+		specs, err := view.PlanSweep(ctx, "main", rules)
+		require.NoError(t, err)
+		assert.Equal(t, expected, specs)
+
+		bot.On("Post", "/profiles/transfer", expected[0], mock.IsType(&ProfileTransfer{})).Return(nil).Once()
+		bot.On("Post", "/profiles/transfer", expected[1], mock.IsType(&ProfileTransfer{})).Return(ErrDryRun).Once()
+		transfers, err := view.Sweep(ctx, "main", rules)
+		assert.True(t, errors.Is(err, ErrDryRun))
+		assert.Len(t, transfers, 1, "a dry run does not end the sweep")
+
+		_, err = view.PlanSweep(ctx, "other", rules)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewProfileView_OtherUser(t *testing.T) {
+	var main, other mockAPI
+	main.On("Get", "/profiles/", mock.IsType(&[]Profile{})).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]Profile) = []Profile{{ID: "main"}}
+	})
+	main.On("Get", "/accounts/", mock.IsType(&[]Account{})).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]Account) = []Account{{ProfileID: "main"}}
+	})
+	other.On("Get", "/accounts/", mock.IsType(&[]Account{})).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]Account) = []Account{{ProfileID: "stranger"}}
+	})
+	_, err := NewProfileView(context.Background(), &Client{api: &main}, &Client{api: &other})
+	assert.EqualError(t, err, "profile stranger of client 1 is not a profile of the user")
+}
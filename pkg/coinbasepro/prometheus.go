@@ -0,0 +1,220 @@
+package coinbasepro
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requestBuckets are the upper bounds, in seconds, of the latency histogram of api requests.
+var requestBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// waitBuckets are the upper bounds, in seconds, of the histogram of rate limit waits.
+var waitBuckets = []float64{0, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 5}
+
+// PrometheusMetrics collects Metrics and serves them in the Prometheus text exposition format.
+type PrometheusMetrics struct {
+	mu              sync.Mutex
+	requests        map[requestLabels]*histogram
+	rateLimitWaits  *histogram
+	messages        map[string]uint64
+	droppedMessages uint64
+	reconnects      uint64
+	sequenceGaps    map[ProductID]uint64
+	missedMessages  map[ProductID]uint64
+	clockSkew       time.Duration
+}
+
+type requestLabels struct {
+	method   string
+	endpoint string
+	status   string
+}
+
+// NewPrometheusMetrics creates PrometheusMetrics that have observed nothing.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		requests:       make(map[requestLabels]*histogram),
+		rateLimitWaits: newHistogram(waitBuckets),
+		messages:       make(map[string]uint64),
+		sequenceGaps:   make(map[ProductID]uint64),
+		missedMessages: make(map[ProductID]uint64),
+	}
+}
+
+func (p *PrometheusMetrics) ObserveRequest(method string, endpoint string, status int, duration time.Duration) {
+	labels := requestLabels{method: method, endpoint: endpoint, status: "error"}
+	if status != 0 {
+		labels.status = strconv.Itoa(status)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h, ok := p.requests[labels]
+	if !ok {
+		h = newHistogram(requestBuckets)
+		p.requests[labels] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+func (p *PrometheusMetrics) ObserveRateLimitWait(duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rateLimitWaits.observe(duration.Seconds())
+}
+
+func (p *PrometheusMetrics) ObserveMessage(messageType string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages[messageType]++
+}
+
+func (p *PrometheusMetrics) ObserveDroppedMessage() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.droppedMessages++
+}
+
+func (p *PrometheusMetrics) ObserveReconnect() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reconnects++
+}
+
+func (p *PrometheusMetrics) ObserveSequenceGap(productID ProductID, missed int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sequenceGaps[productID]++
+	p.missedMessages[productID] += uint64(missed)
+}
+
+func (p *PrometheusMetrics) ObserveClockSkew(skew time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clockSkew = skew
+}
+
+// ServeHTTP serves the metrics, such as on `/metrics`.
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = p.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (p *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var b strings.Builder
+
+	writeHeader(&b, "reticule_api_requests_total", "counter", "Requests to the api by method, endpoint and status.")
+	keys := make([]requestLabels, 0, len(p.requests))
+	for key := range p.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.endpoint != b.endpoint {
+			return a.endpoint < b.endpoint
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	for _, key := range keys {
+		fmt.Fprintf(&b, "reticule_api_requests_total%s %d\n", key.labels(), p.requests[key].count)
+	}
+	writeHeader(&b, "reticule_api_request_duration_seconds", "histogram", "Latency of requests to the api by method, endpoint and status.")
+	for _, key := range keys {
+		p.requests[key].write(&b, "reticule_api_request_duration_seconds", key.labels())
+	}
+	writeHeader(&b, "reticule_rate_limit_wait_seconds", "histogram", "Time requests waited for the rate limiter.")
+	p.rateLimitWaits.write(&b, "reticule_rate_limit_wait_seconds", "")
+
+	writeHeader(&b, "reticule_feed_messages_total", "counter", "Messages received from the feed by type.")
+	types := make([]string, 0, len(p.messages))
+	for messageType := range p.messages {
+		types = append(types, messageType)
+	}
+	sort.Strings(types)
+	for _, messageType := range types {
+		fmt.Fprintf(&b, "reticule_feed_messages_total{type=%s} %d\n", quote(messageType), p.messages[messageType])
+	}
+	writeHeader(&b, "reticule_feed_dropped_messages_total", "counter", "Messages of the feed dropped because their reader was not ready.")
+	fmt.Fprintf(&b, "reticule_feed_dropped_messages_total %d\n", p.droppedMessages)
+	writeHeader(&b, "reticule_feed_reconnects_total", "counter", "Connections to the feed made after a connection failed.")
+	fmt.Fprintf(&b, "reticule_feed_reconnects_total %d\n", p.reconnects)
+
+	productIDs := make([]string, 0, len(p.sequenceGaps))
+	for productID := range p.sequenceGaps {
+		productIDs = append(productIDs, string(productID))
+	}
+	sort.Strings(productIDs)
+	writeHeader(&b, "reticule_feed_sequence_gaps_total", "counter", "Gaps in the sequence of full channel messages by product.")
+	for _, productID := range productIDs {
+		fmt.Fprintf(&b, "reticule_feed_sequence_gaps_total{product_id=%s} %d\n", quote(productID), p.sequenceGaps[ProductID(productID)])
+	}
+	writeHeader(&b, "reticule_feed_missed_messages_total", "counter", "Full channel messages missed in sequence gaps by product.")
+	for _, productID := range productIDs {
+		fmt.Fprintf(&b, "reticule_feed_missed_messages_total{product_id=%s} %d\n", quote(productID), p.missedMessages[ProductID(productID)])
+	}
+	writeHeader(&b, "reticule_clock_skew_seconds", "gauge", "Server time minus the local clock at the last measurement.")
+	fmt.Fprintf(&b, "reticule_clock_skew_seconds %g\n", p.clockSkew.Seconds())
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (r requestLabels) labels() string {
+	return fmt.Sprintf("{method=%s,endpoint=%s,status=%s}", quote(r.method), quote(r.endpoint), quote(r.status))
+}
+
+func writeHeader(b *strings.Builder, name string, metricType string, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// quote quotes a label value, escaping backslashes, quotes and newlines.
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// histogram counts observations in buckets of the observations no greater than each upper bound.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.bounds {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// write writes the buckets, sum and count of the histogram with the labels, which are either empty or braced.
+func (h *histogram) write(b *strings.Builder, name string, labels string) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(labels, "{"), "}")
+	if inner != "" {
+		inner += ","
+	}
+	for i, bound := range h.bounds {
+		fmt.Fprintf(b, "%s_bucket{%sle=%s} %d\n", name, inner, quote(strconv.FormatFloat(bound, 'f', -1, 64)), h.counts[i])
+	}
+	fmt.Fprintf(b, "%s_bucket{%sle=\"+Inf\"} %d\n", name, inner, h.count)
+	fmt.Fprintf(b, "%s_sum%s %s\n", name, labels, strconv.FormatFloat(h.sum, 'f', -1, 64))
+	fmt.Fprintf(b, "%s_count%s %d\n", name, labels, h.count)
+}
@@ -0,0 +1,54 @@
+package coinbasepro
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	metrics.ObserveRequest("GET", "/accounts/:id", 200, 30*time.Millisecond)
+	metrics.ObserveRequest("GET", "/accounts/:id", 200, 250*time.Millisecond)
+	metrics.ObserveRequest("POST", "/orders/", 0, time.Second)
+	metrics.ObserveRateLimitWait(0)
+	metrics.ObserveMessage("ticker")
+	metrics.ObserveMessage("ticker")
+	metrics.ObserveMessage(`odd"type`)
+	metrics.ObserveDroppedMessage()
+	metrics.ObserveReconnect()
+	metrics.ObserveSequenceGap("BTC-USD", 3)
+	metrics.ObserveSequenceGap("BTC-USD", 2)
+	metrics.ObserveClockSkew(-1500 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	metrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+	for _, line := range []string{
+		`# TYPE reticule_api_requests_total counter`,
+		`reticule_api_requests_total{method="GET",endpoint="/accounts/:id",status="200"} 2`,
+		`reticule_api_requests_total{method="POST",endpoint="/orders/",status="error"} 1`,
+		`# TYPE reticule_api_request_duration_seconds histogram`,
+		`reticule_api_request_duration_seconds_bucket{method="GET",endpoint="/accounts/:id",status="200",le="0.05"} 1`,
+		`reticule_api_request_duration_seconds_bucket{method="GET",endpoint="/accounts/:id",status="200",le="0.25"} 2`,
+		`reticule_api_request_duration_seconds_bucket{method="GET",endpoint="/accounts/:id",status="200",le="+Inf"} 2`,
+		`reticule_api_request_duration_seconds_sum{method="GET",endpoint="/accounts/:id",status="200"} 0.28`,
+		`reticule_api_request_duration_seconds_count{method="GET",endpoint="/accounts/:id",status="200"} 2`,
+		`reticule_rate_limit_wait_seconds_bucket{le="0"} 1`,
+		`reticule_rate_limit_wait_seconds_count 1`,
+		`reticule_feed_messages_total{type="ticker"} 2`,
+		`reticule_feed_messages_total{type="odd\"type"} 1`,
+		`reticule_feed_dropped_messages_total 1`,
+		`reticule_feed_reconnects_total 1`,
+		`reticule_feed_sequence_gaps_total{product_id="BTC-USD"} 2`,
+		`reticule_feed_missed_messages_total{product_id="BTC-USD"} 5`,
+		`# TYPE reticule_clock_skew_seconds gauge`,
+		`reticule_clock_skew_seconds -1.5`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
@@ -0,0 +1,81 @@
+package coinbasepro
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := NewTokenBucket(5, 2)
+	bucket.now = func() time.Time { return now }
+
+	assert.Equal(t, time.Duration(0), bucket.reserve())
+	assert.Equal(t, time.Duration(0), bucket.reserve())
+	assert.Equal(t, 200*time.Millisecond, bucket.reserve())
+	assert.Equal(t, 400*time.Millisecond, bucket.reserve())
+
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), bucket.reserve(), "a second adds five tokens, but the bucket holds only two")
+	assert.Equal(t, time.Duration(0), bucket.reserve())
+	assert.Equal(t, 200*time.Millisecond, bucket.reserve())
+
+	bucket.release()
+	assert.Equal(t, 200*time.Millisecond, bucket.reserve(), "a released token is reserved again")
+}
+
+func TestTokenBucket_Wait(t *testing.T) {
+	bucket := NewTokenBucket(0.001, 1)
+	require.NoError(t, bucket.Wait(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := bucket.Wait(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestWithRateLimiter(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("after") == "" {
+			w.Header().Set("CB-BEFORE", "b")
+			w.Header().Set("CB-AFTER", "a")
+		}
+		_, _ = w.Write([]byte(`[{"trade_id":1}]`))
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	limiter := &countingLimiter{}
+	client, err := NewClient(baseURL, baseURL, &Auth{Key: "k", Passphrase: "p", Secret: "zZ=="}, WithRateLimiter(limiter))
+	require.NoError(t, err)
+	DryRunMode(client, func(SignedRequest) error { return nil })
+
+	fills, err := client.ListFills(context.Background(), FillFilter{ProductID: "BTC-USD"})
+	require.NoError(t, err)
+	assert.Len(t, fills, 2)
+	assert.Equal(t, 2, limiter.waits, "each page of a list waits for the limiter")
+
+	limiter.err = context.Canceled
+	_, err = client.GetServerTime(context.Background())
+	assert.True(t, errors.Is(err, context.Canceled), "a request is not sent when the limiter fails")
+	assert.Equal(t, 2, requests)
+}
+
+type countingLimiter struct {
+	waits int
+	err   error
+}
+
+func (c *countingLimiter) Wait(context.Context) error {
+	c.waits++
+	return c.err
+}
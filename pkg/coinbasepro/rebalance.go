@@ -0,0 +1,380 @@
+package coinbasepro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// RebalanceTargets are the allocations that PlanRebalance trades the Accounts of the current Profile toward.
+type RebalanceTargets struct {
+	// Quote is the Currency in which the Currencies are valued and traded; each Currency is traded through the
+	// Product of the Currency and the Quote, such as BTC-USD
+	Quote CurrencyName `json:"quote" yaml:"quote"`
+	// Weights are the relative target allocations of each Currency. They need not sum to 100; a Quote Currency that
+	// is not weighted has a target of zero, so that all of its funds are traded. Currencies are not case-sensitive.
+	Weights map[CurrencyName]decimal.Decimal `json:"weights" yaml:"weights"`
+	// Tolerance is the drift, in percentage points of the total value, within which a Currency is not traded
+	Tolerance decimal.Decimal `json:"tolerance" yaml:"tolerance"`
+	// Type of the orders of the trades, OrderTypeMarket when empty
+	Type OrderType `json:"type" yaml:"type"`
+	// FeeRate is the fee on the value of each trade, such as 0.005, which is deducted from the proceeds of the sells
+	// and kept back from the buys. PlanRebalance uses the TakerFeeRate of GetFees when it is zero.
+	FeeRate decimal.Decimal `json:"fee_rate" yaml:"fee_rate"`
+}
+
+func (r RebalanceTargets) Validate() error {
+	if r.Quote == "" {
+		return errors.New("'quote' is required")
+	}
+	if len(r.Weights) == 0 {
+		return errors.New("'weights' are required")
+	}
+	sum := decimal.Zero
+	currencies := make(map[CurrencyName]bool, len(r.Weights))
+	for currency, weight := range r.Weights {
+		if weight.IsNegative() {
+			return fmt.Errorf("weight %s of %s is negative", weight, currency)
+		}
+		upper := CurrencyName(strings.ToUpper(string(currency)))
+		if currencies[upper] {
+			return fmt.Errorf("%s is weighted more than once", upper)
+		}
+		currencies[upper] = true
+		sum = sum.Add(weight)
+	}
+	if !sum.IsPositive() {
+		return errors.New("a weight must be positive")
+	}
+	if r.Tolerance.IsNegative() {
+		return fmt.Errorf("tolerance %s is negative", r.Tolerance)
+	}
+	if r.FeeRate.IsNegative() || r.FeeRate.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return fmt.Errorf("fee rate %s is not between 0 and 1", r.FeeRate)
+	}
+	switch r.Type {
+	case "", OrderTypeMarket, OrderTypeLimit:
+		return nil
+	}
+	return fmt.Errorf("type(%q) is not valid", r.Type)
+}
+
+// normalized is the RebalanceTargets with the Quote and the Currencies of the Weights in upper case, as the api names
+// them.
+func (r RebalanceTargets) normalized() RebalanceTargets {
+	r.Quote = CurrencyName(strings.ToUpper(string(r.Quote)))
+	weights := make(map[CurrencyName]decimal.Decimal, len(r.Weights))
+	for currency, weight := range r.Weights {
+		weights[CurrencyName(strings.ToUpper(string(currency)))] = weight
+	}
+	r.Weights = weights
+	return r
+}
+
+// RebalancePlan is the trades that move the Available funds of the current Profile toward the RebalanceTargets.
+type RebalancePlan struct {
+	// Quote is the Currency in which all values are expressed
+	Quote CurrencyName `json:"quote"`
+	// Total is the value of the Available funds of the targeted Currencies
+	Total decimal.Decimal `json:"total"`
+	// Allocations are the current and target allocations of each targeted Currency
+	Allocations []RebalanceAllocation `json:"allocations"`
+	// Trades are the trades to make, sells before buys so that the buys are funded by the sells, net of their fees
+	Trades []RebalanceTrade `json:"trades"`
+	// Skipped are the trades that the increments and limits of their Product do not allow, each with its Reason
+	Skipped []RebalanceTrade `json:"skipped,omitempty"`
+}
+
+// RebalanceAllocation is the current and target allocation of a Currency of a RebalancePlan.
+type RebalanceAllocation struct {
+	Currency  CurrencyName    `json:"currency"`
+	Available decimal.Decimal `json:"available"`
+	// Price of one unit of Currency in the Quote Currency
+	Price decimal.Decimal `json:"price"`
+	// Value is the Available funds in the Quote Currency
+	Value decimal.Decimal `json:"value"`
+	// Allocation is the percentage of the Total made up by the Currency
+	Allocation decimal.Decimal `json:"allocation"`
+	// Target is the percentage of the Total weighted to the Currency
+	Target decimal.Decimal `json:"target"`
+	// TargetValue is the Target in the Quote Currency
+	TargetValue decimal.Decimal `json:"target_value"`
+}
+
+// RebalanceTrade is a single order of a RebalancePlan.
+type RebalanceTrade struct {
+	ProductID ProductID `json:"product_id"`
+	Side      Side      `json:"side"`
+	Type      OrderType `json:"type"`
+	// Size of the trade, rounded down to the BaseIncrement of the Product
+	Size decimal.Decimal `json:"size"`
+	// Price is the last trade price, rounded down to the QuoteIncrement of the Product, and the price of a limit order
+	Price decimal.Decimal `json:"price"`
+	// Funds limit a market buy, which is sized in the Quote Currency so that a rising price cannot overspend
+	Funds *decimal.Decimal `json:"funds,omitempty"`
+	// Value is the Size at the Price
+	Value decimal.Decimal `json:"value"`
+	// Reason the trade was skipped
+	Reason string `json:"reason,omitempty"`
+}
+
+// LimitOrder is the trade as a LimitOrder at its Price.
+func (r RebalanceTrade) LimitOrder() LimitOrder {
+	return LimitOrder{
+		ProductID:           r.ProductID,
+		SelfTradePrevention: SelfTradeDecrementAndCancel,
+		Side:                r.Side,
+		Type:                OrderTypeLimit,
+		Price:               r.Price,
+		Size:                r.Size,
+		TimeInForce:         TimeInForceGoodTillCanceled,
+	}
+}
+
+// MarketOrder is the trade as a MarketOrder of its Funds, or of its Size when it has no Funds.
+func (r RebalanceTrade) MarketOrder() MarketOrder {
+	order := MarketOrder{
+		ProductID:           r.ProductID,
+		SelfTradePrevention: SelfTradeDecrementAndCancel,
+		Side:                r.Side,
+		Type:                OrderTypeMarket,
+	}
+	if r.Funds != nil {
+		order.Funds = r.Funds
+		return order
+	}
+	size := r.Size
+	order.Size = &size
+	return order
+}
+
+// PlanRebalance values the Available funds of each Currency of the RebalanceTargets in the Quote Currency with the
+// last trade price from GetProductTicker, and plans the trades that bring each Currency that has drifted beyond the
+// Tolerance to its target. Holds are not traded. Each Currency other than the Quote must have a Product with the
+// Quote, such as BTC-USD for BTC valued in USD.
+func (c *Client) PlanRebalance(ctx context.Context, targets RebalanceTargets) (RebalancePlan, error) {
+	if err := targets.Validate(); err != nil {
+		return RebalancePlan{}, err
+	}
+	targets = targets.normalized()
+	if targets.FeeRate.IsZero() {
+		fees, err := c.GetFees(ctx)
+		if err != nil {
+			return RebalancePlan{}, err
+		}
+		targets.FeeRate = fees.TakerFeeRate
+	}
+	accounts, err := c.ListAccounts(ctx)
+	if err != nil {
+		return RebalancePlan{}, err
+	}
+	products, err := c.ListProducts(ctx)
+	if err != nil {
+		return RebalancePlan{}, err
+	}
+	byID := make(map[ProductID]Product, len(products))
+	for _, product := range products {
+		byID[ProductID(product.ID)] = product
+	}
+	tickers := make(map[ProductID]ProductTicker)
+	for currency := range targets.Weights {
+		if currency == targets.Quote {
+			continue
+		}
+		productID := rebalanceProductID(currency, targets.Quote)
+		product, ok := byID[productID]
+		if !ok {
+			return RebalancePlan{}, fmt.Errorf("no product %s trades %s for %s", productID, currency, targets.Quote)
+		}
+		ticker, err := c.GetProductTicker(ctx, ProductID(product.ID))
+		if err != nil {
+			return RebalancePlan{}, err
+		}
+		tickers[productID] = ticker
+	}
+	return planRebalance(targets, accounts, byID, tickers)
+}
+
+// Rebalance places the orders of the Trades of the RebalancePlan in order. An order that fails ends the Rebalance
+// with the Orders placed so far. Orders that a DryRunClient does not send do not end the Rebalance, which then returns
+// ErrDryRun. Limit sells that rest on the book leave less of the Quote Currency for the buys that follow them.
+func (c *Client) Rebalance(ctx context.Context, plan RebalancePlan) ([]Order, error) {
+	var orders []Order
+	var dryRun bool
+	for _, trade := range plan.Trades {
+		var order Order
+		var err error
+		if trade.Type == OrderTypeLimit {
+			order, err = c.CreateLimitOrder(ctx, trade.LimitOrder())
+		} else {
+			order, err = c.CreateMarketOrder(ctx, trade.MarketOrder())
+		}
+		switch {
+		case errors.Is(err, ErrDryRun):
+			dryRun = true
+		case err != nil:
+			return orders, err
+		default:
+			orders = append(orders, order)
+		}
+	}
+	if dryRun {
+		return orders, ErrDryRun
+	}
+	return orders, nil
+}
+
+func rebalanceProductID(currency CurrencyName, quote CurrencyName) ProductID {
+	return ProductID(fmt.Sprintf("%s-%s", currency, quote))
+}
+
+// planRebalance plans the trades of the RebalanceTargets from the Accounts, the Products by ID and the tickers of the
+// Products of each Currency with the Quote.
+func planRebalance(targets RebalanceTargets, accounts []Account, products map[ProductID]Product, tickers map[ProductID]ProductTicker) (RebalancePlan, error) {
+	orderType := targets.Type
+	if orderType == "" {
+		orderType = OrderTypeMarket
+	}
+	currencies := make([]CurrencyName, 0, len(targets.Weights)+1)
+	weightSum := decimal.Zero
+	for currency, weight := range targets.Weights {
+		currencies = append(currencies, currency)
+		weightSum = weightSum.Add(weight)
+	}
+	if _, ok := targets.Weights[targets.Quote]; !ok {
+		currencies = append(currencies, targets.Quote)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i] < currencies[j] })
+
+	plan := RebalancePlan{Quote: targets.Quote, Trades: []RebalanceTrade{}}
+	hundred := decimal.NewFromInt(100)
+	quoteAvailable := decimal.Zero
+	for _, currency := range currencies {
+		allocation := RebalanceAllocation{Currency: currency, Price: decimal.NewFromInt(1)}
+		for _, account := range accounts {
+			if account.Currency == currency {
+				allocation.Available = allocation.Available.Add(account.Available)
+			}
+		}
+		if currency == targets.Quote {
+			quoteAvailable = allocation.Available
+		} else {
+			productID := rebalanceProductID(currency, targets.Quote)
+			allocation.Price = tickers[productID].Price
+			if !allocation.Price.IsPositive() {
+				return RebalancePlan{}, fmt.Errorf("product %q has no last trade price", productID)
+			}
+		}
+		allocation.Value = allocation.Available.Mul(allocation.Price)
+		plan.Total = plan.Total.Add(allocation.Value)
+		plan.Allocations = append(plan.Allocations, allocation)
+	}
+	if !plan.Total.IsPositive() {
+		return RebalancePlan{}, fmt.Errorf("no funds of the target currencies are available to rebalance")
+	}
+
+	type buy struct {
+		product Product
+		price   decimal.Decimal
+		value   decimal.Decimal
+	}
+	var buys []buy
+	one := decimal.NewFromInt(1)
+	budget, wanted := quoteAvailable, decimal.Zero
+	for i := range plan.Allocations {
+		allocation := &plan.Allocations[i]
+		weight := targets.Weights[allocation.Currency]
+		allocation.Allocation = allocation.Value.Mul(hundred).DivRound(plan.Total, 4)
+		allocation.Target = weight.Mul(hundred).DivRound(weightSum, 4)
+		allocation.TargetValue = plan.Total.Mul(weight).Div(weightSum)
+		drift := allocation.Allocation.Sub(allocation.Target)
+		if allocation.Currency == targets.Quote || drift.Abs().LessThanOrEqual(targets.Tolerance) {
+			continue
+		}
+		product := products[rebalanceProductID(allocation.Currency, targets.Quote)]
+		excess := allocation.Value.Sub(allocation.TargetValue)
+		if excess.IsPositive() {
+			trade := newRebalanceTrade(product, SideSell, orderType, excess, allocation.Price)
+			if trade.Reason == "" {
+				budget = budget.Add(trade.Value.Mul(one.Sub(targets.FeeRate)))
+			}
+			plan.add(trade)
+			continue
+		}
+		buys = append(buys, buy{product: product, price: allocation.Price, value: excess.Neg()})
+		wanted = wanted.Add(excess.Neg())
+	}
+	// sells that are skipped, rounded down or charged fees leave less of the quote to buy with, so the buys share what
+	// there is after their own fees
+	spendable := budget.Div(one.Add(targets.FeeRate))
+	scale := one
+	if wanted.GreaterThan(spendable) {
+		scale = spendable.Div(wanted)
+	}
+	for _, b := range buys {
+		plan.add(newRebalanceTrade(b.product, SideBuy, orderType, b.value.Mul(scale), b.price))
+	}
+	sort.SliceStable(plan.Trades, func(i, j int) bool {
+		if plan.Trades[i].Side != plan.Trades[j].Side {
+			return plan.Trades[i].Side == SideSell
+		}
+		return plan.Trades[i].Value.GreaterThan(plan.Trades[j].Value)
+	})
+	return plan, nil
+}
+
+func (r *RebalancePlan) add(trade RebalanceTrade) {
+	if trade.Reason != "" {
+		r.Skipped = append(r.Skipped, trade)
+		return
+	}
+	r.Trades = append(r.Trades, trade)
+}
+
+// newRebalanceTrade sizes a trade of value in the quote of the Product at price, within the increments and limits of
+// the Product. A trade that the Product does not allow has a Reason.
+func newRebalanceTrade(product Product, side Side, orderType OrderType, value decimal.Decimal, price decimal.Decimal) RebalanceTrade {
+	price = product.RoundQuote(price)
+	if !price.IsPositive() {
+		return RebalanceTrade{
+			ProductID: ProductID(product.ID),
+			Side:      side,
+			Type:      orderType,
+			Price:     price,
+			Reason:    fmt.Sprintf("price rounds down to zero at increment %s", product.QuoteIncrement),
+		}
+	}
+	size := product.RoundBase(value.Div(price))
+	if product.BaseMaxSize.IsPositive() && size.GreaterThan(product.BaseMaxSize) {
+		size = product.BaseMaxSize
+	}
+	trade := RebalanceTrade{
+		ProductID: ProductID(product.ID),
+		Side:      side,
+		Type:      orderType,
+		Size:      size,
+		Price:     price,
+		Value:     size.Mul(price),
+	}
+	if orderType == OrderTypeMarket && side == SideBuy {
+		funds := product.RoundQuote(trade.Value)
+		trade.Funds = &funds
+	}
+	var err error
+	switch {
+	case !size.IsPositive():
+		err = fmt.Errorf("size rounds down to zero at increment %s", product.BaseIncrement)
+	case orderType == OrderTypeLimit:
+		err = product.ValidateLimitOrder(trade.LimitOrder())
+	default:
+		err = product.ValidateMarketOrder(trade.MarketOrder())
+	}
+	if err != nil {
+		trade.Reason = err.Error()
+	}
+	return trade
+}
@@ -0,0 +1,157 @@
+package coinbasepro
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRebalance(t *testing.T) {
+	d := decimal.RequireFromString
+	accounts := []Account{
+		{Currency: "USD", Balance: d("1200"), Available: d("1000"), Hold: d("200")},
+		{Currency: "BTC", Balance: d("0.1"), Available: d("0.1")},
+		{Currency: "ETH", Balance: d("10"), Available: d("10")},
+		{Currency: "LTC", Balance: d("5"), Available: d("5")},
+	}
+	products := map[ProductID]Product{
+		"BTC-USD": {ID: "BTC-USD", BaseCurrency: "BTC", QuoteCurrency: "USD", BaseIncrement: d("0.0001"), BaseMinSize: d("0.001"), QuoteIncrement: d("0.01"), MinMarketFunds: d("10")},
+		"ETH-USD": {ID: "ETH-USD", BaseCurrency: "ETH", QuoteCurrency: "USD", BaseIncrement: d("0.01"), BaseMinSize: d("0.1"), QuoteIncrement: d("0.01")},
+	}
+	tickers := map[ProductID]ProductTicker{
+		"BTC-USD": {Price: d("10000")},
+		"ETH-USD": {Price: d("100")},
+	}
+	weights := map[CurrencyName]decimal.Decimal{"BTC": d("50"), "ETH": d("30"), "USD": d("20")}
+	t.Run("Market", func(t *testing.T) {
+		plan, err := planRebalance(RebalanceTargets{Quote: "USD", Weights: weights}, accounts, products, tickers)
+		require.NoError(t, err)
+		assert.Equal(t, "3000", plan.Total.String(), "holds and untargeted currencies are not valued")
+		require.Len(t, plan.Allocations, 3)
+		assert.Equal(t, CurrencyName("BTC"), plan.Allocations[0].Currency)
+		assert.Equal(t, "33.3333", plan.Allocations[0].Allocation.String())
+		assert.Equal(t, "50", plan.Allocations[0].Target.String())
+		assert.Equal(t, "1500", plan.Allocations[0].TargetValue.String())
+		require.Len(t, plan.Trades, 2)
+		sell, buy := plan.Trades[0], plan.Trades[1]
+		assert.Equal(t, ProductID("ETH-USD"), sell.ProductID, "sells come before buys")
+		assert.Equal(t, SideSell, sell.Side)
+		assert.Equal(t, "1", sell.Size.String())
+		assert.Nil(t, sell.Funds)
+		assert.Equal(t, ProductID("BTC-USD"), buy.ProductID)
+		assert.Equal(t, SideBuy, buy.Side)
+		assert.Equal(t, "0.05", buy.Size.String())
+		require.NotNil(t, buy.Funds)
+		assert.Equal(t, "500", buy.Funds.String(), "market buys are limited by funds")
+		assert.Empty(t, plan.Skipped)
+	})
+	t.Run("Tolerance", func(t *testing.T) {
+		plan, err := planRebalance(RebalanceTargets{Quote: "USD", Weights: weights, Tolerance: d("5")}, accounts, products, tickers)
+		require.NoError(t, err)
+		require.Len(t, plan.Trades, 1, "eth drifts less than the tolerance")
+		assert.Equal(t, ProductID("BTC-USD"), plan.Trades[0].ProductID)
+	})
+	t.Run("Limit", func(t *testing.T) {
+		plan, err := planRebalance(RebalanceTargets{Quote: "USD", Weights: weights, Type: OrderTypeLimit}, accounts, products, tickers)
+		require.NoError(t, err)
+		require.Len(t, plan.Trades, 2)
+		assert.Nil(t, plan.Trades[1].Funds)
+		order := plan.Trades[1].LimitOrder()
+		assert.NoError(t, order.Validate())
+		assert.Equal(t, "10000", order.Price.String())
+		assert.Equal(t, "0.05", order.Size.String())
+	})
+	t.Run("Skipped", func(t *testing.T) {
+		limitOnly := map[ProductID]Product{"BTC-USD": products["BTC-USD"], "ETH-USD": products["ETH-USD"]}
+		eth := limitOnly["ETH-USD"]
+		eth.LimitOnly = true
+		limitOnly["ETH-USD"] = eth
+		plan, err := planRebalance(RebalanceTargets{Quote: "USD", Weights: map[CurrencyName]decimal.Decimal{"BTC": d("1"), "ETH": d("1")}},
+			[]Account{{Currency: "ETH", Available: d("10")}}, limitOnly, tickers)
+		require.NoError(t, err)
+		assert.Empty(t, plan.Trades)
+		require.Len(t, plan.Skipped, 2)
+		assert.Equal(t, "product ETH-USD only accepts limit orders", plan.Skipped[0].Reason)
+		assert.Equal(t, "size rounds down to zero at increment 0.0001", plan.Skipped[1].Reason, "a skipped sell does not fund the buys")
+	})
+	t.Run("FeeRate", func(t *testing.T) {
+		held := []Account{{Currency: "BTC", Available: d("0.1")}, {Currency: "ETH", Available: d("10")}}
+		plan, err := planRebalance(RebalanceTargets{Quote: "USD", Weights: map[CurrencyName]decimal.Decimal{"BTC": d("3"), "ETH": d("1")}, FeeRate: d("0.01")},
+			held, products, tickers)
+		require.NoError(t, err)
+		require.Len(t, plan.Trades, 2)
+		sell, buy := plan.Trades[0], plan.Trades[1]
+		assert.Equal(t, "500", sell.Value.String())
+		assert.Equal(t, "0.049", buy.Size.String(), "the buy is funded by the sell after the fees of both")
+		assert.Equal(t, "490", buy.Funds.String())
+		assert.True(t, buy.Value.Mul(d("1.01")).LessThanOrEqual(sell.Value.Mul(d("0.99"))))
+	})
+	t.Run("PriceRoundsToZero", func(t *testing.T) {
+		plan, err := planRebalance(RebalanceTargets{Quote: "USD", Weights: map[CurrencyName]decimal.Decimal{"SHIB": d("1"), "USD": d("1")}},
+			[]Account{{Currency: "USD", Available: d("100")}},
+			map[ProductID]Product{"SHIB-USD": {ID: "SHIB-USD", BaseCurrency: "SHIB", QuoteCurrency: "USD", BaseIncrement: d("1"), QuoteIncrement: d("0.01")}},
+			map[ProductID]ProductTicker{"SHIB-USD": {Price: d("0.004")}})
+		require.NoError(t, err)
+		assert.Empty(t, plan.Trades)
+		require.Len(t, plan.Skipped, 1)
+		assert.Equal(t, "price rounds down to zero at increment 0.01", plan.Skipped[0].Reason)
+	})
+	t.Run("NoPrice", func(t *testing.T) {
+		_, err := planRebalance(RebalanceTargets{Quote: "USD", Weights: weights}, accounts, products, map[ProductID]ProductTicker{})
+		assert.Error(t, err)
+	})
+}
+
+func TestRebalanceTargets_Validate(t *testing.T) {
+	one := decimal.NewFromInt(1)
+	assert.NoError(t, RebalanceTargets{Quote: "USD", Weights: map[CurrencyName]decimal.Decimal{"BTC": one}}.Validate())
+	assert.Error(t, RebalanceTargets{Weights: map[CurrencyName]decimal.Decimal{"BTC": one}}.Validate())
+	assert.Error(t, RebalanceTargets{Quote: "USD"}.Validate())
+	assert.Error(t, RebalanceTargets{Quote: "USD", Weights: map[CurrencyName]decimal.Decimal{"BTC": one.Neg()}}.Validate())
+	assert.Error(t, RebalanceTargets{Quote: "USD", Weights: map[CurrencyName]decimal.Decimal{"BTC": decimal.Zero}}.Validate())
+	assert.Error(t, RebalanceTargets{Quote: "USD", Weights: map[CurrencyName]decimal.Decimal{"BTC": one}, Type: "stop"}.Validate())
+	assert.Error(t, RebalanceTargets{Quote: "USD", Weights: map[CurrencyName]decimal.Decimal{"BTC": one}, FeeRate: one}.Validate())
+	assert.Error(t, RebalanceTargets{Quote: "USD", Weights: map[CurrencyName]decimal.Decimal{"BTC": one, "btc": one}}.Validate())
+}
+
+func TestClient_Rebalance(t *testing.T) {
+	ctx := context.Background()
+	d := decimal.RequireFromString
+	var api mockAPI
+	defer api.AssertExpectations(t)
+	api.On("Get", "/accounts/", mock.IsType(&[]Account{})).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]Account) = []Account{
+			{Currency: "USD", Available: d("100")},
+			{Currency: "BTC", Available: d("0.03")},
+		}
+	})
+	api.On("Get", "/products/", mock.IsType(&[]Product{})).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]Product) = []Product{{ID: "BTC-USD", BaseCurrency: "BTC", QuoteCurrency: "USD", BaseIncrement: d("0.001")}}
+	})
+	api.On("Get", "/products/BTC-USD/ticker", mock.IsType(&ProductTicker{})).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*ProductTicker).Price = d("10000")
+	})
+	api.On("Get", "/fees/", mock.IsType(&Fees{})).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*Fees).TakerFeeRate = d("0.005")
+	}).Once()
+	client := Client{api: &api}
+	plan, err := client.PlanRebalance(ctx, RebalanceTargets{Quote: "usd", Weights: map[CurrencyName]decimal.Decimal{"usd": d("3"), "btc": d("1")}})
+	require.NoError(t, err)
+	require.Len(t, plan.Trades, 1, "currencies are not case-sensitive")
+	assert.Equal(t, CurrencyName("USD"), plan.Quote)
+	sell := mock.MatchedBy(func(order MarketOrder) bool {
+		return order.ProductID == "BTC-USD" && order.Side == SideSell && order.Funds == nil && order.Size.String() == "0.02"
+	})
+	api.On("Post", "/orders/", sell, mock.IsType(&Order{})).Return(ErrDryRun).Once()
+	orders, err := client.Rebalance(ctx, plan)
+	assert.True(t, errors.Is(err, ErrDryRun))
+	assert.Empty(t, orders)
+
+	_, err = client.PlanRebalance(ctx, RebalanceTargets{Quote: "USD", Weights: map[CurrencyName]decimal.Decimal{"ETH": d("1")}, FeeRate: d("0.005")})
+	assert.EqualError(t, err, "no product ETH-USD trades ETH for USD")
+}
@@ -3,8 +3,12 @@ package coinbasepro
 import (
 	"encoding/json"
 	"errors"
+	"time"
 )
 
+// ErrReportExpired is returned by WaitForReport when a Report expires before it is ready.
+var ErrReportExpired = errors.New("report expired before it was ready")
+
 type ReportSpec struct {
 	AccountID string       `json:"account_id"`
 	EndDate   Time         `json:"end_date"`
@@ -87,3 +91,43 @@ const (
 	// ReportStatusReady indicates that the report is ready for download from `file_url`
 	ReportStatusReady ReportStatus = "ready"
 )
+
+// ReportBackoff controls the delay between polls of WaitForReport. The delay starts at Initial and is multiplied by
+// Multiplier after each poll, up to Max. A field that is not positive is taken from DefaultReportBackoff, so that a
+// partial ReportBackoff does not poll without delay.
+type ReportBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+}
+
+// DefaultReportBackoff polls after one second, doubling the delay up to thirty seconds.
+var DefaultReportBackoff = ReportBackoff{
+	Initial:    time.Second,
+	Max:        30 * time.Second,
+	Multiplier: 2,
+}
+
+func (r ReportBackoff) withDefaults() ReportBackoff {
+	if r.Initial <= 0 {
+		r.Initial = DefaultReportBackoff.Initial
+	}
+	if r.Max <= 0 {
+		r.Max = DefaultReportBackoff.Max
+	}
+	if r.Multiplier <= 0 {
+		r.Multiplier = DefaultReportBackoff.Multiplier
+	}
+	return r
+}
+
+func (r ReportBackoff) next(delay time.Duration) time.Duration {
+	next := time.Duration(float64(delay) * r.Multiplier)
+	if next > r.Max {
+		return r.Max
+	}
+	if next < r.Initial {
+		return r.Initial
+	}
+	return next
+}
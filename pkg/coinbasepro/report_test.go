@@ -3,6 +3,7 @@ package coinbasepro
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -92,3 +93,13 @@ func TestReportSpec(t *testing.T) {
 		})
 	})
 }
+
+func TestReportBackoff_next(t *testing.T) {
+	backoff := ReportBackoff{Initial: time.Second, Max: 3 * time.Second, Multiplier: 2}
+	assert.Equal(t, 2*time.Second, backoff.next(time.Second))
+	assert.Equal(t, 3*time.Second, backoff.next(2*time.Second))
+	partial := ReportBackoff{Initial: time.Millisecond}.withDefaults()
+	assert.Equal(t, 2*time.Millisecond, partial.next(time.Millisecond), "a zero multiplier is the default")
+	assert.Equal(t, DefaultReportBackoff.Max, partial.next(time.Minute), "a zero max is the default")
+	assert.Equal(t, DefaultReportBackoff, ReportBackoff{}.withDefaults())
+}
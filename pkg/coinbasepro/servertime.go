@@ -0,0 +1,135 @@
+package coinbasepro
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// clockSkewWarning is the skew from the server time beyond which the local clock is logged as wrong. The api rejects
+// requests signed more than 30 seconds from its time.
+const clockSkewWarning = 5 * time.Second
+
+// serverTimeTimeout limits each request for the server time.
+const serverTimeTimeout = 10 * time.Second
+
+// WithServerTime signs requests with the time of the server instead of the local clock. The skew of the local clock
+// is measured from ServerTime.Epoch when the client is created, and again at each interval until the client is
+// closed. Each skew is observed by the Metrics of the client and logged as a warning when it exceeds 5 seconds.
+func WithServerTime(interval time.Duration) Option {
+	return func(o *options) {
+		o.serverTime = interval
+	}
+}
+
+// serverClock is the local clock corrected by its skew from the server time.
+type serverClock struct {
+	mu         sync.Mutex
+	timeURL    string
+	httpClient *http.Client
+	local      func() time.Time
+	logger     Logger
+	metrics    Metrics
+	skew       time.Duration
+	stop       chan struct{}
+	once       sync.Once
+}
+
+// newServerClock measures the skew of the local clock from the server at baseURL, and measures it again at each
+// interval until closed. The local clock is used without correction until the server time can be read.
+func newServerClock(baseURL *url.URL, httpClient *http.Client, local func() time.Time, logger Logger, interval time.Duration) (*serverClock, error) {
+	timeURL, err := baseURL.Parse("/time")
+	if err != nil {
+		return nil, err
+	}
+	s := &serverClock{
+		timeURL:    timeURL.String(),
+		httpClient: httpClient,
+		local:      local,
+		logger:     loggerOr(logger),
+		stop:       make(chan struct{}),
+	}
+	s.update()
+	go s.run(interval)
+	return s, nil
+}
+
+// now is the local time corrected by the last measured skew.
+func (s *serverClock) now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.local().Add(s.skew)
+}
+
+func (s *serverClock) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.update()
+		}
+	}
+}
+
+// update measures the skew, keeping the last skew when the server time cannot be read.
+func (s *serverClock) update() {
+	ctx, cancel := context.WithTimeout(context.Background(), serverTimeTimeout)
+	defer cancel()
+	skew, err := s.measure(ctx)
+	if err != nil {
+		s.logger.Warn("server time cannot be read", "error", err)
+		return
+	}
+	s.mu.Lock()
+	s.skew = skew
+	metrics := observe(s.metrics)
+	s.mu.Unlock()
+	metrics.ObserveClockSkew(skew)
+	if skew > clockSkewWarning || skew < -clockSkewWarning {
+		s.logger.Warn("local clock is skewed from the server time", "skew", skew)
+	}
+}
+
+// measure compares the server time to the local time halfway through the request for it.
+func (s *serverClock) measure(ctx context.Context) (skew time.Duration, capture error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.timeURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Add("Accept", "application/json")
+	sent := s.local()
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { Capture(&capture, resp.Body.Close()) }()
+	received := s.local()
+	if resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("server time: %s", resp.Status)
+	}
+	var serverTime ServerTime
+	if err = json.NewDecoder(resp.Body).Decode(&serverTime); err != nil {
+		return 0, err
+	}
+	server := time.Unix(0, serverTime.Epoch.Shift(9).IntPart())
+	return server.Sub(sent.Add(received.Sub(sent) / 2)), nil
+}
+
+func (s *serverClock) instrument(metrics Metrics) {
+	s.mu.Lock()
+	s.metrics = metrics
+	skew := s.skew
+	s.mu.Unlock()
+	observe(metrics).ObserveClockSkew(skew)
+}
+
+func (s *serverClock) close() {
+	s.once.Do(func() { close(s.stop) })
+}
@@ -0,0 +1,66 @@
+package coinbasepro
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithServerTime(t *testing.T) {
+	local := time.Unix(1609459200, 0)
+	var mu sync.Mutex
+	epoch, timestamps := "1609459260.5", []string(nil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/time" {
+			_, _ = w.Write([]byte(`{"iso":"2021-01-01T00:01:00.5Z","epoch":` + epoch + `}`))
+			return
+		}
+		timestamps = append(timestamps, r.Header.Get("CB-ACCESS-TIMESTAMP"))
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	logger := &recordingLogger{}
+	client, err := NewClient(baseURL, baseURL, &Auth{Key: "k", Passphrase: "p", Secret: "zZ=="},
+		WithClock(func() time.Time { return local }),
+		WithLogger(logger),
+		WithServerTime(time.Hour),
+	)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, client.Close()) }()
+	metrics := &recordingMetrics{}
+	MetricsMode(client, metrics)
+
+	_, err = client.ListAccounts(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1609459260"}, timestamps, "requests are signed with the server time")
+	assert.Equal(t, []time.Duration{60500 * time.Millisecond}, metrics.skews, "the skew measured at start is observed once instrumented")
+	assert.Contains(t, logger.lines, "WARN local clock is skewed from the server time skew=1m0.5s")
+
+	api := client.api.(*APIClient)
+	logger.lines = nil
+	mu.Lock()
+	epoch = "oops"
+	mu.Unlock()
+	api.serverClock.update()
+	assert.Equal(t, time.Unix(1609459260, 500000000), api.serverClock.now(), "the last skew is kept when the server time cannot be read")
+	require.Len(t, logger.lines, 1)
+	assert.Contains(t, logger.lines[0], "WARN server time cannot be read")
+
+	mu.Lock()
+	epoch = "1609459201"
+	mu.Unlock()
+	api.serverClock.update()
+	assert.Equal(t, time.Second, metrics.skews[len(metrics.skews)-1])
+	assert.Len(t, logger.lines, 1, "a small skew is not a warning")
+}
@@ -0,0 +1,312 @@
+package coinbasepro
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracer starts a Span for each request to the api. It mirrors the OpenTelemetry trace.Tracer, so that a tracer of an
+// OpenTelemetry SDK can be adapted to it in a few lines, and its spans join the traces of the calling service through
+// the context.
+//
+// This is not an OpenTelemetry SDK. There is no sampling, batching, resource or OTLP export; the ExportingTracer only
+// hands finished Spans to a SpanExporter, and traces are propagated only by the W3C traceparent header. A request
+// carries the traceparent of the Span of its request, when the Span has a TraceParent method as the Spans of an
+// ExportingTracer do, or else the traceparent of its context, such as one set by ContextWithTraceParent.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an operation of a trace.
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key and value that describe a Span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// TracingMode traces the requests of the client with tracer.
+func TracingMode(client *Client, tracer Tracer) {
+	if api, ok := client.api.(instrumentable); ok {
+		api.trace(tracer)
+	}
+}
+
+func (a *APIClient) trace(tracer Tracer) {
+	a.tracer = tracer
+}
+
+func (d *DevelopmentClient) trace(tracer Tracer) {
+	d.tracer = tracer
+	d.api.trace(tracer)
+}
+
+func (d *DryRunClient) trace(tracer Tracer) {
+	if api, ok := d.api.(instrumentable); ok {
+		api.trace(tracer)
+	}
+}
+
+// startRequest starts the Span of a request, named by its method and endpoint, such as `GET /accounts/:id`. Without
+// a tracer, the Span does nothing.
+func startRequest(ctx context.Context, tracer Tracer, baseURL *url.URL, method string, relativePath string) (context.Context, requestSpan) {
+	if tracer == nil {
+		return ctx, requestSpan{noSpan{}}
+	}
+	route := endpoint(relativePath)
+	ctx, span := tracer.Start(ctx, method+" "+route)
+	if propagated, ok := span.(interface{ TraceParent() string }); ok {
+		if parent, err := parseTraceParent(propagated.TraceParent()); err == nil {
+			ctx = context.WithValue(ctx, traceParentKey{}, parent)
+		}
+	}
+	attributes := []Attribute{
+		{Key: "http.request.method", Value: method},
+		{Key: "http.route", Value: route},
+		{Key: "url.path", Value: strings.SplitN(relativePath, "?", 2)[0]},
+	}
+	if baseURL != nil {
+		attributes = append(attributes, Attribute{Key: "server.address", Value: baseURL.Host})
+	}
+	if i := strings.IndexByte(relativePath, '?'); i >= 0 {
+		query, _ := url.ParseQuery(relativePath[i+1:])
+		for _, param := range []string{"before", "after", "limit"} {
+			if value := query.Get(param); value != "" {
+				attributes = append(attributes, Attribute{Key: "coinbasepro.pagination." + param, Value: value})
+			}
+		}
+	}
+	span.SetAttributes(attributes...)
+	return ctx, requestSpan{span}
+}
+
+// requestSpan is the Span of a request to the api.
+type requestSpan struct {
+	Span
+}
+
+// finish ends the span with the status and page of the response, or the error of the request.
+func (s requestSpan) finish(resp *http.Response, err error) {
+	defer s.End()
+	var apiErr Error
+	switch {
+	case resp != nil:
+		s.SetAttributes(Attribute{Key: "http.response.status_code", Value: resp.StatusCode})
+		if isPaged(resp) {
+			s.SetAttributes(
+				Attribute{Key: "coinbasepro.page.before", Value: resp.Header.Get("CB-BEFORE")},
+				Attribute{Key: "coinbasepro.page.after", Value: resp.Header.Get("CB-AFTER")},
+			)
+		}
+	case errors.As(err, &apiErr):
+		s.SetAttributes(Attribute{Key: "http.response.status_code", Value: apiErr.StatusCode})
+	}
+	if err != nil {
+		s.RecordError(err)
+	}
+}
+
+type noSpan struct{}
+
+func (noSpan) SetAttributes(...Attribute) {}
+func (noSpan) RecordError(error)          {}
+func (noSpan) End()                       {}
+
+// SpanData is a finished Span, as exported by an ExportingTracer.
+type SpanData struct {
+	TraceID      string                 `json:"trace_id"`
+	SpanID       string                 `json:"span_id"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
+	Name         string                 `json:"name"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Errors       []string               `json:"errors,omitempty"`
+}
+
+// SpanExporter exports finished Spans.
+type SpanExporter interface {
+	ExportSpan(span SpanData) error
+}
+
+// ExportingTracer is a Tracer that passes each Span to its SpanExporter when it ends. Spans started within a Span of
+// the ExportingTracer are its children.
+type ExportingTracer struct {
+	exporter SpanExporter
+	logger   Logger
+}
+
+// NewExportingTracer creates an ExportingTracer that exports to exporter, and logs the Spans that cannot be exported.
+// Of the options, only WithLogger applies to an ExportingTracer.
+func NewExportingTracer(exporter SpanExporter, opts ...Option) *ExportingTracer {
+	return &ExportingTracer{
+		exporter: exporter,
+		logger:   loggerOr(newOptions(opts).logger),
+	}
+}
+
+// Start starts a Span that is the child of the Span of the context, whether of the ExportingTracer or of a caller
+// that set the traceparent of the context with ContextWithTraceParent, or else the root of a new trace.
+func (e *ExportingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &exportedSpan{
+		exporter: e.exporter,
+		logger:   e.logger,
+		data: SpanData{
+			TraceID:    randomID(16),
+			SpanID:     randomID(8),
+			Name:       name,
+			Start:      time.Now(),
+			Attributes: make(map[string]interface{}),
+		},
+		flags: "01",
+	}
+	if parent, ok := ctx.Value(traceParentKey{}).(traceParent); ok {
+		span.data.TraceID = parent.traceID
+		span.data.ParentSpanID = parent.spanID
+		span.flags = parent.flags
+	}
+	return context.WithValue(ctx, traceParentKey{}, traceParent{traceID: span.data.TraceID, spanID: span.data.SpanID, flags: span.flags}), span
+}
+
+type exportedSpan struct {
+	mu       sync.Mutex
+	exporter SpanExporter
+	logger   Logger
+	data     SpanData
+	flags    string
+	ended    bool
+}
+
+// TraceParent is the W3C traceparent of the Span.
+func (s *exportedSpan) TraceParent() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return traceParent{traceID: s.data.TraceID, spanID: s.data.SpanID, flags: s.flags}.String()
+}
+
+func (s *exportedSpan) SetAttributes(attributes ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, attribute := range attributes {
+		s.data.Attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s *exportedSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Errors = append(s.data.Errors, err.Error())
+}
+
+func (s *exportedSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	if err := s.exporter.ExportSpan(data); err != nil {
+		s.logger.Warn("span cannot be exported", "span", data.Name, "error", err)
+	}
+}
+
+type traceParentKey struct{}
+
+// traceParent identifies the Span that is the parent of the Spans and requests of a context, as the W3C traceparent
+// header does.
+type traceParent struct {
+	traceID string
+	spanID  string
+	flags   string
+}
+
+func (t traceParent) String() string {
+	return "00-" + t.traceID + "-" + t.spanID + "-" + t.flags
+}
+
+// parseTraceParent parses a traceparent header of version 00, or of a later version that extends it.
+func parseTraceParent(header string) (traceParent, error) {
+	fields := strings.Split(strings.TrimSpace(header), "-")
+	if len(fields) < 4 || !isHex(fields[0], 2) || fields[0] == "ff" || fields[0] == "00" && len(fields) != 4 {
+		return traceParent{}, fmt.Errorf("traceparent %q is not valid", header)
+	}
+	parent := traceParent{traceID: fields[1], spanID: fields[2], flags: fields[3]}
+	if !isHex(parent.traceID, 32) || parent.traceID == strings.Repeat("0", 32) ||
+		!isHex(parent.spanID, 16) || parent.spanID == strings.Repeat("0", 16) || !isHex(parent.flags, 2) {
+		return traceParent{}, fmt.Errorf("traceparent %q is not valid", header)
+	}
+	return parent, nil
+}
+
+// isHex indicates whether s is size lowercase hex digits.
+func isHex(s string, size int) bool {
+	if len(s) != size {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ContextWithTraceParent returns a context whose requests, and the Spans of an ExportingTracer, join the trace of the
+// W3C traceparent header of a caller, such as that of an incoming http request.
+func ContextWithTraceParent(ctx context.Context, header string) (context.Context, error) {
+	parent, err := parseTraceParent(header)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, traceParentKey{}, parent), nil
+}
+
+// TraceParent returns the W3C traceparent header of the context, or an empty string when the context is not part of a
+// trace.
+func TraceParent(ctx context.Context) string {
+	if parent, ok := ctx.Value(traceParentKey{}).(traceParent); ok {
+		return parent.String()
+	}
+	return ""
+}
+
+func randomID(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// JSONSpanExporter writes each Span as a line of json.
+type JSONSpanExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONSpanExporter creates a JSONSpanExporter that writes to w.
+func NewJSONSpanExporter(w io.Writer) *JSONSpanExporter {
+	return &JSONSpanExporter{w: w}
+}
+
+func (j *JSONSpanExporter) ExportSpan(span SpanData) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return json.NewEncoder(j.w).Encode(span)
+}
@@ -0,0 +1,179 @@
+package coinbasepro
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracingMode(t *testing.T) {
+	var traceParents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParents = append(traceParents, r.Header.Get("traceparent"))
+		if r.URL.Path == "/orders/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"NotFound"}`))
+			return
+		}
+		w.Header().Set("CB-BEFORE", "b")
+		w.Header().Set("CB-AFTER", "a")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client, err := NewClient(baseURL, baseURL, &Auth{Key: "k", Passphrase: "p", Secret: "zZ=="})
+	require.NoError(t, err)
+	exporter := &recordingExporter{}
+	tracer := NewExportingTracer(exporter)
+	TracingMode(client, tracer)
+
+	ctx, parent := tracer.Start(context.Background(), "trade")
+	_, err = client.GetOrders(ctx, OrderFilter{}, PaginationParams{After: "x", Limit: 10})
+	require.NoError(t, err)
+	_, err = client.GetOrder(ctx, "missing")
+	require.Error(t, err)
+	parent.End()
+
+	require.Len(t, exporter.spans, 3)
+	orders, missing, trade := exporter.spans[0], exporter.spans[1], exporter.spans[2]
+	assert.Equal(t, "GET /orders/", orders.Name)
+	assert.Equal(t, trade.TraceID, orders.TraceID, "a request joins the trace of its context")
+	assert.Equal(t, trade.SpanID, orders.ParentSpanID)
+	assert.Equal(t, []string{"00-" + trade.TraceID + "-" + orders.SpanID + "-01", "00-" + trade.TraceID + "-" + missing.SpanID + "-01"},
+		traceParents, "a request carries the traceparent of its span")
+	assert.Equal(t, map[string]interface{}{
+		"http.request.method":          "GET",
+		"http.route":                   "/orders/",
+		"url.path":                     "/orders/",
+		"server.address":               baseURL.Host,
+		"coinbasepro.pagination.after": "x",
+		"coinbasepro.pagination.limit": "10",
+		"http.response.status_code":    200,
+		"coinbasepro.page.before":      "b",
+		"coinbasepro.page.after":       "a",
+	}, orders.Attributes)
+	assert.Empty(t, orders.Errors)
+
+	assert.Equal(t, "GET /orders/missing", missing.Name, "a lowercase id cannot be told from a static segment")
+	assert.Equal(t, 404, missing.Attributes["http.response.status_code"])
+	assert.Equal(t, []string{"NotFound (404)"}, missing.Errors)
+}
+
+func TestContextWithTraceParent(t *testing.T) {
+	var traceParents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParents = append(traceParents, r.Header.Get("traceparent"))
+		_, _ = w.Write([]byte(`{"iso":"2021-01-01T00:00:00Z","epoch":1609459200}`))
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client, err := NewClient(baseURL, baseURL, &Auth{Key: "k", Passphrase: "p", Secret: "zZ=="})
+	require.NoError(t, err)
+	caller := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx, err := ContextWithTraceParent(context.Background(), caller)
+	require.NoError(t, err)
+	assert.Equal(t, caller, TraceParent(ctx))
+
+	_, err = client.GetServerTime(ctx)
+	require.NoError(t, err)
+	exporter := &recordingExporter{}
+	TracingMode(client, NewExportingTracer(exporter))
+	_, err = client.GetServerTime(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, exporter.spans, 1)
+	span := exporter.spans[0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID, "a span joins the trace of the caller")
+	assert.Equal(t, "00f067aa0ba902b7", span.ParentSpanID)
+	assert.Equal(t, []string{caller, "00-4bf92f3577b34da6a3ce929d0e0e4736-" + span.SpanID + "-01"}, traceParents,
+		"a request without a tracer carries the traceparent of the caller")
+	assert.Empty(t, TraceParent(context.Background()))
+}
+
+func TestParseTraceParent(t *testing.T) {
+	for header, valid := range map[string]bool{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00":        true,
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future": true,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future": false,
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":        false,
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01":        false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":        false,
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01":        false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7":           false,
+		"": false,
+	} {
+		_, err := parseTraceParent(header)
+		assert.Equal(t, valid, err == nil, header)
+	}
+}
+
+func TestTracingMode_DevelopmentClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"iso":"2021-01-01T00:00:00Z","epoch":1609459200}`))
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	api, err := NewAPIClient(baseURL, baseURL, &Auth{Key: "k", Passphrase: "p", Secret: "zZ=="})
+	require.NoError(t, err)
+	dev, err := NewDevelopmentClient(api, afero.NewMemMapFs())
+	require.NoError(t, err)
+	client := &Client{api: dev}
+	exporter := &recordingExporter{}
+	TracingMode(client, NewExportingTracer(exporter))
+
+	_, err = client.GetServerTime(context.Background())
+	require.NoError(t, err)
+	require.Len(t, exporter.spans, 1, "the development client traces its requests once")
+	assert.Equal(t, "GET /time", exporter.spans[0].Name)
+	assert.Equal(t, 200, exporter.spans[0].Attributes["http.response.status_code"])
+}
+
+func TestJSONSpanExporter(t *testing.T) {
+	var b bytes.Buffer
+	_, span := NewExportingTracer(NewJSONSpanExporter(&b)).Start(context.Background(), "GET /time")
+	span.SetAttributes(Attribute{Key: "http.route", Value: "/time"})
+	span.End()
+	span.End()
+
+	var data SpanData
+	require.NoError(t, json.Unmarshal(b.Bytes(), &data))
+	assert.Equal(t, "GET /time", data.Name)
+	assert.Len(t, data.TraceID, 32)
+	assert.Len(t, data.SpanID, 16)
+	assert.Equal(t, "/time", data.Attributes["http.route"])
+	assert.Equal(t, 1, bytes.Count(b.Bytes(), []byte("\n")), "a span is exported once")
+}
+
+func TestExportingTracer_Logger(t *testing.T) {
+	logger := &recordingLogger{}
+	_, span := NewExportingTracer(failingExporter{}, WithLogger(logger)).Start(context.Background(), "GET /time")
+	span.End()
+	assert.Equal(t, []string{"WARN span cannot be exported span=GET /time error=closed"}, logger.lines)
+}
+
+type failingExporter struct{}
+
+func (failingExporter) ExportSpan(SpanData) error {
+	return errors.New("closed")
+}
+
+type recordingExporter struct {
+	spans []SpanData
+}
+
+func (r *recordingExporter) ExportSpan(span SpanData) error {
+	r.spans = append(r.spans, span)
+	return nil
+}
@@ -7,7 +7,20 @@ func NewFeed() Feed {
 	}
 }
 
+// NewBlockingFeed creates a Feed that buffers messages and is never dropped from: Watch waits for room on Messages
+// instead, so that a consumer that falls behind holds up the feed rather than losing messages.
+func NewBlockingFeed(buffer int) Feed {
+	return Feed{
+		Subscriptions: make(chan SubscriptionRequest, 1),
+		Messages:      make(chan interface{}, buffer),
+		Block:         true,
+	}
+}
+
 type Feed struct {
 	Subscriptions chan SubscriptionRequest
 	Messages      chan interface{}
+	// Block makes Watch wait for the consumer of Messages, instead of dropping a message the consumer is not ready
+	// to receive.
+	Block bool
 }
@@ -0,0 +1,302 @@
+// Package costbasis tracks tax lots acquired and disposed of through Coinbase Pro Fills, Deposits and Withdrawals and
+// computes realized and unrealized profit and loss per Product.
+//
+// A Book holds the open Lots of each Product. Events must be applied to the Book in chronological order: a buy Fill
+// or a Deposit opens a Lot, a sell Fill disposes of Lots and realizes a gain or loss, and a Withdrawal removes Lots
+// without realizing anything. The Method of the Book selects which Lots are disposed of first.
+//
+// All amounts are expressed in the quote Currency of the Product. Fees paid on a buy are added to the basis of the
+// Lot and fees paid on a sell are deducted from the proceeds.
+package costbasis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+)
+
+// Method selects the Lots that are disposed of first.
+type Method string
+
+const (
+	// MethodFIFO disposes of the earliest acquired Lots first.
+	MethodFIFO Method = "fifo"
+	// MethodLIFO disposes of the latest acquired Lots first.
+	MethodLIFO Method = "lifo"
+	// MethodHIFO disposes of the Lots with the highest unit cost first.
+	MethodHIFO Method = "hifo"
+	// MethodAverage disposes of Lots in FIFO order, for acquisition dates, but assigns every disposed unit the average
+	// unit cost of all open Lots.
+	MethodAverage Method = "average"
+)
+
+func (m Method) Validate() error {
+	switch m {
+	case MethodFIFO, MethodLIFO, MethodHIFO, MethodAverage:
+		return nil
+	default:
+		return fmt.Errorf("method(%q) is not one of [ fifo, lifo, hifo, average ]", m)
+	}
+}
+
+// Lot is a quantity of the base Currency of a Product acquired at a single time and cost.
+type Lot struct {
+	// ID identifies the Fill or Deposit that opened the Lot
+	ID        string                `json:"id"`
+	ProductID coinbasepro.ProductID `json:"product_id"`
+	Source    EventType             `json:"source"`
+	Acquired  time.Time             `json:"acquired"`
+	// Size is the quantity originally acquired
+	Size decimal.Decimal `json:"size"`
+	// Remaining is the quantity still held
+	Remaining decimal.Decimal `json:"remaining"`
+	// Cost is the basis of the originally acquired Size, including fees
+	Cost decimal.Decimal `json:"cost"`
+}
+
+// UnitCost is the basis of a single unit of the Lot.
+func (l *Lot) UnitCost() decimal.Decimal {
+	if l.Size.IsZero() {
+		return decimal.Zero
+	}
+	return l.Cost.Div(l.Size)
+}
+
+// Basis is the basis of the Remaining quantity of the Lot.
+func (l *Lot) Basis() decimal.Decimal {
+	return l.Remaining.Mul(l.UnitCost())
+}
+
+// Disposal records the sale of all or part of a Lot.
+type Disposal struct {
+	ProductID coinbasepro.ProductID `json:"product_id"`
+	// LotID is empty when more was sold than the Book held; such a Disposal has no Basis
+	LotID  string `json:"lot_id"`
+	SaleID string `json:"sale_id"`
+	// Source is the EventType that opened the Lot
+	Source   EventType       `json:"source,omitempty"`
+	Acquired time.Time       `json:"acquired"`
+	Disposed time.Time       `json:"disposed"`
+	Size     decimal.Decimal `json:"size"`
+	Proceeds decimal.Decimal `json:"proceeds"`
+	Basis    decimal.Decimal `json:"basis"`
+	Gain     decimal.Decimal `json:"gain"`
+}
+
+// Unmatched indicates that the Disposal sold more than the Book held, usually because history is incomplete.
+func (d Disposal) Unmatched() bool {
+	return d.LotID == ""
+}
+
+// Book tracks the open Lots and Disposals of each Product.
+type Book struct {
+	method    Method
+	lots      map[coinbasepro.ProductID][]*Lot
+	disposals []Disposal
+	fees      map[coinbasepro.ProductID]decimal.Decimal
+	last      time.Time
+}
+
+// NewBook creates an empty Book that disposes of Lots according to the Method.
+func NewBook(method Method) (*Book, error) {
+	if err := method.Validate(); err != nil {
+		return nil, err
+	}
+	return &Book{
+		method: method,
+		lots:   make(map[coinbasepro.ProductID][]*Lot),
+		fees:   make(map[coinbasepro.ProductID]decimal.Decimal),
+	}, nil
+}
+
+// Apply records the Event in the Book. Events must be applied in chronological order.
+func (b *Book) Apply(event Event) error {
+	if err := event.Validate(); err != nil {
+		return err
+	}
+	if event.Time.Before(b.last) {
+		return fmt.Errorf("event %q at %s is before previously applied event at %s", event.ID, event.Time, b.last)
+	}
+	b.last = event.Time
+	b.fees[event.ProductID] = b.fees[event.ProductID].Add(event.Fee)
+	switch event.Type {
+	case EventTypeBuy, EventTypeDeposit:
+		b.lots[event.ProductID] = append(b.lots[event.ProductID], &Lot{
+			ID:        event.ID,
+			ProductID: event.ProductID,
+			Source:    event.Type,
+			Acquired:  event.Time,
+			Size:      event.Size,
+			Remaining: event.Size,
+			Cost:      event.Size.Mul(event.Price).Add(event.Fee),
+		})
+	case EventTypeSell:
+		proceeds := event.Size.Mul(event.Price).Sub(event.Fee)
+		b.dispose(event, proceeds)
+	case EventTypeWithdrawal:
+		b.remove(event.ProductID, event.Size)
+	}
+	return nil
+}
+
+// ApplyAll applies the Events in chronological order. The Events are not modified.
+func (b *Book) ApplyAll(events []Event) error {
+	sorted := make([]Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+	for _, event := range sorted {
+		if err := b.Apply(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lots returns the open Lots of the Product, in acquisition order.
+func (b *Book) Lots(productID coinbasepro.ProductID) []*Lot {
+	return b.lots[productID]
+}
+
+// Disposals returns every Disposal recorded by the Book, in the order they occurred.
+func (b *Book) Disposals() []Disposal {
+	return b.disposals
+}
+
+// ProductIDs returns the Products with Lots or Disposals in the Book, sorted.
+func (b *Book) ProductIDs() []coinbasepro.ProductID {
+	seen := make(map[coinbasepro.ProductID]bool)
+	for productID := range b.lots {
+		seen[productID] = true
+	}
+	for _, disposal := range b.disposals {
+		seen[disposal.ProductID] = true
+	}
+	productIDs := make([]coinbasepro.ProductID, 0, len(seen))
+	for productID := range seen {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+	return productIDs
+}
+
+// dispose sells event.Size from the open Lots, splitting the proceeds between Lots in proportion to the size sold
+// from each.
+func (b *Book) dispose(event Event, proceeds decimal.Decimal) {
+	remaining := event.Size
+	unitProceeds := proceeds.Div(event.Size)
+	averageCost := b.averageCost(event.ProductID)
+	defer b.average(event.ProductID, averageCost)
+	for _, lot := range b.ordered(event.ProductID) {
+		if !remaining.IsPositive() {
+			break
+		}
+		size := decimal.Min(remaining, lot.Remaining)
+		unitCost := lot.UnitCost()
+		if b.method == MethodAverage {
+			unitCost = averageCost
+		}
+		disposal := Disposal{
+			ProductID: event.ProductID,
+			LotID:     lot.ID,
+			SaleID:    event.ID,
+			Source:    lot.Source,
+			Acquired:  lot.Acquired,
+			Disposed:  event.Time,
+			Size:      size,
+			Proceeds:  size.Mul(unitProceeds),
+			Basis:     size.Mul(unitCost),
+		}
+		disposal.Gain = disposal.Proceeds.Sub(disposal.Basis)
+		b.disposals = append(b.disposals, disposal)
+		lot.Remaining = lot.Remaining.Sub(size)
+		remaining = remaining.Sub(size)
+	}
+	b.prune(event.ProductID)
+	if remaining.IsPositive() {
+		disposal := Disposal{
+			ProductID: event.ProductID,
+			SaleID:    event.ID,
+			Disposed:  event.Time,
+			Size:      remaining,
+			Proceeds:  remaining.Mul(unitProceeds),
+		}
+		disposal.Gain = disposal.Proceeds
+		b.disposals = append(b.disposals, disposal)
+	}
+}
+
+// remove takes size out of the open Lots, in Method order, without realizing any gain or loss.
+func (b *Book) remove(productID coinbasepro.ProductID, size decimal.Decimal) {
+	remaining := size
+	averageCost := b.averageCost(productID)
+	defer b.average(productID, averageCost)
+	for _, lot := range b.ordered(productID) {
+		if !remaining.IsPositive() {
+			break
+		}
+		removed := decimal.Min(remaining, lot.Remaining)
+		lot.Remaining = lot.Remaining.Sub(removed)
+		remaining = remaining.Sub(removed)
+	}
+	b.prune(productID)
+}
+
+// ordered returns the open Lots of the Product in the order they are disposed of.
+func (b *Book) ordered(productID coinbasepro.ProductID) []*Lot {
+	lots := make([]*Lot, len(b.lots[productID]))
+	copy(lots, b.lots[productID])
+	switch b.method {
+	case MethodLIFO:
+		for i, j := 0, len(lots)-1; i < j; i, j = i+1, j-1 {
+			lots[i], lots[j] = lots[j], lots[i]
+		}
+	case MethodHIFO:
+		sort.SliceStable(lots, func(i, j int) bool {
+			return lots[i].UnitCost().GreaterThan(lots[j].UnitCost())
+		})
+	}
+	return lots
+}
+
+func (b *Book) averageCost(productID coinbasepro.ProductID) decimal.Decimal {
+	var size, basis decimal.Decimal
+	for _, lot := range b.lots[productID] {
+		size = size.Add(lot.Remaining)
+		basis = basis.Add(lot.Basis())
+	}
+	if size.IsZero() {
+		return decimal.Zero
+	}
+	return basis.Div(size)
+}
+
+// average gives every open Lot the same averageCost after a MethodAverage disposal or removal, so that the average
+// unit cost of the Lots that remain is unchanged.
+func (b *Book) average(productID coinbasepro.ProductID, averageCost decimal.Decimal) {
+	if b.method != MethodAverage {
+		return
+	}
+	for _, lot := range b.lots[productID] {
+		lot.Cost = lot.Size.Mul(averageCost)
+	}
+}
+
+// prune drops Lots that have been completely disposed of.
+func (b *Book) prune(productID coinbasepro.ProductID) {
+	open := b.lots[productID][:0]
+	for _, lot := range b.lots[productID] {
+		if lot.Remaining.IsPositive() {
+			open = append(open, lot)
+		}
+	}
+	if len(open) == 0 {
+		delete(b.lots, productID)
+		return
+	}
+	b.lots[productID] = open
+}
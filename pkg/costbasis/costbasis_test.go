@@ -0,0 +1,142 @@
+package costbasis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBook(t *testing.T) {
+	// buy 1 @ 100, buy 1 @ 300, buy 1 @ 200, sell 2 @ 400
+	events := []Event{
+		event("1", EventTypeBuy, 1, 1, 100),
+		event("2", EventTypeBuy, 2, 1, 300),
+		event("3", EventTypeBuy, 3, 1, 200),
+		event("4", EventTypeSell, 4, 2, 400),
+	}
+	tests := []struct {
+		method   Method
+		realized string
+		basis    string
+		lots     []string
+	}{
+		{method: MethodFIFO, realized: "400", basis: "200", lots: []string{"3"}},
+		{method: MethodLIFO, realized: "300", basis: "100", lots: []string{"1"}},
+		{method: MethodHIFO, realized: "300", basis: "100", lots: []string{"1"}},
+		{method: MethodAverage, realized: "400", basis: "200", lots: []string{"3"}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.method), func(t *testing.T) {
+			book, err := NewBook(test.method)
+			require.NoError(t, err)
+			require.NoError(t, book.ApplyAll(events))
+			report := book.Report(map[coinbasepro.ProductID]decimal.Decimal{"BTC-USD": decimal.NewFromInt(500)})
+			require.Len(t, report.Products, 1)
+			product := report.Products[0]
+			assert.Equal(t, "1", product.Held.String())
+			assert.Equal(t, test.realized, product.Realized.String())
+			assert.Equal(t, test.basis, product.Basis.String())
+			assert.Equal(t, "500", product.MarketValue.String())
+			assert.Equal(t, decimal.NewFromInt(500).Sub(product.Basis).String(), product.Unrealized.String())
+			var lots []string
+			for _, lot := range book.Lots("BTC-USD") {
+				lots = append(lots, lot.ID)
+			}
+			assert.Equal(t, test.lots, lots)
+		})
+	}
+	t.Run("Fees", func(t *testing.T) {
+		book, err := NewBook(MethodFIFO)
+		require.NoError(t, err)
+		buy := event("1", EventTypeBuy, 1, 2, 100)
+		buy.Fee = decimal.NewFromInt(2)
+		sell := event("2", EventTypeSell, 2, 1, 150)
+		sell.Fee = decimal.NewFromInt(1)
+		require.NoError(t, book.ApplyAll([]Event{buy, sell}))
+		disposals := book.Disposals()
+		require.Len(t, disposals, 1)
+		assert.Equal(t, "149", disposals[0].Proceeds.String())
+		assert.Equal(t, "101", disposals[0].Basis.String())
+		assert.Equal(t, "48", disposals[0].Gain.String())
+		report := book.Report(nil)
+		assert.Equal(t, "3", report.Totals.Fees.String())
+		assert.Equal(t, []coinbasepro.ProductID{"BTC-USD"}, report.Unpriced)
+	})
+	t.Run("Withdrawal", func(t *testing.T) {
+		book, err := NewBook(MethodFIFO)
+		require.NoError(t, err)
+		require.NoError(t, book.ApplyAll([]Event{
+			event("1", EventTypeDeposit, 1, 2, 0),
+			event("2", EventTypeWithdrawal, 2, 1, 0),
+		}))
+		lots := book.Lots("BTC-USD")
+		require.Len(t, lots, 1)
+		assert.Equal(t, "1", lots[0].Remaining.String())
+		assert.Empty(t, book.Disposals())
+	})
+	t.Run("Unmatched", func(t *testing.T) {
+		book, err := NewBook(MethodFIFO)
+		require.NoError(t, err)
+		require.NoError(t, book.ApplyAll([]Event{
+			event("1", EventTypeBuy, 1, 1, 100),
+			event("2", EventTypeSell, 2, 3, 150),
+		}))
+		disposals := book.Disposals()
+		require.Len(t, disposals, 2)
+		assert.False(t, disposals[0].Unmatched())
+		assert.True(t, disposals[1].Unmatched())
+		assert.Equal(t, "300", disposals[1].Gain.String())
+		assert.Empty(t, book.Lots("BTC-USD"))
+	})
+	t.Run("OutOfOrder", func(t *testing.T) {
+		book, err := NewBook(MethodFIFO)
+		require.NoError(t, err)
+		require.NoError(t, book.Apply(event("2", EventTypeBuy, 2, 1, 100)))
+		assert.Error(t, book.Apply(event("1", EventTypeBuy, 1, 1, 100)))
+	})
+	t.Run("InvalidMethod", func(t *testing.T) {
+		_, err := NewBook("random")
+		assert.Error(t, err)
+	})
+}
+
+func TestEvents(t *testing.T) {
+	completed := coinbasepro.Time(time.Unix(3, 0))
+	fills := []*coinbasepro.Fill{
+		{TradeID: 1, ProductID: "BTC-USD", Side: coinbasepro.SideSell, Size: decimal.NewFromInt(1), Price: decimal.NewFromInt(10)},
+		{TradeID: 2, ProductID: "XLM-BTC", Side: coinbasepro.SideBuy, Size: decimal.NewFromInt(1)},
+	}
+	deposits := []*coinbasepro.Deposit{
+		{ID: "d1", Currency: "BTC", Amount: decimal.NewFromInt(2), CompletedAt: &completed},
+		{ID: "d2", Currency: "USD", Amount: decimal.NewFromInt(2), CompletedAt: &completed},
+		{ID: "d3", Currency: "BTC", Amount: decimal.NewFromInt(2)},
+	}
+	withdrawals := []*coinbasepro.Withdrawal{
+		{ID: "w1", Currency: "ETH", Amount: decimal.NewFromInt(1), CompletedAt: &completed},
+		{ID: "w2", Currency: "ETH", Amount: decimal.NewFromInt(1), CompletedAt: &completed, CanceledAt: &completed},
+	}
+	events := Events(fills, deposits, withdrawals, "USD")
+	require.Len(t, events, 3)
+	assert.Equal(t, "1", events[0].ID)
+	assert.Equal(t, EventTypeSell, events[0].Type)
+	assert.Equal(t, coinbasepro.ProductID("BTC-USD"), events[1].ProductID)
+	assert.Equal(t, EventTypeDeposit, events[1].Type)
+	assert.Equal(t, time.Unix(3, 0), events[1].Time)
+	assert.Equal(t, coinbasepro.ProductID("ETH-USD"), events[2].ProductID)
+	assert.Equal(t, EventTypeWithdrawal, events[2].Type)
+}
+
+func event(id string, eventType EventType, at int64, size int64, price int64) Event {
+	return Event{
+		ID:        id,
+		ProductID: "BTC-USD",
+		Type:      eventType,
+		Time:      time.Unix(at, 0),
+		Size:      decimal.NewFromInt(size),
+		Price:     decimal.NewFromInt(price),
+	}
+}
@@ -0,0 +1,147 @@
+package costbasis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+)
+
+// EventType identifies how an Event changes the Lots of a Book.
+type EventType string
+
+const (
+	// EventTypeBuy opens a Lot at the Event Price plus Fee.
+	EventTypeBuy EventType = "buy"
+	// EventTypeSell disposes of Lots for the Event Price less Fee.
+	EventTypeSell EventType = "sell"
+	// EventTypeDeposit opens a Lot at the Event Price, which is zero unless the basis of the deposited funds is known.
+	EventTypeDeposit EventType = "deposit"
+	// EventTypeWithdrawal removes Lots without realizing a gain or loss.
+	EventTypeWithdrawal EventType = "withdrawal"
+)
+
+func (e EventType) Validate() error {
+	switch e {
+	case EventTypeBuy, EventTypeSell, EventTypeDeposit, EventTypeWithdrawal:
+		return nil
+	default:
+		return fmt.Errorf("event type(%q) is not one of [ buy, sell, deposit, withdrawal ]", e)
+	}
+}
+
+// Event is a single change to the holdings of the base Currency of a Product.
+type Event struct {
+	// ID identifies the Fill, Deposit or Withdrawal that caused the Event
+	ID        string                `json:"id"`
+	ProductID coinbasepro.ProductID `json:"product_id"`
+	Type      EventType             `json:"type"`
+	Time      time.Time             `json:"time"`
+	// Size is the quantity of the base Currency
+	Size decimal.Decimal `json:"size"`
+	// Price per unit of the base Currency in the quote Currency
+	Price decimal.Decimal `json:"price"`
+	// Fee in the quote Currency
+	Fee decimal.Decimal `json:"fee"`
+}
+
+func (e Event) Validate() error {
+	if err := e.Type.Validate(); err != nil {
+		return err
+	}
+	if !e.Size.IsPositive() {
+		return fmt.Errorf("event %q size(%s) must be positive", e.ID, e.Size)
+	}
+	if e.Price.IsNegative() || e.Fee.IsNegative() {
+		return fmt.Errorf("event %q price(%s) and fee(%s) must not be negative", e.ID, e.Price, e.Fee)
+	}
+	return nil
+}
+
+// FillEvent converts a Fill into a buy or sell Event.
+func FillEvent(fill *coinbasepro.Fill) Event {
+	eventType := EventTypeBuy
+	if fill.Side == coinbasepro.SideSell {
+		eventType = EventTypeSell
+	}
+	return Event{
+		ID:        strconv.FormatInt(fill.TradeID, 10),
+		ProductID: fill.ProductID,
+		Type:      eventType,
+		Time:      fill.CreatedAt.Time(),
+		Size:      fill.Size,
+		Price:     fill.Price,
+		Fee:       fill.Fee,
+	}
+}
+
+// Events converts Fills, Deposits and Withdrawals into the Events of Products quoted in the quote Currency. Fills of
+// Products with another quote Currency are skipped, as are transfers of the quote Currency itself and transfers that
+// were canceled or have not completed. A Deposit or Withdrawal of a Currency is recorded against the Product formed
+// with the quote Currency, such as BTC-USD for a BTC Deposit with a USD quote.
+func Events(fills []*coinbasepro.Fill, deposits []*coinbasepro.Deposit, withdrawals []*coinbasepro.Withdrawal, quote coinbasepro.CurrencyName) []Event {
+	var events []Event
+	suffix := "-" + string(quote)
+	for _, fill := range fills {
+		if !strings.HasSuffix(string(fill.ProductID), suffix) {
+			continue
+		}
+		events = append(events, FillEvent(fill))
+	}
+	for _, deposit := range deposits {
+		if deposit.Currency == quote || deposit.CanceledAt != nil || deposit.CompletedAt == nil {
+			continue
+		}
+		events = append(events, Event{
+			ID:        deposit.ID,
+			ProductID: coinbasepro.ProductID(string(deposit.Currency) + suffix),
+			Type:      EventTypeDeposit,
+			Time:      deposit.CompletedAt.Time(),
+			Size:      deposit.Amount,
+		})
+	}
+	for _, withdrawal := range withdrawals {
+		if withdrawal.Currency == quote || withdrawal.CanceledAt != nil || withdrawal.CompletedAt == nil {
+			continue
+		}
+		events = append(events, Event{
+			ID:        withdrawal.ID,
+			ProductID: coinbasepro.ProductID(string(withdrawal.Currency) + suffix),
+			Type:      EventTypeWithdrawal,
+			Time:      withdrawal.CompletedAt.Time(),
+			Size:      withdrawal.Amount,
+		})
+	}
+	return events
+}
+
+// LedgerEvents converts the transfer LedgerEntries of an Account holding the currency into Deposit and Withdrawal
+// Events of the Product formed with the quote Currency. A positive Amount is a Deposit and a negative Amount is a
+// Withdrawal. LedgerEntries of any other type are skipped, as they are already accounted for by Fills.
+func LedgerEvents(currency coinbasepro.CurrencyName, entries []*coinbasepro.LedgerEntry, quote coinbasepro.CurrencyName) []Event {
+	if currency == quote {
+		return nil
+	}
+	var events []Event
+	productID := coinbasepro.ProductID(string(currency) + "-" + string(quote))
+	for _, entry := range entries {
+		if entry.Type != coinbasepro.LedgerEntryTypeTransfer || entry.Amount.IsZero() {
+			continue
+		}
+		event := Event{
+			ID:        entry.ID,
+			ProductID: productID,
+			Type:      EventTypeDeposit,
+			Time:      entry.CreatedAt.Time(),
+			Size:      entry.Amount.Abs(),
+		}
+		if entry.Amount.IsNegative() {
+			event.Type = EventTypeWithdrawal
+		}
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,82 @@
+package costbasis
+
+import (
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+)
+
+// Report summarizes realized and unrealized profit and loss for every Product in a Book.
+type Report struct {
+	Method   Method                  `json:"method"`
+	Products []*ProductReport        `json:"products"`
+	Totals   ProductReportTotals     `json:"totals"`
+	Unpriced []coinbasepro.ProductID `json:"unpriced,omitempty"`
+}
+
+// ProductReport is the profit and loss of a single Product.
+type ProductReport struct {
+	ProductID coinbasepro.ProductID `json:"product_id"`
+	// Held is the quantity of the base Currency in open Lots
+	Held decimal.Decimal `json:"held"`
+	// Basis is the basis of the open Lots
+	Basis decimal.Decimal `json:"basis"`
+	// Price is the market price used to value the open Lots, zero when unknown
+	Price decimal.Decimal `json:"price"`
+	// MarketValue is Held at Price
+	MarketValue decimal.Decimal `json:"market_value"`
+	// Proceeds of every Disposal, net of fees
+	Proceeds decimal.Decimal `json:"proceeds"`
+	// Realized is the gain or loss of every Disposal
+	Realized decimal.Decimal `json:"realized"`
+	// Unrealized is the gain or loss of the open Lots at Price
+	Unrealized decimal.Decimal `json:"unrealized"`
+	// Fees paid on every buy and sell
+	Fees decimal.Decimal `json:"fees"`
+}
+
+// ProductReportTotals sums the ProductReports of a Report.
+type ProductReportTotals struct {
+	Basis       decimal.Decimal `json:"basis"`
+	MarketValue decimal.Decimal `json:"market_value"`
+	Proceeds    decimal.Decimal `json:"proceeds"`
+	Realized    decimal.Decimal `json:"realized"`
+	Unrealized  decimal.Decimal `json:"unrealized"`
+	Fees        decimal.Decimal `json:"fees"`
+}
+
+// Report values the open Lots of the Book at prices and sums the Disposals of each Product. Products with open Lots
+// but no price are listed as Unpriced and report no MarketValue or Unrealized gain.
+func (b *Book) Report(prices map[coinbasepro.ProductID]decimal.Decimal) Report {
+	report := Report{Method: b.method}
+	products := make(map[coinbasepro.ProductID]*ProductReport)
+	for _, productID := range b.ProductIDs() {
+		product := ProductReport{ProductID: productID, Fees: b.fees[productID]}
+		for _, lot := range b.lots[productID] {
+			product.Held = product.Held.Add(lot.Remaining)
+			product.Basis = product.Basis.Add(lot.Basis())
+		}
+		if price, ok := prices[productID]; ok {
+			product.Price = price
+			product.MarketValue = product.Held.Mul(price)
+			product.Unrealized = product.MarketValue.Sub(product.Basis)
+		} else if product.Held.IsPositive() {
+			report.Unpriced = append(report.Unpriced, productID)
+		}
+		products[productID] = &product
+		report.Products = append(report.Products, &product)
+	}
+	for _, disposal := range b.disposals {
+		product := products[disposal.ProductID]
+		product.Proceeds = product.Proceeds.Add(disposal.Proceeds)
+		product.Realized = product.Realized.Add(disposal.Gain)
+	}
+	for _, product := range report.Products {
+		report.Totals.Basis = report.Totals.Basis.Add(product.Basis)
+		report.Totals.MarketValue = report.Totals.MarketValue.Add(product.MarketValue)
+		report.Totals.Proceeds = report.Totals.Proceeds.Add(product.Proceeds)
+		report.Totals.Realized = report.Totals.Realized.Add(product.Realized)
+		report.Totals.Unrealized = report.Totals.Unrealized.Add(product.Unrealized)
+		report.Totals.Fees = report.Totals.Fees.Add(product.Fees)
+	}
+	return report
+}
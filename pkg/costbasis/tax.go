@@ -0,0 +1,102 @@
+package costbasis
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Term classifies a Disposal by holding period.
+type Term string
+
+const (
+	// TermShort is a Disposal of a Lot held for one year or less.
+	TermShort Term = "short"
+	// TermLong is a Disposal of a Lot held for more than one year.
+	TermLong Term = "long"
+	// TermUnknown is an Unmatched Disposal, whose acquisition date is not known.
+	TermUnknown Term = "unknown"
+)
+
+// Term classifies the Disposal as TermLong when the Lot was held for more than one year, counting from the day
+// after acquisition. Holding periods count calendar days in UTC, so a sale on the anniversary of the acquisition is
+// TermShort whatever the time of day.
+func (d Disposal) Term() Term {
+	if d.Unmatched() {
+		return TermUnknown
+	}
+	acquired, disposed := d.Acquired.UTC(), d.Disposed.UTC()
+	anniversary := time.Date(acquired.Year()+1, acquired.Month(), acquired.Day(), 0, 0, 0, 0, time.UTC)
+	if date(disposed).After(anniversary) {
+		return TermLong
+	}
+	return TermShort
+}
+
+// BasisUnknown indicates that the Basis of the Disposal is not known: it is Unmatched, or it sold a deposited Lot
+// whose basis was not provided and defaulted to zero.
+func (d Disposal) BasisUnknown() bool {
+	return d.Unmatched() || (d.Source == EventTypeDeposit && d.Basis.IsZero())
+}
+
+// date truncates the time to midnight of its day.
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// DisposalsBetween returns the Disposals disposed of at or after from and before to.
+func DisposalsBetween(disposals []Disposal, from time.Time, to time.Time) []Disposal {
+	var between []Disposal
+	for _, disposal := range disposals {
+		if !disposal.Disposed.Before(from) && disposal.Disposed.Before(to) {
+			between = append(between, disposal)
+		}
+	}
+	return between
+}
+
+// form8949Header names the columns of WriteForm8949. The first six columns follow Form 8949 columns (a) through (e)
+// and (h); the remaining columns identify the Disposal and flag a basis that must be completed by hand.
+var form8949Header = []string{
+	"description", "date_acquired", "date_sold", "proceeds", "cost_basis", "gain_or_loss",
+	"term", "product_id", "lot_id", "sale_id", "basis_unknown",
+}
+
+const form8949Date = "01/02/2006"
+
+// WriteForm8949 writes one CSV row per Disposal in the layout of IRS Form 8949, with dates in UTC. Amounts are
+// rounded to cents; an Unmatched Disposal has an empty acquisition date and must be completed by hand. Rows of a
+// Disposal whose BasisUnknown are flagged, since their gain is overstated by the missing basis.
+func WriteForm8949(w io.Writer, disposals []Disposal) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(form8949Header); err != nil {
+		return err
+	}
+	for _, disposal := range disposals {
+		base := strings.SplitN(string(disposal.ProductID), "-", 2)[0]
+		var acquired string
+		if !disposal.Unmatched() {
+			acquired = disposal.Acquired.UTC().Format(form8949Date)
+		}
+		row := []string{
+			disposal.Size.String() + " " + base,
+			acquired,
+			disposal.Disposed.UTC().Format(form8949Date),
+			disposal.Proceeds.StringFixed(2),
+			disposal.Basis.StringFixed(2),
+			disposal.Gain.StringFixed(2),
+			string(disposal.Term()),
+			string(disposal.ProductID),
+			disposal.LotID,
+			disposal.SaleID,
+			strconv.FormatBool(disposal.BasisUnknown()),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,83 @@
+package costbasis
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisposal_Term(t *testing.T) {
+	acquired := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, TermShort, Disposal{LotID: "1", Acquired: acquired, Disposed: acquired.AddDate(1, 0, 0)}.Term())
+	assert.Equal(t, TermShort, Disposal{LotID: "1", Acquired: acquired, Disposed: acquired.AddDate(1, 0, 0).Add(6 * time.Hour)}.Term(),
+		"a sale later on the anniversary is still short term")
+	assert.Equal(t, TermLong, Disposal{LotID: "1", Acquired: acquired, Disposed: acquired.AddDate(1, 0, 1).Add(-12 * time.Hour)}.Term(),
+		"a sale on the day after the anniversary is long term at any hour")
+	assert.Equal(t, TermLong, Disposal{LotID: "1", Acquired: acquired, Disposed: acquired.AddDate(1, 0, 1)}.Term())
+	assert.Equal(t, TermUnknown, Disposal{Disposed: acquired}.Term())
+}
+
+func TestWriteForm8949(t *testing.T) {
+	acquired := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	disposals := []Disposal{
+		{
+			ProductID: "BTC-USD",
+			LotID:     "1",
+			SaleID:    "2",
+			Acquired:  acquired,
+			Disposed:  acquired.AddDate(2, 0, 0),
+			Size:      decimal.NewFromFloat(0.5),
+			Proceeds:  decimal.NewFromFloat(5000.123),
+			Basis:     decimal.NewFromInt(2000),
+			Gain:      decimal.NewFromFloat(3000.123),
+		},
+		{
+			ProductID: "ETH-USD",
+			SaleID:    "3",
+			Disposed:  acquired,
+			Size:      decimal.NewFromInt(1),
+			Proceeds:  decimal.NewFromInt(100),
+			Gain:      decimal.NewFromInt(100),
+		},
+		{
+			ProductID: "BTC-USD",
+			LotID:     "4",
+			SaleID:    "5",
+			Source:    EventTypeDeposit,
+			Acquired:  acquired,
+			Disposed:  acquired.AddDate(0, 1, 0),
+			Size:      decimal.NewFromInt(1),
+			Proceeds:  decimal.NewFromInt(10000),
+			Gain:      decimal.NewFromInt(10000),
+		},
+	}
+	between := DisposalsBetween(disposals, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.Len(t, between, 1)
+	assert.Equal(t, coinbasepro.ProductID("BTC-USD"), between[0].ProductID)
+	var buf bytes.Buffer
+	require.NoError(t, WriteForm8949(&buf, disposals))
+	assert.Equal(t, "description,date_acquired,date_sold,proceeds,cost_basis,gain_or_loss,term,product_id,lot_id,sale_id,basis_unknown\n"+
+		"0.5 BTC,03/01/2019,03/01/2021,5000.12,2000.00,3000.12,long,BTC-USD,1,2,false\n"+
+		"1 ETH,,03/01/2019,100.00,0.00,100.00,unknown,ETH-USD,,3,true\n"+
+		"1 BTC,03/01/2019,04/01/2019,10000.00,0.00,10000.00,short,BTC-USD,4,5,true\n", buf.String(), "a deposited lot without a basis is flagged")
+}
+
+func TestLedgerEvents(t *testing.T) {
+	entries := []*coinbasepro.LedgerEntry{
+		{ID: "1", Type: coinbasepro.LedgerEntryTypeTransfer, Amount: decimal.NewFromInt(2)},
+		{ID: "2", Type: coinbasepro.LedgerEntryTypeMatch, Amount: decimal.NewFromInt(1)},
+		{ID: "3", Type: coinbasepro.LedgerEntryTypeTransfer, Amount: decimal.NewFromInt(-1)},
+	}
+	events := LedgerEvents("BTC", entries, "USD")
+	require.Len(t, events, 2)
+	assert.Equal(t, EventTypeDeposit, events[0].Type)
+	assert.Equal(t, coinbasepro.ProductID("BTC-USD"), events[0].ProductID)
+	assert.Equal(t, EventTypeWithdrawal, events[1].Type)
+	assert.Equal(t, "1", events[1].Size.String())
+	assert.Empty(t, LedgerEvents("USD", entries, "USD"))
+}
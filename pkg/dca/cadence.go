@@ -0,0 +1,182 @@
+package dca
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cadence is a cron expression of five fields, minute hour day-of-month month day-of-week, such as `0 9 * * 1` for
+// 09:00 every Monday. Each field is a `*`, a value, a range `1-5`, a step `*/15` or `1-20/5`, or a comma separated
+// list of them. Days of the week run from 0, Sunday, to 6, and 7 is also Sunday. As in cron, a time matches when it
+// matches either the day of the month or the day of the week if both are restricted. The descriptors @yearly,
+// @monthly, @weekly, @daily and @hourly are also accepted.
+type Cadence struct {
+	spec   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// domAny and dowAny record a `*` day field, which defers to the other day field
+	domAny bool
+	dowAny bool
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCadence parses a cron expression or descriptor. A Cadence that never occurs, such as the 31st of February,
+// is an error.
+func ParseCadence(spec string) (Cadence, error) {
+	expanded := strings.TrimSpace(spec)
+	if descriptor, ok := descriptors[expanded]; ok {
+		expanded = descriptor
+	}
+	fields := strings.Fields(expanded)
+	if len(fields) != 5 {
+		return Cadence{}, fmt.Errorf("cadence %q must have 5 fields: minute hour day-of-month month day-of-week", spec)
+	}
+	c := Cadence{spec: spec, domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+	var err error
+	for _, f := range []struct {
+		bits     *uint64
+		field    string
+		min, max int
+	}{
+		{&c.minute, fields[0], 0, 59},
+		{&c.hour, fields[1], 0, 23},
+		{&c.dom, fields[2], 1, 31},
+		{&c.month, fields[3], 1, 12},
+		{&c.dow, fields[4], 0, 7},
+	} {
+		if *f.bits, err = parseField(f.field, f.min, f.max); err != nil {
+			return Cadence{}, fmt.Errorf("cadence %q: %w", spec, err)
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	// five years from a leap year covers every date
+	if c.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return Cadence{}, fmt.Errorf("cadence %q never occurs", spec)
+	}
+	return c, nil
+}
+
+// parseField sets a bit for each value of the field between min and max.
+func parseField(field string, min int, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		values, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("step of %q is not a positive number", part)
+			}
+			values, step = part[:i], s
+		}
+		lo, hi := min, max
+		var err error
+		switch {
+		case values == "*":
+		case strings.Contains(values, "-"):
+			bounds := strings.SplitN(values, "-", 2)
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("%q is not a range", part)
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, fmt.Errorf("%q is not a range", part)
+			}
+		default:
+			if lo, err = strconv.Atoi(values); err != nil {
+				return 0, fmt.Errorf("%q is not a number", part)
+			}
+			if step == 1 {
+				hi = lo
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is not within %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next is the first time of the Cadence after the time, in the location of the time, or the zero time when there is
+// none in the next five years.
+func (c Cadence) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c Cadence) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// between lists the latest limit times of the Cadence after since and no later than until, oldest first, and the
+// latest of the earlier times that the limit leaves out, which is zero when there are none.
+func (c Cadence) between(since time.Time, until time.Time, limit int) ([]time.Time, time.Time) {
+	var times []time.Time
+	var dropped time.Time
+	for t := c.Next(since); !t.IsZero() && !t.After(until); t = c.Next(t) {
+		if len(times) == limit {
+			dropped, times = times[0], times[1:]
+		}
+		times = append(times, t)
+	}
+	return times, dropped
+}
+
+// IsZero indicates whether the Cadence was not parsed from a spec.
+func (c Cadence) IsZero() bool {
+	return c.spec == ""
+}
+
+func (c Cadence) String() string {
+	return c.spec
+}
+
+func (c Cadence) MarshalText() ([]byte, error) {
+	return []byte(c.spec), nil
+}
+
+func (c *Cadence) UnmarshalText(text []byte) error {
+	cadence, err := ParseCadence(string(text))
+	if err != nil {
+		return err
+	}
+	*c = cadence
+	return nil
+}
@@ -0,0 +1,52 @@
+package dca
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCadence_Next(t *testing.T) {
+	// a Wednesday
+	after := time.Date(2021, 3, 10, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{spec: "* * * * *", next: time.Date(2021, 3, 10, 9, 31, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", next: time.Date(2021, 3, 10, 9, 45, 0, 0, time.UTC)},
+		{spec: "0 9 * * 1", next: time.Date(2021, 3, 15, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 9 * * 7", next: time.Date(2021, 3, 14, 9, 0, 0, 0, time.UTC)},
+		{spec: "30 9,17 * * 1-5", next: time.Date(2021, 3, 10, 17, 30, 0, 0, time.UTC)},
+		{spec: "0 0 1 * *", next: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 15 * 5", next: time.Date(2021, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 12 29 2 *", next: time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{spec: "@daily", next: time.Date(2021, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", next: time.Date(2021, 3, 10, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			cadence, err := ParseCadence(test.spec)
+			require.NoError(t, err)
+			assert.Equal(t, test.next, cadence.Next(after))
+		})
+	}
+}
+
+func TestParseCadence_Errors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "0 0 31 2 *"} {
+		_, err := ParseCadence(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestCadence_UnmarshalYAML(t *testing.T) {
+	var plan Plan
+	require.NoError(t, yaml.Unmarshal([]byte("product_id: BTC-USD\nfunds: 25\ncadence: 0 9 * * 1\n"), &plan))
+	assert.Equal(t, "0 9 * * 1", plan.Cadence.String())
+	assert.Equal(t, "25", plan.Funds.String())
+	assert.Error(t, yaml.Unmarshal([]byte("cadence: weekly\n"), &plan))
+}
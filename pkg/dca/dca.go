@@ -0,0 +1,361 @@
+// Package dca places recurring buys of a fixed amount of a quote Currency, dollar-cost averaging into Products on the
+// Cadence of each Plan of a Schedule.
+//
+// A Scheduler places the runs of each Plan as they come due and records each Execution in a Ledger, so that a
+// restarted Scheduler neither repeats a run nor loses track of the runs it missed while it was not running. Each run
+// is recorded as Pending before its order is placed, with a ClientOrderID derived from the Plan and the due time, so
+// that a run interrupted while it was placed is resolved by its order rather than placed again. The CatchUp policy of
+// a Plan decides which missed runs are placed. A Plan with no Execution in the Ledger starts from the time the
+// Scheduler starts.
+package dca
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+)
+
+// CatchUp is the policy for the runs of a Plan that were missed, such as while the Scheduler was stopped. A run is
+// missed when it is placed more than a minute after it was due.
+type CatchUp string
+
+const (
+	// CatchUpLatest places only the latest of the missed runs. It is the default.
+	CatchUpLatest CatchUp = "latest"
+	// CatchUpAll places the latest missed runs, oldest first, up to ten of them, and records the latest of the earlier
+	// runs as Skipped.
+	CatchUpAll CatchUp = "all"
+	// CatchUpSkip places none of the missed runs and records the latest as Skipped.
+	CatchUpSkip CatchUp = "skip"
+)
+
+func (c CatchUp) Validate() error {
+	switch c {
+	case CatchUpLatest, CatchUpAll, CatchUpSkip:
+		return nil
+	default:
+		return fmt.Errorf("catchup(%q) is not one of [ latest, all, skip ]", c)
+	}
+}
+
+const (
+	// onTime is how late a run can be placed without being missed
+	onTime = time.Minute
+	// maxCatchUp limits the missed runs of a Plan that are placed
+	maxCatchUp = 10
+)
+
+// Schedule is the Plans of a Scheduler.
+type Schedule struct {
+	// CatchUp is the policy of the Plans that have none, CatchUpLatest when empty
+	CatchUp CatchUp `json:"catchup" yaml:"catchup"`
+	Plans   []Plan  `json:"plans" yaml:"plans"`
+}
+
+func (s Schedule) Validate() error {
+	if len(s.Plans) == 0 {
+		return errors.New("a schedule requires a plan")
+	}
+	if s.CatchUp != "" {
+		if err := s.CatchUp.Validate(); err != nil {
+			return err
+		}
+	}
+	ids := make(map[string]bool, len(s.Plans))
+	for _, plan := range s.Plans {
+		if err := plan.Validate(); err != nil {
+			return fmt.Errorf("plan %s: %w", plan.ID(), err)
+		}
+		if ids[plan.ID()] {
+			return fmt.Errorf("plan %s is not unique, name the plans of the same product", plan.ID())
+		}
+		ids[plan.ID()] = true
+	}
+	return nil
+}
+
+// Plan buys Funds of the quote Currency of a Product on each time of its Cadence.
+type Plan struct {
+	// Name identifies the Plan in the Ledger; the ProductID when empty
+	Name      string                `json:"name" yaml:"name"`
+	ProductID coinbasepro.ProductID `json:"product_id" yaml:"product_id"`
+	// Funds is the amount of the quote Currency of each buy, rounded down to the QuoteIncrement of the Product
+	Funds   decimal.Decimal `json:"funds" yaml:"funds"`
+	Cadence Cadence         `json:"cadence" yaml:"cadence"`
+	// Type is OrderTypeMarket, the default, for a MarketOrder of the Funds, or OrderTypeLimit for a post only
+	// LimitOrder at the best bid, sized by the Funds
+	Type coinbasepro.OrderType `json:"type" yaml:"type"`
+	// CatchUp is the policy for missed runs, the CatchUp of the Schedule when empty
+	CatchUp CatchUp `json:"catchup" yaml:"catchup"`
+}
+
+// ID is the Name of the Plan, or its ProductID when it has no Name.
+func (p Plan) ID() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return string(p.ProductID)
+}
+
+func (p Plan) Validate() error {
+	if p.ProductID == "" {
+		return errors.New("'product_id' is required")
+	}
+	if !p.Funds.IsPositive() {
+		return errors.New("'funds' must be positive")
+	}
+	if p.Cadence.IsZero() {
+		return errors.New("'cadence' is required")
+	}
+	switch p.Type {
+	case "", coinbasepro.OrderTypeMarket, coinbasepro.OrderTypeLimit:
+	default:
+		return fmt.Errorf("type(%q) is not valid", p.Type)
+	}
+	if p.CatchUp != "" {
+		return p.CatchUp.Validate()
+	}
+	return nil
+}
+
+// Trader is the part of the coinbasepro.Client that a Scheduler uses.
+type Trader interface {
+	GetProduct(ctx context.Context, productID coinbasepro.ProductID) (coinbasepro.Product, error)
+	GetProductTicker(ctx context.Context, productID coinbasepro.ProductID) (coinbasepro.ProductTicker, error)
+	CreateMarketOrder(ctx context.Context, marketOrder coinbasepro.MarketOrder) (coinbasepro.Order, error)
+	CreateLimitOrder(ctx context.Context, limitOrder coinbasepro.LimitOrder) (coinbasepro.Order, error)
+	GetClientOrder(ctx context.Context, clientID string) (coinbasepro.Order, error)
+}
+
+// Scheduler places the due runs of the Plans of a Schedule with a Trader.
+type Scheduler struct {
+	trader Trader
+	ledger *Ledger
+	plans  []Plan
+	// since is the time after which the next run of each Plan is due, by ID
+	since map[string]time.Time
+}
+
+// NewScheduler creates a Scheduler of the Schedule that resumes each Plan from its last Execution in the Ledger, or
+// from start when it has none.
+func NewScheduler(trader Trader, schedule Schedule, ledger *Ledger, start time.Time) (*Scheduler, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	s := Scheduler{trader: trader, ledger: ledger, since: make(map[string]time.Time, len(schedule.Plans))}
+	for _, plan := range schedule.Plans {
+		if plan.CatchUp == "" {
+			plan.CatchUp = schedule.CatchUp
+		}
+		if plan.CatchUp == "" {
+			plan.CatchUp = CatchUpLatest
+		}
+		if plan.Type == "" {
+			plan.Type = coinbasepro.OrderTypeMarket
+		}
+		s.since[plan.ID()] = start
+		if last, ok := ledger.Last(plan.ID()); ok {
+			s.since[plan.ID()] = last
+		}
+		s.plans = append(s.plans, plan)
+	}
+	return &s, nil
+}
+
+// Next is the earliest time that a run of any Plan is due, and false when no Plan has another run.
+func (s *Scheduler) Next() (time.Time, bool) {
+	var next time.Time
+	for _, plan := range s.plans {
+		due := plan.Cadence.Next(s.since[plan.ID()])
+		if !due.IsZero() && (next.IsZero() || due.Before(next)) {
+			next = due
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// RunDue resolves the Pending Executions of the Ledger, then places the runs of each Plan that are due by now, as
+// allowed by the CatchUp policy of the Plan, and records an Execution of each in the Ledger: first as Pending, then
+// with the outcome of its order. An order that fails is recorded with its Error; only a failure to record ends RunDue,
+// with the Executions recorded so far.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) ([]Execution, error) {
+	executions, err := s.resolve(ctx, now)
+	if err != nil {
+		return executions, err
+	}
+	for _, plan := range s.plans {
+		due, dropped := plan.Cadence.between(s.since[plan.ID()], now, maxCatchUp)
+		if len(due) == 0 {
+			continue
+		}
+		latest := due[len(due)-1]
+		var place []time.Time
+		switch {
+		case plan.CatchUp == CatchUpAll:
+			place = due
+		case plan.CatchUp == CatchUpLatest || now.Sub(latest) <= onTime:
+			place = due[len(due)-1:]
+		}
+		var skipped []time.Time
+		if plan.CatchUp == CatchUpAll && !dropped.IsZero() {
+			skipped = append(skipped, dropped)
+		}
+		if len(place) == 0 {
+			skipped = append(skipped, latest)
+		}
+		for _, at := range skipped {
+			execution := Execution{Plan: plan.ID(), ProductID: plan.ProductID, Due: at, At: now, Funds: plan.Funds, Skipped: true}
+			if err := s.ledger.Record(execution); err != nil {
+				return executions, err
+			}
+			executions = append(executions, execution)
+		}
+		for _, at := range place {
+			execution := Execution{Plan: plan.ID(), ProductID: plan.ProductID, Due: at, At: now, Funds: plan.Funds, ClientOrderID: clientOrderID(plan.ID(), at), Pending: true}
+			if err := s.ledger.Record(execution); err != nil {
+				return executions, err
+			}
+			execution = s.execute(ctx, plan, execution)
+			if err := s.ledger.Record(execution); err != nil {
+				return executions, err
+			}
+			executions = append(executions, execution)
+		}
+		s.since[plan.ID()] = latest
+	}
+	return executions, nil
+}
+
+// resolve records the outcome of each Pending Execution of the Ledger, whose order may or may not have been placed
+// before the run was interrupted, by looking up the order by its ClientOrderID. An Execution whose order cannot be
+// looked up stays Pending until a later RunDue.
+func (s *Scheduler) resolve(ctx context.Context, now time.Time) ([]Execution, error) {
+	var executions []Execution
+	for _, execution := range s.ledger.Pending() {
+		order, err := s.trader.GetClientOrder(ctx, execution.ClientOrderID)
+		var apiErr coinbasepro.Error
+		switch {
+		case err == nil:
+			execution.OrderID = order.ID
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+			execution.Error = "the run was interrupted before its order was placed"
+		default:
+			continue
+		}
+		execution.Pending = false
+		execution.At = now
+		if err = s.ledger.Record(execution); err != nil {
+			return executions, err
+		}
+		executions = append(executions, execution)
+	}
+	return executions, nil
+}
+
+// Run places the runs of every Plan as they come due until the context is done, and passes each Execution to report.
+// An error from report or from RunDue ends the Run.
+func (s *Scheduler) Run(ctx context.Context, report func(Execution) error) error {
+	for {
+		executions, err := s.RunDue(ctx, time.Now())
+		for _, execution := range executions {
+			if reportErr := report(execution); reportErr != nil {
+				return reportErr
+			}
+		}
+		if err != nil {
+			return err
+		}
+		next, ok := s.Next()
+		if !ok {
+			return errors.New("no plan has another run")
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// execute places the order of the Pending Execution of the Plan and returns the Execution with its outcome.
+func (s *Scheduler) execute(ctx context.Context, plan Plan, execution Execution) Execution {
+	execution.Pending = false
+	order, err := s.place(ctx, plan, &execution)
+	switch {
+	case errors.Is(err, coinbasepro.ErrDryRun):
+		execution.DryRun = true
+	case err != nil:
+		execution.Error = err.Error()
+	default:
+		execution.OrderID = order.ID
+	}
+	return execution
+}
+
+func (s *Scheduler) place(ctx context.Context, plan Plan, execution *Execution) (coinbasepro.Order, error) {
+	product, err := s.trader.GetProduct(ctx, plan.ProductID)
+	if err != nil {
+		return coinbasepro.Order{}, err
+	}
+	if plan.Type == coinbasepro.OrderTypeLimit {
+		ticker, err := s.trader.GetProductTicker(ctx, plan.ProductID)
+		if err != nil {
+			return coinbasepro.Order{}, err
+		}
+		price := product.RoundQuote(ticker.Bid)
+		if !price.IsPositive() {
+			return coinbasepro.Order{}, fmt.Errorf("product %s has no bid", plan.ProductID)
+		}
+		size := product.RoundBase(plan.Funds.Div(price))
+		execution.Price, execution.Size = &price, &size
+		execution.Funds = price.Mul(size)
+		order := coinbasepro.LimitOrder{
+			ClientOrderID:       execution.ClientOrderID,
+			ProductID:           plan.ProductID,
+			SelfTradePrevention: coinbasepro.SelfTradeDecrementAndCancel,
+			Side:                coinbasepro.SideBuy,
+			Type:                coinbasepro.OrderTypeLimit,
+			PostOnly:            true,
+			Price:               price,
+			Size:                size,
+			TimeInForce:         coinbasepro.TimeInForceGoodTillCanceled,
+		}
+		if err = product.ValidateLimitOrder(order); err != nil {
+			return coinbasepro.Order{}, err
+		}
+		return s.trader.CreateLimitOrder(ctx, order)
+	}
+	funds := product.RoundQuote(plan.Funds)
+	execution.Funds = funds
+	order := coinbasepro.MarketOrder{
+		ClientOrderID:       execution.ClientOrderID,
+		ProductID:           plan.ProductID,
+		SelfTradePrevention: coinbasepro.SelfTradeDecrementAndCancel,
+		Side:                coinbasepro.SideBuy,
+		Type:                coinbasepro.OrderTypeMarket,
+		Funds:               &funds,
+	}
+	if err = product.ValidateMarketOrder(order); err != nil {
+		return coinbasepro.Order{}, err
+	}
+	return s.trader.CreateMarketOrder(ctx, order)
+}
+
+// clientOrderID is the ClientOrderID of the run of the Plan that is due at due, a UUID derived from both so that an
+// order placed for the run can be found again.
+func clientOrderID(planID string, due time.Time) string {
+	sum := sha256.Sum256([]byte(planID + "\n" + due.UTC().Format(time.RFC3339)))
+	b := sum[:16]
+	// version 8 and the variant of RFC 9562, for a UUID of custom content
+	b[6] = b[6]&0x0f | 0x80
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,216 @@
+package dca
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTrader struct {
+	market []coinbasepro.MarketOrder
+	limit  []coinbasepro.LimitOrder
+	err    error
+	// placed are the IDs of the orders that were placed, by ClientOrderID
+	placed map[string]string
+}
+
+func (f *fakeTrader) GetProduct(_ context.Context, productID coinbasepro.ProductID) (coinbasepro.Product, error) {
+	return coinbasepro.Product{
+		ID:             string(productID),
+		BaseIncrement:  decimal.RequireFromString("0.0001"),
+		BaseMinSize:    decimal.RequireFromString("0.001"),
+		QuoteIncrement: decimal.RequireFromString("0.01"),
+		MinMarketFunds: decimal.RequireFromString("5"),
+	}, nil
+}
+
+func (f *fakeTrader) GetProductTicker(_ context.Context, _ coinbasepro.ProductID) (coinbasepro.ProductTicker, error) {
+	return coinbasepro.ProductTicker{Bid: decimal.RequireFromString("1000.005"), Price: decimal.RequireFromString("1001")}, nil
+}
+
+func (f *fakeTrader) CreateMarketOrder(_ context.Context, order coinbasepro.MarketOrder) (coinbasepro.Order, error) {
+	f.market = append(f.market, order)
+	return coinbasepro.Order{ID: "market"}, f.err
+}
+
+func (f *fakeTrader) CreateLimitOrder(_ context.Context, order coinbasepro.LimitOrder) (coinbasepro.Order, error) {
+	f.limit = append(f.limit, order)
+	return coinbasepro.Order{ID: "limit"}, f.err
+}
+
+func (f *fakeTrader) GetClientOrder(_ context.Context, clientID string) (coinbasepro.Order, error) {
+	if id, ok := f.placed[clientID]; ok {
+		return coinbasepro.Order{ID: id}, nil
+	}
+	return coinbasepro.Order{}, coinbasepro.Error{StatusCode: 404, Message: "NotFound"}
+}
+
+func hourly(t *testing.T, name string, catchUp CatchUp) Plan {
+	cadence, err := ParseCadence("0 * * * *")
+	require.NoError(t, err)
+	return Plan{Name: name, ProductID: "BTC-USD", Funds: decimal.RequireFromString("25.005"), Cadence: cadence, CatchUp: catchUp}
+}
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, 3, 10, 9, 30, 0, 0, time.UTC)
+	fs := afero.NewMemMapFs()
+	ledger, err := OpenLedger(fs, "/dca/ledger.jsonl")
+	require.NoError(t, err)
+	var trader fakeTrader
+	schedule := Schedule{Plans: []Plan{hourly(t, "latest", ""), hourly(t, "all", CatchUpAll), hourly(t, "skip", CatchUpSkip)}}
+	scheduler, err := NewScheduler(&trader, schedule, ledger, start)
+	require.NoError(t, err)
+	next, ok := scheduler.Next()
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(30*time.Minute), next)
+
+	executions, err := scheduler.RunDue(ctx, start.Add(10*time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, executions, "nothing is due before the first run")
+
+	onTime := start.Add(30*time.Minute + 20*time.Second)
+	executions, err = scheduler.RunDue(ctx, onTime)
+	require.NoError(t, err)
+	require.Len(t, executions, 3, "a run placed on time is placed by every policy")
+	for _, execution := range executions {
+		assert.Equal(t, "market", execution.OrderID)
+		assert.Equal(t, "25", execution.Funds.String(), "funds are rounded down to the quote increment")
+		assert.False(t, execution.Pending)
+	}
+	require.Len(t, trader.market, 3)
+	assert.Equal(t, "25", trader.market[0].Funds.String())
+	assert.Equal(t, clientOrderID("latest", start.Add(30*time.Minute)), trader.market[0].ClientOrderID)
+	assert.Equal(t, trader.market[0].ClientOrderID, executions[0].ClientOrderID)
+	executions, err = scheduler.RunDue(ctx, onTime)
+	require.NoError(t, err)
+	assert.Empty(t, executions, "a run is placed once")
+
+	// the scheduler restarts three hours later, after missing the runs at 11:00 and 12:00
+	restarted, err := OpenLedger(fs, "/dca/ledger.jsonl")
+	require.NoError(t, err)
+	last, ok := restarted.Last("all")
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(30*time.Minute), last)
+	trader = fakeTrader{}
+	scheduler, err = NewScheduler(&trader, schedule, restarted, start.Add(3*time.Hour))
+	require.NoError(t, err)
+	executions, err = scheduler.RunDue(ctx, start.Add(3*time.Hour))
+	require.NoError(t, err)
+	var placed []string
+	for _, execution := range executions {
+		placed = append(placed, execution.Plan+" "+execution.Due.Format("15:04")+" "+execution.OrderID)
+	}
+	assert.Equal(t, []string{"latest 12:00 market", "all 11:00 market", "all 12:00 market", "skip 12:00 "}, placed)
+	assert.True(t, executions[3].Skipped)
+	assert.Len(t, trader.market, 3)
+}
+
+func TestScheduler_Limit(t *testing.T) {
+	start := time.Date(2021, 3, 10, 9, 30, 0, 0, time.UTC)
+	ledger, err := OpenLedger(afero.NewMemMapFs(), "ledger.jsonl")
+	require.NoError(t, err)
+	trader := fakeTrader{err: coinbasepro.ErrDryRun}
+	plan := hourly(t, "", "")
+	plan.Type = coinbasepro.OrderTypeLimit
+	scheduler, err := NewScheduler(&trader, Schedule{Plans: []Plan{plan}}, ledger, start)
+	require.NoError(t, err)
+	executions, err := scheduler.RunDue(context.Background(), start.Add(30*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, "BTC-USD", executions[0].Plan, "a plan without a name is identified by its product")
+	assert.True(t, executions[0].DryRun)
+	require.Len(t, trader.limit, 1)
+	order := trader.limit[0]
+	assert.True(t, order.PostOnly)
+	assert.Equal(t, "1000", order.Price.String(), "the price is the bid rounded down to the quote increment")
+	assert.Equal(t, "0.025", order.Size.String())
+
+	trader.err = errors.New("insufficient funds")
+	executions, err = scheduler.RunDue(context.Background(), start.Add(90*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, "insufficient funds", executions[0].Error)
+	executions, err = scheduler.RunDue(context.Background(), start.Add(91*time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, executions, "a failed run is not retried")
+}
+
+func TestScheduler_Pending(t *testing.T) {
+	start := time.Date(2021, 3, 10, 9, 30, 0, 0, time.UTC)
+	fs := afero.NewMemMapFs()
+	ledger, err := OpenLedger(fs, "ledger.jsonl")
+	require.NoError(t, err)
+	// the scheduler stopped after recording two runs as pending, having placed only the order of the first
+	due := start.Add(30 * time.Minute)
+	placed := Execution{Plan: "placed", ProductID: "BTC-USD", Due: due, At: due, ClientOrderID: clientOrderID("placed", due), Pending: true}
+	lost := Execution{Plan: "lost", ProductID: "BTC-USD", Due: due, At: due, ClientOrderID: clientOrderID("lost", due), Pending: true}
+	require.NoError(t, ledger.Record(placed))
+	require.NoError(t, ledger.Record(lost))
+
+	restarted, err := OpenLedger(fs, "ledger.jsonl")
+	require.NoError(t, err)
+	require.Len(t, restarted.Pending(), 2)
+	trader := fakeTrader{placed: map[string]string{placed.ClientOrderID: "order"}}
+	scheduler, err := NewScheduler(&trader, Schedule{Plans: []Plan{hourly(t, "placed", ""), hourly(t, "lost", "")}}, restarted, start)
+	require.NoError(t, err)
+	executions, err := scheduler.RunDue(context.Background(), due.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, executions, 2)
+	assert.Equal(t, "lost", executions[0].Plan)
+	assert.Equal(t, "the run was interrupted before its order was placed", executions[0].Error)
+	assert.Equal(t, "placed", executions[1].Plan)
+	assert.Equal(t, "order", executions[1].OrderID)
+	assert.Empty(t, trader.market, "a pending run is not placed again")
+	assert.Empty(t, restarted.Pending())
+}
+
+func TestScheduler_CatchUpAll(t *testing.T) {
+	start := time.Date(2021, 3, 10, 9, 30, 0, 0, time.UTC)
+	ledger, err := OpenLedger(afero.NewMemMapFs(), "ledger.jsonl")
+	require.NoError(t, err)
+	var trader fakeTrader
+	scheduler, err := NewScheduler(&trader, Schedule{Plans: []Plan{hourly(t, "", CatchUpAll)}}, ledger, start)
+	require.NoError(t, err)
+	executions, err := scheduler.RunDue(context.Background(), start.Add(15*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, executions, maxCatchUp+1)
+	assert.True(t, executions[0].Skipped, "the runs before the latest ten are skipped")
+	assert.Equal(t, "14:00", executions[0].Due.Format("15:04"))
+	assert.Equal(t, "15:00", executions[1].Due.Format("15:04"))
+	assert.Equal(t, "00:00", executions[maxCatchUp].Due.Format("15:04"))
+	assert.Len(t, trader.market, maxCatchUp)
+}
+
+func TestClientOrderID(t *testing.T) {
+	due := time.Date(2021, 3, 10, 10, 0, 0, 0, time.UTC)
+	id := clientOrderID("btc", due)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+	assert.Equal(t, id, clientOrderID("btc", due.In(time.FixedZone("EST", -5*60*60))), "the id of a run does not depend on the zone")
+	assert.NotEqual(t, id, clientOrderID("btc", due.Add(time.Hour)))
+	assert.NotEqual(t, id, clientOrderID("eth", due))
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	plan := hourly(t, "", "")
+	assert.NoError(t, Schedule{Plans: []Plan{plan}}.Validate())
+	assert.Error(t, Schedule{}.Validate())
+	assert.Error(t, Schedule{Plans: []Plan{plan, plan}}.Validate(), "plans of the same product need names")
+	assert.Error(t, Schedule{CatchUp: "sometimes", Plans: []Plan{plan}}.Validate())
+	invalid := plan
+	invalid.Funds = decimal.Zero
+	assert.Error(t, Schedule{Plans: []Plan{invalid}}.Validate())
+	invalid = plan
+	invalid.Cadence = Cadence{}
+	assert.Error(t, Schedule{Plans: []Plan{invalid}}.Validate())
+	invalid = plan
+	invalid.Type = "stop"
+	assert.Error(t, Schedule{Plans: []Plan{invalid}}.Validate())
+}
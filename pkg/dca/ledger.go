@@ -0,0 +1,135 @@
+package dca
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/afero"
+)
+
+// Execution is the outcome of a single due run of a Plan.
+type Execution struct {
+	// Plan is the ID of the Plan
+	Plan      string                `json:"plan"`
+	ProductID coinbasepro.ProductID `json:"product_id"`
+	// Due is the time of the Cadence that the Execution runs
+	Due time.Time `json:"due"`
+	// At is when the Execution ran
+	At time.Time `json:"at"`
+	// OrderID is the ID of the Order placed, if any
+	OrderID string `json:"order_id,omitempty"`
+	// ClientOrderID is the ClientOrderID of the order of the run, derived from the Plan and Due
+	ClientOrderID string `json:"client_oid,omitempty"`
+	// Pending indicates that the order of the run is about to be placed. An Execution that is still Pending when a
+	// Scheduler restarts was interrupted, and is resolved by its ClientOrderID rather than placed again.
+	Pending bool `json:"pending,omitempty"`
+	// Funds is the amount of the quote Currency of the order
+	Funds decimal.Decimal `json:"funds"`
+	// Price and Size of a limit order
+	Price *decimal.Decimal `json:"price,omitempty"`
+	Size  *decimal.Decimal `json:"size,omitempty"`
+	// Skipped indicates that the run was missed and not placed, as the CatchUpSkip policy of the Plan requires
+	Skipped bool `json:"skipped,omitempty"`
+	// DryRun indicates that the order was validated and signed by a DryRunClient but not sent
+	DryRun bool `json:"dry_run,omitempty"`
+	// Error is why the order failed. A failed Execution is not retried, since an order that fails in flight may still
+	// have been placed.
+	Error string `json:"error,omitempty"`
+}
+
+// Ledger is a local file of the Executions of each Plan, one json line each, so that a restarted Scheduler does not
+// repeat them.
+type Ledger struct {
+	fs      afero.Fs
+	path    string
+	last    map[string]time.Time
+	pending map[string]Execution
+}
+
+// OpenLedger reads the Executions of the ledger file at path. A missing file is an empty Ledger, which is created
+// with its directory on the first Record.
+func OpenLedger(fs afero.Fs, path string) (*Ledger, error) {
+	ledger := Ledger{fs: fs, path: path, last: make(map[string]time.Time), pending: make(map[string]Execution)}
+	f, err := fs.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &ledger, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var execution Execution
+		if err = json.Unmarshal(scanner.Bytes(), &execution); err != nil {
+			return nil, fmt.Errorf("ledger %s line %d: %w", path, line, err)
+		}
+		ledger.remember(execution)
+	}
+	return &ledger, scanner.Err()
+}
+
+// Last is the latest Due of the Executions of the Plan, Pending or not, and false when the Plan has none.
+func (l *Ledger) Last(planID string) (time.Time, bool) {
+	last, ok := l.last[planID]
+	return last, ok
+}
+
+// Pending is the Executions whose outcome has not been recorded, oldest first.
+func (l *Ledger) Pending() []Execution {
+	pending := make([]Execution, 0, len(l.pending))
+	for _, execution := range l.pending {
+		pending = append(pending, execution)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].Due.Equal(pending[j].Due) {
+			return pending[i].Due.Before(pending[j].Due)
+		}
+		return pending[i].Plan < pending[j].Plan
+	})
+	return pending
+}
+
+// Record appends the Execution to the ledger file.
+func (l *Ledger) Record(execution Execution) (capture error) {
+	if err := l.fs.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+	f, err := l.fs.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() { coinbasepro.Capture(&capture, f.Close()) }()
+	if err = json.NewEncoder(f).Encode(execution); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	l.remember(execution)
+	return nil
+}
+
+func (l *Ledger) remember(execution Execution) {
+	if execution.ClientOrderID != "" {
+		if execution.Pending {
+			l.pending[execution.ClientOrderID] = execution
+		} else {
+			delete(l.pending, execution.ClientOrderID)
+		}
+	}
+	if last, ok := l.last[execution.Plan]; !ok || execution.Due.After(last) {
+		l.last[execution.Plan] = execution.Due
+	}
+}
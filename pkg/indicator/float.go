@@ -0,0 +1,241 @@
+package indicator
+
+import (
+	"math"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+)
+
+// Float64Series is the float64 fast path equivalent of a Series. Undefined values are math.NaN().
+type Float64Series []float64
+
+// Float64Candle is a float64 representation of a coinbasepro.Candle.
+type Float64Candle struct {
+	Close  float64
+	High   float64
+	Low    float64
+	Open   float64
+	Volume float64
+}
+
+// Float64Candles converts Candles to their float64 representation. Conversion is lossy.
+func Float64Candles(candles []*coinbasepro.Candle) []Float64Candle {
+	out := make([]Float64Candle, len(candles))
+	for i, candle := range candles {
+		out[i].Close, _ = candle.Close.Float64()
+		out[i].High, _ = candle.High.Float64()
+		out[i].Low, _ = candle.Low.Float64()
+		out[i].Open, _ = candle.Open.Float64()
+		out[i].Volume, _ = candle.Volume.Float64()
+	}
+	return out
+}
+
+// Float64Closes extracts the Close of each Float64Candle.
+func Float64Closes(candles []Float64Candle) []float64 {
+	closes := make([]float64, len(candles))
+	for i, candle := range candles {
+		closes[i] = candle.Close
+	}
+	return closes
+}
+
+// SMAFloat64 is the float64 fast path of SMA.
+func SMAFloat64(values []float64, period int) Float64Series {
+	return smaFloat64(values, period)
+}
+
+// EMAFloat64 is the float64 fast path of EMA.
+func EMAFloat64(values []float64, period int) Float64Series {
+	return emaFloat64(values, period)
+}
+
+// RSIFloat64 is the float64 fast path of RSI.
+func RSIFloat64(values []float64, period int) Float64Series {
+	out := undefined(len(values))
+	if period < 1 || len(values) <= period {
+		return out
+	}
+	p := float64(period)
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		change := values[i] - values[i-1]
+		if change > 0 {
+			gain += change
+		} else {
+			loss -= change
+		}
+	}
+	gain /= p
+	loss /= p
+	out[period] = rsiFloat64(gain, loss)
+	for i := period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		gain = (gain*(p-1) + math.Max(change, 0)) / p
+		loss = (loss*(p-1) + math.Max(-change, 0)) / p
+		out[i] = rsiFloat64(gain, loss)
+	}
+	return out
+}
+
+func rsiFloat64(gain float64, loss float64) float64 {
+	if loss == 0 {
+		return 100
+	}
+	return 100 - 100/(1+gain/loss)
+}
+
+// MACDFloat64Series is the float64 fast path equivalent of a MACDSeries.
+type MACDFloat64Series struct {
+	MACD      Float64Series
+	Signal    Float64Series
+	Histogram Float64Series
+}
+
+// MACDFloat64 is the float64 fast path of MACD.
+func MACDFloat64(values []float64, fast int, slow int, signal int) MACDFloat64Series {
+	fastEMA := emaFloat64(values, fast)
+	slowEMA := emaFloat64(values, slow)
+	macd := make(Float64Series, len(values))
+	for i := range values {
+		macd[i] = fastEMA[i] - slowEMA[i]
+	}
+	signalEMA := emaFloat64(macd, signal)
+	histogram := make(Float64Series, len(values))
+	for i := range values {
+		histogram[i] = macd[i] - signalEMA[i]
+	}
+	return MACDFloat64Series{
+		MACD:      macd,
+		Signal:    signalEMA,
+		Histogram: histogram,
+	}
+}
+
+// BollingerFloat64Series is the float64 fast path equivalent of a BollingerSeries.
+type BollingerFloat64Series struct {
+	Middle Float64Series
+	Upper  Float64Series
+	Lower  Float64Series
+}
+
+// BollingerFloat64 is the float64 fast path of Bollinger.
+func BollingerFloat64(values []float64, period int, k float64) BollingerFloat64Series {
+	middle := smaFloat64(values, period)
+	upper := undefined(len(values))
+	lower := undefined(len(values))
+	for i := range values {
+		if math.IsNaN(middle[i]) {
+			continue
+		}
+		var variance float64
+		for _, value := range values[i+1-period : i+1] {
+			variance += (value - middle[i]) * (value - middle[i])
+		}
+		width := math.Sqrt(variance/float64(period)) * k
+		upper[i] = middle[i] + width
+		lower[i] = middle[i] - width
+	}
+	return BollingerFloat64Series{
+		Middle: middle,
+		Upper:  upper,
+		Lower:  lower,
+	}
+}
+
+// ATRFloat64 is the float64 fast path of ATR.
+func ATRFloat64(candles []Float64Candle, period int) Float64Series {
+	out := undefined(len(candles))
+	if period < 1 || len(candles) < period {
+		return out
+	}
+	trueRanges := make([]float64, len(candles))
+	for i, candle := range candles {
+		trueRange := candle.High - candle.Low
+		if i > 0 {
+			prevClose := candles[i-1].Close
+			trueRange = math.Max(trueRange, math.Max(math.Abs(candle.High-prevClose), math.Abs(candle.Low-prevClose)))
+		}
+		trueRanges[i] = trueRange
+	}
+	p := float64(period)
+	var atr float64
+	for _, trueRange := range trueRanges[:period] {
+		atr += trueRange
+	}
+	atr /= p
+	out[period-1] = atr
+	for i := period; i < len(candles); i++ {
+		atr = (atr*(p-1) + trueRanges[i]) / p
+		out[i] = atr
+	}
+	return out
+}
+
+// VWAPFloat64 is the float64 fast path of VWAP.
+func VWAPFloat64(candles []Float64Candle) Float64Series {
+	out := undefined(len(candles))
+	var cumulativeValue, cumulativeVolume float64
+	for i, candle := range candles {
+		cumulativeValue += (candle.High + candle.Low + candle.Close) / 3 * candle.Volume
+		cumulativeVolume += candle.Volume
+		if cumulativeVolume == 0 {
+			continue
+		}
+		out[i] = cumulativeValue / cumulativeVolume
+	}
+	return out
+}
+
+func undefined(n int) Float64Series {
+	out := make(Float64Series, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
+
+// smaFloat64 skips any leading NaN values, so that it can be applied to the output of another indicator.
+func smaFloat64(values []float64, period int) Float64Series {
+	out := undefined(len(values))
+	start := firstDefinedFloat64(values)
+	if period < 1 || len(values)-start < period {
+		return out
+	}
+	var sum float64
+	for i := start; i < len(values); i++ {
+		sum += values[i]
+		if i-start >= period {
+			sum -= values[i-period]
+		}
+		if i-start >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// emaFloat64 skips any leading NaN values, so that it can be applied to the output of another indicator.
+func emaFloat64(values []float64, period int) Float64Series {
+	out := smaFloat64(values, period)
+	start := firstDefinedFloat64(values) + period - 1
+	if period < 1 || start >= len(values) || math.IsNaN(out[start]) {
+		return out
+	}
+	alpha := 2 / float64(period+1)
+	prev := out[start]
+	for i := start + 1; i < len(values); i++ {
+		prev = (values[i]-prev)*alpha + prev
+		out[i] = prev
+	}
+	return out
+}
+
+func firstDefinedFloat64(values []float64) int {
+	for i, value := range values {
+		if !math.IsNaN(value) {
+			return i
+		}
+	}
+	return len(values)
+}
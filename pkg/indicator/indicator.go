@@ -0,0 +1,288 @@
+// Package indicator computes common technical indicators (SMA, EMA, RSI, MACD, Bollinger Bands, ATR and VWAP) over
+// the Candles of coinbasepro.HistoricRates.
+//
+// Indicators are computed with decimal.Decimal to match the precision of the coinbasepro types. When precision is
+// less important than speed, the Float64 variants of each indicator provide an opt-in fast path.
+//
+// Coinbase Pro returns Candles newest first; all indicators expect Candles in chronological order, oldest first.
+// Use Chronological to order the Candles of a HistoricRates response.
+package indicator
+
+import (
+	"math"
+	"sort"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+)
+
+// Series holds one indicator value per Candle, aligned by index with the Candles from which it was computed.
+// A nil value indicates that not enough Candles preceded it for the indicator to be defined.
+type Series []*decimal.Decimal
+
+// Chronological returns the Candles of the HistoricRates sorted oldest first. The HistoricRates are not modified.
+func Chronological(rates coinbasepro.HistoricRates) []*coinbasepro.Candle {
+	candles := make([]*coinbasepro.Candle, len(rates.Candles))
+	copy(candles, rates.Candles)
+	sort.SliceStable(candles, func(i, j int) bool {
+		return candles[i].Time.Time().Before(candles[j].Time.Time())
+	})
+	return candles
+}
+
+// Closes extracts the Close of each Candle.
+func Closes(candles []*coinbasepro.Candle) []decimal.Decimal {
+	closes := make([]decimal.Decimal, len(candles))
+	for i, candle := range candles {
+		closes[i] = candle.Close
+	}
+	return closes
+}
+
+// SMA is the simple moving average of the values over period.
+func SMA(values []decimal.Decimal, period int) Series {
+	return sma(series(values), period)
+}
+
+// EMA is the exponential moving average of the values over period. The EMA is seeded with the SMA of the first
+// period values and smoothed with a factor of 2/(period+1).
+func EMA(values []decimal.Decimal, period int) Series {
+	return ema(series(values), period)
+}
+
+// RSI is the relative strength index of the values over period, using Wilder's smoothing of average gains and losses.
+// RSI ranges from 0 to 100.
+func RSI(values []decimal.Decimal, period int) Series {
+	out := make(Series, len(values))
+	if period < 1 || len(values) <= period {
+		return out
+	}
+	hundred := decimal.NewFromInt(100)
+	p := decimal.NewFromInt(int64(period))
+	var gain, loss decimal.Decimal
+	for i := 1; i <= period; i++ {
+		change := values[i].Sub(values[i-1])
+		if change.IsPositive() {
+			gain = gain.Add(change)
+		} else {
+			loss = loss.Sub(change)
+		}
+	}
+	gain = gain.Div(p)
+	loss = loss.Div(p)
+	out[period] = rsi(gain, loss, hundred)
+	for i := period + 1; i < len(values); i++ {
+		change := values[i].Sub(values[i-1])
+		up, down := decimal.Zero, decimal.Zero
+		if change.IsPositive() {
+			up = change
+		} else {
+			down = change.Neg()
+		}
+		gain = gain.Mul(p.Sub(decimal.NewFromInt(1))).Add(up).Div(p)
+		loss = loss.Mul(p.Sub(decimal.NewFromInt(1))).Add(down).Div(p)
+		out[i] = rsi(gain, loss, hundred)
+	}
+	return out
+}
+
+func rsi(gain decimal.Decimal, loss decimal.Decimal, hundred decimal.Decimal) *decimal.Decimal {
+	if loss.IsZero() {
+		return &hundred
+	}
+	value := hundred.Sub(hundred.Div(decimal.NewFromInt(1).Add(gain.Div(loss))))
+	return &value
+}
+
+// MACDSeries holds the moving average convergence/divergence line, its Signal line and the Histogram of their difference.
+type MACDSeries struct {
+	MACD      Series
+	Signal    Series
+	Histogram Series
+}
+
+// MACD is the moving average convergence/divergence of the values: the difference between the fast and slow EMA,
+// with a Signal line that is the EMA of the MACD over signal periods. The classic parameters are 12, 26 and 9.
+func MACD(values []decimal.Decimal, fast int, slow int, signal int) MACDSeries {
+	fastEMA := EMA(values, fast)
+	slowEMA := EMA(values, slow)
+	macd := make(Series, len(values))
+	for i := range values {
+		if fastEMA[i] == nil || slowEMA[i] == nil {
+			continue
+		}
+		value := fastEMA[i].Sub(*slowEMA[i])
+		macd[i] = &value
+	}
+	signalEMA := ema(macd, signal)
+	histogram := make(Series, len(values))
+	for i := range values {
+		if macd[i] == nil || signalEMA[i] == nil {
+			continue
+		}
+		value := macd[i].Sub(*signalEMA[i])
+		histogram[i] = &value
+	}
+	return MACDSeries{
+		MACD:      macd,
+		Signal:    signalEMA,
+		Histogram: histogram,
+	}
+}
+
+// BollingerSeries holds the Middle (SMA), Upper and Lower Bollinger Bands.
+type BollingerSeries struct {
+	Middle Series
+	Upper  Series
+	Lower  Series
+}
+
+// Bollinger computes Bollinger Bands of the values: the SMA over period, plus and minus k population standard
+// deviations. The classic parameters are 20 and 2.
+func Bollinger(values []decimal.Decimal, period int, k decimal.Decimal) BollingerSeries {
+	middle := SMA(values, period)
+	upper := make(Series, len(values))
+	lower := make(Series, len(values))
+	for i := range values {
+		if middle[i] == nil {
+			continue
+		}
+		var variance decimal.Decimal
+		for _, value := range values[i+1-period : i+1] {
+			deviation := value.Sub(*middle[i])
+			variance = variance.Add(deviation.Mul(deviation))
+		}
+		variance = variance.Div(decimal.NewFromInt(int64(period)))
+		width := sqrt(variance).Mul(k)
+		up := middle[i].Add(width)
+		low := middle[i].Sub(width)
+		upper[i] = &up
+		lower[i] = &low
+	}
+	return BollingerSeries{
+		Middle: middle,
+		Upper:  upper,
+		Lower:  lower,
+	}
+}
+
+// ATR is the average true range of the Candles over period, using Wilder's smoothing. The true range of a Candle is
+// the greatest of its High-Low range and the distance of its High and Low from the previous Close.
+func ATR(candles []*coinbasepro.Candle, period int) Series {
+	out := make(Series, len(candles))
+	if period < 1 || len(candles) < period {
+		return out
+	}
+	trueRanges := make([]decimal.Decimal, len(candles))
+	for i, candle := range candles {
+		trueRange := candle.High.Sub(candle.Low)
+		if i > 0 {
+			prevClose := candles[i-1].Close
+			trueRange = decimal.Max(trueRange, candle.High.Sub(prevClose).Abs(), candle.Low.Sub(prevClose).Abs())
+		}
+		trueRanges[i] = trueRange
+	}
+	p := decimal.NewFromInt(int64(period))
+	atr := decimal.Sum(decimal.Zero, trueRanges[:period]...).Div(p)
+	value := atr
+	out[period-1] = &value
+	for i := period; i < len(candles); i++ {
+		atr = atr.Mul(p.Sub(decimal.NewFromInt(1))).Add(trueRanges[i]).Div(p)
+		value := atr
+		out[i] = &value
+	}
+	return out
+}
+
+// VWAP is the cumulative volume weighted average price of the Candles, using the typical price (High+Low+Close)/3
+// of each Candle. VWAP is undefined until a Candle with Volume is seen.
+func VWAP(candles []*coinbasepro.Candle) Series {
+	out := make(Series, len(candles))
+	three := decimal.NewFromInt(3)
+	var cumulativeValue, cumulativeVolume decimal.Decimal
+	for i, candle := range candles {
+		typical := candle.High.Add(candle.Low).Add(candle.Close).Div(three)
+		cumulativeValue = cumulativeValue.Add(typical.Mul(candle.Volume))
+		cumulativeVolume = cumulativeVolume.Add(candle.Volume)
+		if cumulativeVolume.IsZero() {
+			continue
+		}
+		value := cumulativeValue.Div(cumulativeVolume)
+		out[i] = &value
+	}
+	return out
+}
+
+func series(values []decimal.Decimal) Series {
+	s := make(Series, len(values))
+	for i := range values {
+		s[i] = &values[i]
+	}
+	return s
+}
+
+// sma skips any leading nil values of the Series, so that it can be applied to the output of another indicator.
+func sma(values Series, period int) Series {
+	out := make(Series, len(values))
+	start := firstDefined(values)
+	if period < 1 || len(values)-start < period {
+		return out
+	}
+	p := decimal.NewFromInt(int64(period))
+	var sum decimal.Decimal
+	for i := start; i < len(values); i++ {
+		sum = sum.Add(*values[i])
+		if i-start >= period {
+			sum = sum.Sub(*values[i-period])
+		}
+		if i-start >= period-1 {
+			value := sum.Div(p)
+			out[i] = &value
+		}
+	}
+	return out
+}
+
+// ema skips any leading nil values of the Series, so that it can be applied to the output of another indicator.
+func ema(values Series, period int) Series {
+	out := sma(values, period)
+	start := firstDefined(values) + period - 1
+	if period < 1 || start >= len(values) || out[start] == nil {
+		return out
+	}
+	alpha := decimal.NewFromInt(2).Div(decimal.NewFromInt(int64(period + 1)))
+	prev := *out[start]
+	for i := start + 1; i < len(values); i++ {
+		value := values[i].Sub(prev).Mul(alpha).Add(prev)
+		out[i] = &value
+		prev = value
+	}
+	return out
+}
+
+func firstDefined(values Series) int {
+	for i, value := range values {
+		if value != nil {
+			return i
+		}
+	}
+	return len(values)
+}
+
+// sqrt approximates the square root of a non-negative decimal using Newton's method.
+func sqrt(d decimal.Decimal) decimal.Decimal {
+	if !d.IsPositive() {
+		return decimal.Zero
+	}
+	f, _ := d.Float64()
+	guess := decimal.NewFromFloat(math.Sqrt(f))
+	two := decimal.NewFromInt(2)
+	for i := 0; i < 8; i++ {
+		next := guess.Add(d.DivRound(guess, int32(decimal.DivisionPrecision))).DivRound(two, int32(decimal.DivisionPrecision))
+		if next.Equal(guess) {
+			break
+		}
+		guess = next
+	}
+	return guess
+}
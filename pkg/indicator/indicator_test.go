@@ -0,0 +1,132 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChronological(t *testing.T) {
+	newest := &coinbasepro.Candle{Time: coinbasepro.Time(time.Unix(2, 0))}
+	oldest := &coinbasepro.Candle{Time: coinbasepro.Time(time.Unix(1, 0))}
+	rates := coinbasepro.HistoricRates{Candles: []*coinbasepro.Candle{newest, oldest}}
+	assert.Equal(t, []*coinbasepro.Candle{oldest, newest}, Chronological(rates))
+	assert.Equal(t, []*coinbasepro.Candle{newest, oldest}, rates.Candles)
+}
+
+func TestIndicators(t *testing.T) {
+	values := decimals(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	t.Run("SMA", func(t *testing.T) {
+		sma := SMA(values, 3)
+		assertUndefined(t, sma[:2])
+		assertSeries(t, []float64{2, 3, 4, 5, 6, 7, 8, 9}, sma[2:])
+		assertUndefined(t, SMA(values, 11))
+		assertUndefined(t, SMA(values, 0))
+	})
+	t.Run("EMA", func(t *testing.T) {
+		ema := EMA(values, 3)
+		assertUndefined(t, ema[:2])
+		assertSeries(t, []float64{2, 3, 4, 5, 6, 7, 8, 9}, ema[2:])
+	})
+	t.Run("RSI", func(t *testing.T) {
+		rsi := RSI(values, 3)
+		assertUndefined(t, rsi[:3])
+		assertSeries(t, []float64{100, 100, 100, 100, 100, 100, 100}, rsi[3:])
+		rsi = RSI(decimals(1, 2, 1, 2, 1), 2)
+		assertSeries(t, []float64{50, 75, 37.5}, rsi[2:])
+	})
+	t.Run("MACD", func(t *testing.T) {
+		macd := MACD(values, 2, 3, 2)
+		assertUndefined(t, macd.MACD[:2])
+		assertSeries(t, []float64{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}, macd.MACD[2:])
+		assertUndefined(t, macd.Signal[:3])
+		assertSeries(t, []float64{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}, macd.Signal[3:])
+		assertSeries(t, []float64{0, 0, 0, 0, 0, 0, 0}, macd.Histogram[3:])
+	})
+	t.Run("Bollinger", func(t *testing.T) {
+		bollinger := Bollinger(decimals(1, 2, 3), 3, decimal.NewFromInt(2))
+		assertUndefined(t, bollinger.Middle[:2])
+		assertSeries(t, []float64{2}, bollinger.Middle[2:])
+		assertSeries(t, []float64{2 + 2*math.Sqrt(2.0/3)}, bollinger.Upper[2:])
+		assertSeries(t, []float64{2 - 2*math.Sqrt(2.0/3)}, bollinger.Lower[2:])
+	})
+	t.Run("ATR", func(t *testing.T) {
+		atr := ATR(candles(), 2)
+		assertUndefined(t, atr[:1])
+		assertSeries(t, []float64{2, 2}, atr[1:])
+	})
+	t.Run("VWAP", func(t *testing.T) {
+		vwap := VWAP(candles())
+		assertSeries(t, []float64{2, 2.5, 3}, vwap)
+	})
+	t.Run("Float64", func(t *testing.T) {
+		floats := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		assertFloat64Equivalent(t, SMA(values, 3), SMAFloat64(floats, 3))
+		assertFloat64Equivalent(t, EMA(values, 3), EMAFloat64(floats, 3))
+		assertFloat64Equivalent(t, RSI(decimals(1, 2, 1, 2, 1), 2), RSIFloat64([]float64{1, 2, 1, 2, 1}, 2))
+		macd := MACD(values, 2, 3, 2)
+		macdFloat64 := MACDFloat64(floats, 2, 3, 2)
+		assertFloat64Equivalent(t, macd.MACD, macdFloat64.MACD)
+		assertFloat64Equivalent(t, macd.Signal, macdFloat64.Signal)
+		assertFloat64Equivalent(t, macd.Histogram, macdFloat64.Histogram)
+		bollinger := Bollinger(values, 3, decimal.NewFromInt(2))
+		bollingerFloat64 := BollingerFloat64(floats, 3, 2)
+		assertFloat64Equivalent(t, bollinger.Upper, bollingerFloat64.Upper)
+		assertFloat64Equivalent(t, bollinger.Lower, bollingerFloat64.Lower)
+		float64Candles := Float64Candles(candles())
+		assert.Equal(t, []float64{2, 3, 4}, Float64Closes(float64Candles))
+		assertFloat64Equivalent(t, ATR(candles(), 2), ATRFloat64(float64Candles, 2))
+		assertFloat64Equivalent(t, VWAP(candles()), VWAPFloat64(float64Candles))
+	})
+}
+
+func candles() []*coinbasepro.Candle {
+	return []*coinbasepro.Candle{
+		{High: decimal.NewFromInt(3), Low: decimal.NewFromInt(1), Close: decimal.NewFromInt(2), Volume: decimal.NewFromInt(1)},
+		{High: decimal.NewFromInt(4), Low: decimal.NewFromInt(2), Close: decimal.NewFromInt(3), Volume: decimal.NewFromInt(1)},
+		{High: decimal.NewFromInt(5), Low: decimal.NewFromInt(3), Close: decimal.NewFromInt(4), Volume: decimal.NewFromInt(1)},
+	}
+}
+
+func decimals(values ...int64) []decimal.Decimal {
+	out := make([]decimal.Decimal, len(values))
+	for i, value := range values {
+		out[i] = decimal.NewFromInt(value)
+	}
+	return out
+}
+
+func assertUndefined(t *testing.T, series Series) {
+	t.Helper()
+	for i, value := range series {
+		assert.Nil(t, value, "index %d", i)
+	}
+}
+
+func assertSeries(t *testing.T, expected []float64, series Series) {
+	t.Helper()
+	require.Len(t, series, len(expected))
+	for i, value := range series {
+		require.NotNil(t, value, "index %d", i)
+		actual, _ := value.Float64()
+		assert.InDelta(t, expected[i], actual, 1e-9, "index %d", i)
+	}
+}
+
+func assertFloat64Equivalent(t *testing.T, expected Series, actual Float64Series) {
+	t.Helper()
+	require.Len(t, actual, len(expected))
+	for i, value := range expected {
+		if value == nil {
+			assert.True(t, math.IsNaN(actual[i]), "index %d", i)
+			continue
+		}
+		f, _ := value.Float64()
+		assert.InDelta(t, f, actual[i], 1e-9, "index %d", i)
+	}
+}
@@ -0,0 +1,136 @@
+package indicator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/durp/reticule/pkg/coinbasepro"
+	"github.com/shopspring/decimal"
+)
+
+// Name identifies an indicator.
+type Name string
+
+const (
+	NameSMA       Name = "sma"
+	NameEMA       Name = "ema"
+	NameRSI       Name = "rsi"
+	NameMACD      Name = "macd"
+	NameBollinger Name = "bollinger"
+	NameATR       Name = "atr"
+	NameVWAP      Name = "vwap"
+)
+
+// defaultParams are the classic parameters of each indicator, used when a Spec omits them.
+var defaultParams = map[Name][]int64{
+	NameSMA:       {20},
+	NameEMA:       {20},
+	NameRSI:       {14},
+	NameMACD:      {12, 26, 9},
+	NameBollinger: {20, 2},
+	NameATR:       {14},
+	NameVWAP:      {},
+}
+
+// Spec describes an indicator and its parameters in the form `name[:param...]`, such as `sma:20`, `macd:12:26:9` or
+// `bollinger:20:2`. Omitted parameters take their classic defaults.
+type Spec struct {
+	Name   Name
+	Params []decimal.Decimal
+}
+
+// UnmarshalText parses a Spec from its `name[:param...]` form and fills any omitted parameters with defaults.
+func (s *Spec) UnmarshalText(text []byte) error {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(string(text))), ":")
+	spec := Spec{Name: Name(parts[0])}
+	defaults, ok := defaultParams[spec.Name]
+	if !ok {
+		return fmt.Errorf("indicator(%q) is not one of [ sma, ema, rsi, macd, bollinger, atr, vwap ]", spec.Name)
+	}
+	if len(parts)-1 > len(defaults) {
+		return fmt.Errorf("indicator(%q) accepts at most %d parameters", spec.Name, len(defaults))
+	}
+	for i, def := range defaults {
+		if i+1 < len(parts) {
+			param, err := decimal.NewFromString(parts[i+1])
+			if err != nil {
+				return fmt.Errorf("indicator(%q) parameter %q is not a number", spec.Name, parts[i+1])
+			}
+			spec.Params = append(spec.Params, param)
+			continue
+		}
+		spec.Params = append(spec.Params, decimal.NewFromInt(def))
+	}
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	*s = spec
+	return nil
+}
+
+// Validate ensures the Spec names a known indicator with positive parameters. Periods must be whole numbers.
+func (s Spec) Validate() error {
+	defaults, ok := defaultParams[s.Name]
+	if !ok {
+		return fmt.Errorf("indicator(%q) is not valid", s.Name)
+	}
+	if len(s.Params) != len(defaults) {
+		return fmt.Errorf("indicator(%q) requires %d parameters", s.Name, len(defaults))
+	}
+	for i, param := range s.Params {
+		if !param.IsPositive() {
+			return fmt.Errorf("indicator(%q) parameters must be positive", s.Name)
+		}
+		// the Bollinger multiplier is the only parameter that is not a period
+		if !(s.Name == NameBollinger && i == 1) && !param.Equal(param.Truncate(0)) {
+			return fmt.Errorf("indicator(%q) periods must be whole numbers", s.Name)
+		}
+	}
+	return nil
+}
+
+// String returns the column name prefix of the Spec, such as `sma_20` or `macd_12_26_9`.
+func (s Spec) String() string {
+	parts := []string{string(s.Name)}
+	for _, param := range s.Params {
+		parts = append(parts, param.String())
+	}
+	return strings.Join(parts, "_")
+}
+
+// Columns computes the indicator over chronologically ordered Candles. Indicators that produce more than one line,
+// such as MACD and Bollinger, produce one suffixed column per line.
+func (s Spec) Columns(candles []*coinbasepro.Candle) map[string]Series {
+	name := s.String()
+	period := func(i int) int {
+		return int(s.Params[i].IntPart())
+	}
+	switch s.Name {
+	case NameSMA:
+		return map[string]Series{name: SMA(Closes(candles), period(0))}
+	case NameEMA:
+		return map[string]Series{name: EMA(Closes(candles), period(0))}
+	case NameRSI:
+		return map[string]Series{name: RSI(Closes(candles), period(0))}
+	case NameMACD:
+		macd := MACD(Closes(candles), period(0), period(1), period(2))
+		return map[string]Series{
+			name:                macd.MACD,
+			name + "_signal":    macd.Signal,
+			name + "_histogram": macd.Histogram,
+		}
+	case NameBollinger:
+		bollinger := Bollinger(Closes(candles), period(0), s.Params[1])
+		return map[string]Series{
+			name + "_middle": bollinger.Middle,
+			name + "_upper":  bollinger.Upper,
+			name + "_lower":  bollinger.Lower,
+		}
+	case NameATR:
+		return map[string]Series{name: ATR(candles, period(0))}
+	case NameVWAP:
+		return map[string]Series{name: VWAP(candles)}
+	default:
+		panic(s.Validate())
+	}
+}
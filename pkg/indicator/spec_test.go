@@ -0,0 +1,57 @@
+package indicator
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpec(t *testing.T) {
+	t.Run("UnmarshalText", func(t *testing.T) {
+		t.Run("Success", func(t *testing.T) {
+			var s Spec
+			require.NoError(t, s.UnmarshalText([]byte("macd:5")))
+			assert.Equal(t, NameMACD, s.Name)
+			assert.Equal(t, "macd_5_26_9", s.String())
+			require.NoError(t, s.UnmarshalText([]byte("Bollinger:10:2.5")))
+			assert.Equal(t, "bollinger_10_2.5", s.String())
+			require.NoError(t, s.UnmarshalText([]byte("vwap")))
+			assert.Equal(t, "vwap", s.String())
+		})
+		t.Run("Error", func(t *testing.T) {
+			var s Spec
+			assert.Error(t, s.UnmarshalText([]byte("blah:1")))
+			assert.Error(t, s.UnmarshalText([]byte("sma:1:2")))
+			assert.Error(t, s.UnmarshalText([]byte("sma:X")))
+			assert.Error(t, s.UnmarshalText([]byte("sma:0")))
+			assert.Error(t, s.UnmarshalText([]byte("sma:1.5")))
+			assert.Error(t, s.UnmarshalText([]byte("vwap:1")))
+		})
+	})
+	t.Run("Columns", func(t *testing.T) {
+		for spec, columns := range map[string][]string{
+			"sma:2":       {"sma_2"},
+			"ema:2":       {"ema_2"},
+			"rsi:2":       {"rsi_2"},
+			"macd:1:2:1":  {"macd_1_2_1", "macd_1_2_1_signal", "macd_1_2_1_histogram"},
+			"bollinger:2": {"bollinger_2_2_middle", "bollinger_2_2_upper", "bollinger_2_2_lower"},
+			"atr:2":       {"atr_2"},
+			"vwap":        {"vwap"},
+		} {
+			var s Spec
+			require.NoError(t, s.UnmarshalText([]byte(spec)))
+			computed := s.Columns(candles())
+			assert.Len(t, computed, len(columns), spec)
+			for _, column := range columns {
+				assert.Len(t, computed[column], 3, column)
+			}
+		}
+	})
+	t.Run("Validate", func(t *testing.T) {
+		assert.Error(t, Spec{Name: NameSMA}.Validate())
+		assert.Error(t, Spec{Name: "blah"}.Validate())
+		assert.NoError(t, Spec{Name: NameSMA, Params: []decimal.Decimal{decimal.NewFromInt(1)}}.Validate())
+	})
+}
@@ -14,18 +14,25 @@ import (
 
 	"github.com/durp/reticule/pkg/set"
 	"github.com/jeremywohl/flatten"
-	"github.com/sirupsen/logrus"
 )
 
-func NewStore() *Store {
+// Logger receives the log of a Store as a message followed by alternating keys and values.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// NewStore creates an empty Store that logs to logger, or logs nothing when logger is nil.
+func NewStore(logger Logger) *Store {
 	return &Store{
 		shapes: make(map[string]Occurrence),
+		logger: logger,
 	}
 }
 
 func (s *Store) Load(shapes map[string]Occurrence) error {
 	s.shapes = shapes
-	logrus.Debugf("loaded %d shapes", len(s.shapes))
+	s.log().Debug("loaded shapes", "count", len(s.shapes))
 	return nil
 }
 
@@ -36,8 +43,21 @@ func (s *Store) Write(w io.Writer) error {
 
 type Store struct {
 	shapes map[string]Occurrence
+	logger Logger
 }
 
+func (s *Store) log() Logger {
+	if s.logger == nil {
+		return noLogger{}
+	}
+	return s.logger
+}
+
+type noLogger struct{}
+
+func (noLogger) Debug(string, ...interface{}) {}
+func (noLogger) Error(string, ...interface{}) {}
+
 type Occurrence struct {
 	Shape Shape
 	Count int
@@ -92,7 +112,7 @@ func (s *Store) AddShape(name string, raw interface{}) error {
 			Keys: keys,
 		})
 	default:
-		logrus.Errorf("unhandled type %T", v)
+		s.log().Error("unhandled type", "name", name, "type", fmt.Sprintf("%T", v))
 	}
 	return nil
 }